@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/ecdsa"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/pkg/taproot"
+	"github.com/MixinNetwork/multi-party-sig/protocols/cmp"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost/sign"
+)
+
+func passphraseFlag(fs *flag.FlagSet) *string {
+	return fs.String("passphrase-file", "", "file holding the passphrase of the shares and identity, instead of "+passphraseEnv)
+}
+
+func identityCommand(args []string) error {
+	fs := flag.NewFlagSet("identity", flag.ExitOnError)
+	id := fs.String("id", "", "ID of this party")
+	out := fs.String("out", "", "file to write the identity to")
+	passphraseFile := passphraseFlag(fs)
+	_ = fs.Parse(args)
+
+	if *id == "" {
+		return errors.New("missing -id")
+	}
+	if strings.ContainsAny(*id, " \t\r\n#") {
+		return fmt.Errorf("invalid -id %q", *id)
+	}
+	if err := checkOutput(*out); err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+	i, err := newIdentity(party.ID(*id))
+	if err != nil {
+		return err
+	}
+	line, err := i.peerLine()
+	if err != nil {
+		return err
+	}
+	if err = i.write(*out, passphrase); err != nil {
+		return err
+	}
+	// the line is added to the peers file of every party.
+	fmt.Println(line)
+	return nil
+}
+
+func keygenCommand(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	var c ceremony
+	c.register(fs)
+	protocolName := fs.String("protocol", "", "cmp, frost or frost-taproot")
+	curveName := fs.String("curve", "secp256k1", "secp256k1, or ed25519 for frost")
+	id := fs.String("id", "", "ID of this party")
+	parties := fs.String("parties", "", "comma separated IDs of all parties, including this one")
+	threshold := fs.Int("threshold", 0, "number of corrupted parties tolerated, one more party is needed to sign")
+	out := fs.String("out", "", "file to write the share to")
+	passphraseFile := passphraseFlag(fs)
+	_ = fs.Parse(args)
+
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := checkOutput(*out); err != nil {
+		return err
+	}
+	group, err := parseCurve(*curveName)
+	if err != nil {
+		return err
+	}
+	partyIDs, err := parseIDs(*parties)
+	if err != nil {
+		return err
+	}
+	selfID := party.ID(*id)
+	if !party.NewIDSlice(partyIDs).Contains(selfID) {
+		return fmt.Errorf("-id %q is not one of -parties", selfID)
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	var start protocol.StartFunc
+	switch *protocolName {
+	case protocolCMP:
+		if group.Name() != (curve.Secp256k1{}).Name() {
+			return fmt.Errorf("cmp only supports secp256k1")
+		}
+		pl := pool.NewPool(0)
+		defer pl.TearDown()
+		start = cmp.Keygen(group, selfID, partyIDs, *threshold, pl)
+	case protocolFrost:
+		start = frost.Keygen(group, selfID, partyIDs, *threshold)
+	case protocolFrostTaproot:
+		if group.Name() != (curve.Secp256k1{}).Name() {
+			return fmt.Errorf("frost-taproot only supports secp256k1")
+		}
+		start = frost.KeygenTaproot(selfID, partyIDs, *threshold)
+	default:
+		return fmt.Errorf("unknown -protocol %q", *protocolName)
+	}
+
+	result, err := c.run(start, selfID, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeResult(result, "", *out, passphrase)
+}
+
+func signCommand(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	var c ceremony
+	c.register(fs)
+	shareFile := fs.String("share", "", "share file of this party")
+	signers := fs.String("signers", "", "comma separated IDs of the signers, including this party")
+	message := fs.String("message", "", "hex encoded message to sign, which should be a 32 byte hash except for ed25519")
+	passphraseFile := passphraseFlag(fs)
+	_ = fs.Parse(args)
+
+	if err := c.validate(); err != nil {
+		return err
+	}
+	signerIDs, err := parseIDs(*signers)
+	if err != nil {
+		return err
+	}
+	m, err := hex.DecodeString(*message)
+	if err != nil {
+		return fmt.Errorf("invalid -message: %w", err)
+	}
+	if len(m) == 0 {
+		return errors.New("missing -message")
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+	s, err := readShare(*shareFile, passphrase)
+	if err != nil {
+		return err
+	}
+
+	var start protocol.StartFunc
+	switch config := s.config.(type) {
+	case *cmp.Config:
+		pl := pool.NewPool(0)
+		defer pl.TearDown()
+		start = cmp.Sign(config, signerIDs, m, pl)
+	case *frost.Config:
+		variant := sign.ProtocolDefault
+		if config.Curve().Name() == (curve.Edwards25519{}).Name() {
+			variant = sign.ProtocolEd25519SHA512
+		}
+		start = frost.Sign(config, signerIDs, m, variant)
+	case *frost.TaprootConfig:
+		start = frost.SignTaproot(config, signerIDs, m)
+	}
+
+	result, err := c.run(start, s.header.ID, passphrase)
+	if err != nil {
+		return err
+	}
+	var sig []byte
+	switch result := result.(type) {
+	case *ecdsa.Signature:
+		sig = result.Serialize()
+	case *frost.Signature:
+		sig, err = result.MarshalBinary()
+	case taproot.Signature:
+		sig = result
+	default:
+		err = fmt.Errorf("unexpected result %T", result)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(sig))
+	return nil
+}
+
+func refreshCommand(args []string) error {
+	fs := flag.NewFlagSet("refresh", flag.ExitOnError)
+	var c ceremony
+	c.register(fs)
+	shareFile := fs.String("share", "", "share file of this party")
+	out := fs.String("out", "", "file to write the new share to")
+	passphraseFile := passphraseFlag(fs)
+	_ = fs.Parse(args)
+
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := checkOutput(*out); err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+	s, err := readShare(*shareFile, passphrase)
+	if err != nil {
+		return err
+	}
+
+	var start protocol.StartFunc
+	switch config := s.config.(type) {
+	case *cmp.Config:
+		pl := pool.NewPool(0)
+		defer pl.TearDown()
+		start = cmp.Refresh(config, pl)
+	case *frost.Config:
+		start = frost.Refresh(config)
+	case *frost.TaprootConfig:
+		start = frost.RefreshTaproot(config)
+	}
+
+	result, err := c.run(start, s.header.ID, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeResult(result, s.header.Path, *out, passphrase)
+}
+
+func deriveCommand(args []string) error {
+	fs := flag.NewFlagSet("derive", flag.ExitOnError)
+	shareFile := fs.String("share", "", "share file of this party")
+	path := fs.String("path", "", "BIP-32 path of the child key, such as 0/1, without hardened indices")
+	out := fs.String("out", "", "file to write the derived share to")
+	passphraseFile := passphraseFlag(fs)
+	_ = fs.Parse(args)
+
+	if err := checkOutput(*out); err != nil {
+		return err
+	}
+	indices, err := parsePath(*path)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+	s, err := readShare(*shareFile, passphrase)
+	if err != nil {
+		return err
+	}
+
+	result := s.config
+	for _, i := range indices {
+		switch config := result.(type) {
+		case *cmp.Config:
+			result, err = config.DeriveBIP32(i)
+		case *frost.Config:
+			result, err = config.DeriveChild(i)
+		case *frost.TaprootConfig:
+			result, err = config.DeriveChild(i)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	derivedPath := s.header.Path
+	if derivedPath == "" {
+		derivedPath = "m"
+	}
+	for _, i := range indices {
+		derivedPath += "/" + strconv.FormatUint(uint64(i), 10)
+	}
+	return writeResult(result, derivedPath, *out, passphrase)
+}
+
+// parsePath parses a BIP-32 path of non-hardened indices, such as m/0/1 or 0/1.
+func parsePath(path string) ([]uint32, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/")
+	if path == "" {
+		return nil, errors.New("missing -path")
+	}
+	fields := strings.Split(path, "/")
+	indices := make([]uint32, 0, len(fields))
+	for _, f := range fields {
+		i, err := strconv.ParseUint(f, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q in -path, hardened indices are not supported", f)
+		}
+		indices = append(indices, uint32(i))
+	}
+	return indices, nil
+}
+
+func inspectCommand(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	shareFile := fs.String("share", "", "share file to inspect")
+	_ = fs.Parse(args)
+
+	_, h, err := readShareFile(*shareFile)
+	if err != nil {
+		return err
+	}
+	parties := make([]string, 0, len(h.Parties))
+	for _, id := range h.Parties {
+		parties = append(parties, string(id))
+	}
+	fmt.Printf("protocol:   %s\n", h.Protocol)
+	fmt.Printf("curve:      %s\n", h.Curve)
+	fmt.Printf("id:         %s\n", h.ID)
+	fmt.Printf("threshold:  %d\n", h.Threshold)
+	fmt.Printf("parties:    %s\n", strings.Join(parties, ","))
+	fmt.Printf("public key: %s\n", hex.EncodeToString(h.PublicKey))
+	if h.Path != "" {
+		fmt.Printf("path:       %s\n", h.Path)
+	}
+	return nil
+}
+
+// writeResult writes the config returned by a protocol to a new share file, and prints its public key.
+func writeResult(result interface{}, path, out string, passphrase []byte) error {
+	config, ok := result.(encoding.BinaryMarshaler)
+	if !ok {
+		return fmt.Errorf("unexpected result %T", result)
+	}
+	s, err := newShare(config, path)
+	if err != nil {
+		return err
+	}
+	if err = s.write(out, passphrase); err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(s.header.PublicKey))
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// TestKeygenSign runs a frost keygen between three processes, and then signs with two of them,
+// exchanging the messages through a directory, as mpsig does with -dir.
+func TestKeygenSign(t *testing.T) {
+	tmp := t.TempDir()
+	passphrase := []byte("correct horse battery staple")
+	passphraseFile := filepath.Join(tmp, "passphrase")
+	require.NoError(t, os.WriteFile(passphraseFile, append(passphrase, '\n'), 0o600))
+	messages := filepath.Join(tmp, "messages")
+	require.NoError(t, os.Mkdir(messages, 0o700))
+	shareFile := func(id string) string { return filepath.Join(tmp, id+".share") }
+	identityFile := func(id string) string { return filepath.Join(tmp, id+".identity") }
+
+	parties := []string{"a", "b", "c"}
+	var peers []string
+	for _, id := range parties {
+		i, err := newIdentity(party.ID(id))
+		require.NoError(t, err)
+		require.NoError(t, i.write(identityFile(id), passphrase))
+		line, err := i.peerLine()
+		require.NoError(t, err)
+		peers = append(peers, line)
+	}
+	peersFile := filepath.Join(tmp, "peers")
+	require.NoError(t, os.WriteFile(peersFile, []byte(strings.Join(peers, "\n")+"\n"), 0o600))
+
+	// without identities, the shares would go through the directory in the clear.
+	err := keygenCommand([]string{
+		"-protocol", "frost-taproot", "-id", "a", "-parties", strings.Join(parties, ","), "-threshold", "1",
+		"-session", "keygen", "-dir", messages, "-out", shareFile("a"), "-passphrase-file", passphraseFile,
+	})
+	assert.EqualError(t, err, "missing -identity")
+
+	var errGroup errgroup.Group
+	for _, id := range parties {
+		errGroup.Go(func() error {
+			return keygenCommand([]string{
+				"-protocol", "frost-taproot", "-id", id, "-parties", strings.Join(parties, ","), "-threshold", "1",
+				"-session", "keygen", "-dir", messages, "-timeout", "1m",
+				"-identity", identityFile(id), "-peers", peersFile,
+				"-out", shareFile(id), "-passphrase-file", passphraseFile,
+			})
+		})
+	}
+	require.NoError(t, errGroup.Wait())
+
+	var publicKey []byte
+	for _, id := range parties {
+		_, header, err := readShareFile(shareFile(id))
+		require.NoError(t, err)
+		if publicKey == nil {
+			publicKey = header.PublicKey
+		}
+		assert.True(t, bytes.Equal(publicKey, header.PublicKey), "party %s has another public key", id)
+	}
+
+	// the signature is verified by the signers before they output it.
+	message := strings.Repeat("ab", 32)
+	for _, id := range parties[:2] {
+		errGroup.Go(func() error {
+			return signCommand([]string{
+				"-share", shareFile(id), "-signers", "a,b", "-message", message,
+				"-session", "sign", "-dir", messages, "-timeout", "1m", "-passphrase-file", passphraseFile,
+				"-identity", identityFile(id), "-peers", peersFile,
+			})
+		})
+	}
+	require.NoError(t, errGroup.Wait())
+}
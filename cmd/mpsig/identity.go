@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// identityVersion is the version of the identity file format.
+const identityVersion = 1
+
+// identityHeader is the public part of an identity file, which can be read without the passphrase.
+type identityHeader struct {
+	Version int
+	ID      party.ID
+}
+
+// identityFile is the content of an identity file.
+//
+// The keys are encrypted as the config of a share file, with the encoded header as additional data.
+type identityFile struct {
+	Header     cbor.RawMessage
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// identitySecrets is the encrypted content of an identity file.
+type identitySecrets struct {
+	// Key is the Ed25519 private key signing the messages of the party.
+	Key []byte
+	// EncryptionKey is the secp256k1 scalar decrypting the messages sent to the party.
+	EncryptionKey []byte
+}
+
+// identity holds the static keys of a party, which authenticate its messages in every ceremony.
+type identity struct {
+	id            party.ID
+	key           ed25519.PrivateKey
+	encryptionKey curve.Scalar
+}
+
+// peer holds the public keys of a party, as listed in a peers file.
+type peer struct {
+	key           protocol.IdentityPublicKey
+	encryptionKey curve.Point
+}
+
+// newIdentity generates the keys of party id.
+func newIdentity(id party.ID) (*identity, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &identity{id: id, key: key, encryptionKey: sample.Scalar(rand.Reader, curve.Secp256k1{})}, nil
+}
+
+// peerLine returns the line of a peers file listing the public keys of i.
+func (i *identity) peerLine() (string, error) {
+	encryptionKey, err := i.encryptionKey.ActOnBase().MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	public := i.key.Public().(ed25519.PublicKey)
+	return fmt.Sprintf("%s %s %s", i.id, hex.EncodeToString(public), hex.EncodeToString(encryptionKey)), nil
+}
+
+// write encrypts the identity to a new file at path, which must not exist yet.
+func (i *identity) write(path string, passphrase []byte) error {
+	header, err := cbor.Marshal(&identityHeader{Version: identityVersion, ID: i.id})
+	if err != nil {
+		return err
+	}
+	encryptionKey, err := i.encryptionKey.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	plaintext, err := cbor.Marshal(&identitySecrets{Key: i.key, EncryptionKey: encryptionKey})
+	if err != nil {
+		return err
+	}
+	f := &identityFile{Header: header}
+	if f.Salt, f.Nonce, f.Ciphertext, err = seal(passphrase, plaintext, header); err != nil {
+		return err
+	}
+	data, err := cbor.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return createFile(path, data)
+}
+
+// readIdentity decrypts the identity file at path.
+func readIdentity(path string, passphrase []byte) (*identity, error) {
+	if path == "" {
+		return nil, errors.New("missing -identity")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &identityFile{}
+	if err = cbor.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: invalid identity file: %w", path, err)
+	}
+	h := &identityHeader{}
+	if err = cbor.Unmarshal(f.Header, h); err != nil {
+		return nil, fmt.Errorf("%s: invalid identity header: %w", path, err)
+	}
+	if h.Version != identityVersion {
+		return nil, fmt.Errorf("%s: unsupported identity version %d", path, h.Version)
+	}
+	plaintext, err := open(passphrase, f.Salt, f.Nonce, f.Ciphertext, f.Header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	secrets := &identitySecrets{}
+	if err = cbor.Unmarshal(plaintext, secrets); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(secrets.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s: invalid identity key", path)
+	}
+	encryptionKey := curve.Secp256k1{}.NewScalar()
+	if err = encryptionKey.UnmarshalBinary(secrets.EncryptionKey); err != nil {
+		return nil, fmt.Errorf("%s: invalid encryption key: %w", path, err)
+	}
+	return &identity{id: h.ID, key: secrets.Key, encryptionKey: encryptionKey}, nil
+}
+
+// readPeers reads the peers file at path, which has a line "<id> <identity key> <encryption key>"
+// for every party, with hex encoded keys, as printed by mpsig identity.
+// Empty lines and lines starting with # are ignored.
+func readPeers(path string) (map[party.ID]peer, error) {
+	if path == "" {
+		return nil, errors.New("missing -peers")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	peers := make(map[party.ID]peer)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected an ID, an identity key and an encryption key", path, n)
+		}
+		id := party.ID(fields[0])
+		if _, ok := peers[id]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate party %s", path, n, id)
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s:%d: invalid identity key", path, n)
+		}
+		encoded, err := hex.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid encryption key", path, n)
+		}
+		encryptionKey := curve.Secp256k1{}.NewPoint()
+		if err = encryptionKey.UnmarshalBinary(encoded); err != nil || encryptionKey.IsIdentity() {
+			return nil, fmt.Errorf("%s:%d: invalid encryption key", path, n)
+		}
+		peers[id] = peer{key: protocol.Ed25519PublicKey(key), encryptionKey: encryptionKey}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// identities returns the identities authenticating the messages of i with the other peers,
+// and encrypting the point-to-point messages between them.
+func (i *identity) identities(peers map[party.ID]peer) (*protocol.Identities, error) {
+	// the handler checks that the keys of the party match its identity.
+	if _, ok := peers[i.id]; !ok {
+		return nil, fmt.Errorf("party %s is not in -peers", i.id)
+	}
+	ids := &protocol.Identities{
+		Key:            protocol.Ed25519Identity(i.key),
+		Keys:           make(map[party.ID]protocol.IdentityPublicKey, len(peers)),
+		EncryptionKey:  i.encryptionKey,
+		EncryptionKeys: make(map[party.ID]curve.Point, len(peers)),
+	}
+	for id, p := range peers {
+		ids.Keys[id] = p.key
+		ids.EncryptionKeys[id] = p.encryptionKey
+	}
+	return ids, nil
+}
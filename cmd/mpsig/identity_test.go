@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityRoundTrip(t *testing.T) {
+	passphrase := []byte("correct horse battery staple")
+	tmp := t.TempDir()
+	i, err := newIdentity("a")
+	require.NoError(t, err)
+	path := filepath.Join(tmp, "a.identity")
+	require.NoError(t, i.write(path, passphrase))
+
+	restored, err := readIdentity(path, passphrase)
+	require.NoError(t, err)
+	assert.Equal(t, party.ID("a"), restored.id)
+	assert.Equal(t, i.key, restored.key)
+	assert.True(t, i.encryptionKey.Equal(restored.encryptionKey))
+
+	_, err = readIdentity(path, []byte("wrong"))
+	assert.Error(t, err)
+
+	// an identity is never overwritten.
+	assert.Error(t, i.write(path, passphrase))
+
+	line, err := i.peerLine()
+	require.NoError(t, err)
+	peersFile := filepath.Join(tmp, "peers")
+	require.NoError(t, os.WriteFile(peersFile, []byte("# parties\n"+line+"\n\n"), 0o600))
+	peers, err := readPeers(peersFile)
+	require.NoError(t, err)
+	require.Contains(t, peers, party.ID("a"))
+	assert.True(t, peers["a"].encryptionKey.Equal(i.encryptionKey.ActOnBase()))
+
+	ids, err := restored.identities(peers)
+	require.NoError(t, err)
+	digest := make([]byte, 64)
+	signature, err := ids.Key.Sign(digest)
+	require.NoError(t, err)
+	assert.True(t, ids.Keys["a"].Verify(digest, signature))
+
+	// a party must be in the peers file.
+	b, err := newIdentity("b")
+	require.NoError(t, err)
+	_, err = b.identities(peers)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(peersFile, []byte(line+"\n"+line+"\n"), 0o600))
+	_, err = readPeers(peersFile)
+	assert.Error(t, err, "duplicate party")
+	require.NoError(t, os.WriteFile(peersFile, []byte("a 00 00\n"), 0o600))
+	_, err = readPeers(peersFile)
+	assert.Error(t, err, "invalid keys")
+}
@@ -0,0 +1,223 @@
+// Command mpsig runs threshold key generation and signing ceremonies with cmp or frost,
+// with one process per party.
+//
+// The parties exchange their messages through a shared directory given by -dir, or through a
+// relay listening on the Unix socket given by -socket, started with `relay -socket`.
+// The directory can also be copied back and forth between air-gapped machines, since every
+// message is a separate file which is never modified.
+//
+// Every party first creates its identity with `mpsig identity`, which prints a line with its public keys.
+// The lines of all the parties form the peers file given by -peers to every ceremony, along with the
+// identity of the party given by -identity. Messages are signed by their sender, and those sent to a
+// single party, which contain secret shares, are encrypted to their recipient, so that the directory
+// or the relay can't read the shares or forge messages. keygen, sign and refresh refuse to run without them.
+//
+// Shares and identities are written to new files, encrypted with a passphrase read from -passphrase-file,
+// or from the MPSIG_PASSPHRASE environment variable. A share or identity file is never overwritten.
+//
+// Usage:
+//
+//	mpsig identity -id a -out a.identity
+//	mpsig keygen -protocol cmp|frost|frost-taproot [-curve secp256k1|ed25519] -id a -parties a,b,c -threshold 1 -session s (-dir d | -socket s) -identity a.identity -peers peers -out a.share
+//	mpsig sign -share a.share -signers a,b -message <hex> -session s (-dir d | -socket s) -identity a.identity -peers peers
+//	mpsig refresh -share a.share -session s (-dir d | -socket s) -identity a.identity -peers peers -out a2.share
+//	mpsig derive -share a.share -path 0/1 -out a-0-1.share
+//	mpsig inspect -share a.share
+//
+// All the parties of a ceremony must give the same -session, which should not be reused.
+//
+// keygen and refresh print the hex encoded public key, and sign prints the hex encoded signature:
+// the compressed point R followed by s for cmp, the 64 byte signature of RFC 8032 for frost with
+// ed25519, the 64 byte signature of BIP-340 for frost-taproot, and the compressed point R followed
+// by z for frost with secp256k1. For ed25519, -message is the message itself, and otherwise it
+// should be a 32 byte hash.
+//
+// derive only needs the share of the party, and computes the share of a non-hardened BIP-32 child
+// key, which can then be used with sign. It is only available with secp256k1.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/transport/dir"
+	"github.com/MixinNetwork/multi-party-sig/transport/relay"
+)
+
+const usage = `usage: mpsig <command> [flags]
+
+commands:
+  identity create the keys authenticating the messages of a party
+  keygen   generate a new key with the other parties
+  sign     sign a message with the other signers
+  refresh  replace the shares of a key with the other parties
+  derive   derive the share of a BIP-32 child key
+  inspect  print the public information of a share
+
+Run mpsig <command> -h for the flags of a command.
+`
+
+var commands = map[string]func(args []string) error{
+	"identity": identityCommand,
+	"keygen":   keygenCommand,
+	"sign":     signCommand,
+	"refresh":  refreshCommand,
+	"derive":   deriveCommand,
+	"inspect":  inspectCommand,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "mpsig: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "mpsig %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// ceremony holds the flags of the commands which run a protocol with the other parties.
+type ceremony struct {
+	dir      string
+	socket   string
+	session  string
+	timeout  time.Duration
+	identity string
+	peers    string
+}
+
+func (c *ceremony) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.dir, "dir", "", "shared directory to exchange messages through")
+	fs.StringVar(&c.socket, "socket", "", "Unix socket of the relay to exchange messages through")
+	fs.StringVar(&c.session, "session", "", "session identifier, the same for all parties")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Minute, "time after which the ceremony is aborted")
+	fs.StringVar(&c.identity, "identity", "", "identity file of this party, created by mpsig identity")
+	fs.StringVar(&c.peers, "peers", "", "file listing the public keys of the parties, as printed by mpsig identity")
+}
+
+func (c *ceremony) validate() error {
+	if c.session == "" {
+		return errors.New("missing -session")
+	}
+	if (c.dir == "") == (c.socket == "") {
+		return errors.New("exactly one of -dir and -socket is required")
+	}
+	// without identities, the shares would be sent in the clear.
+	if c.identity == "" {
+		return errors.New("missing -identity")
+	}
+	if c.peers == "" {
+		return errors.New("missing -peers")
+	}
+	return nil
+}
+
+// run executes the protocol started by start as party id, and returns its result.
+// The messages are authenticated and encrypted with the identity of the party, decrypted with passphrase.
+func (c *ceremony) run(start protocol.StartFunc, id party.ID, passphrase []byte) (interface{}, error) {
+	self, err := readIdentity(c.identity, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if self.id != id {
+		return nil, fmt.Errorf("-identity is the identity of party %s, not %s", self.id, id)
+	}
+	peers, err := readPeers(c.peers)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := self.identities(peers)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	h, err := protocol.NewMultiHandler(start, []byte(c.session), protocol.WithContext(ctx), protocol.WithIdentities(identities))
+	if err != nil {
+		return nil, err
+	}
+	if c.dir != "" {
+		err = dir.HandlerLoop(ctx, h, dir.New(c.dir, id), h.SSID())
+	} else {
+		err = relay.HandlerLoop(ctx, h, unixClient(c.socket, id), h.SSID())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.Result()
+}
+
+// unixClient returns a relay.Client for party id, connecting to the relay on the Unix socket at path.
+func unixClient(path string, id party.ID) *relay.Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}
+	// the host is ignored, as all connections go to the socket.
+	c := relay.NewClient("http://relay", id)
+	c.HTTPClient = &http.Client{Transport: transport}
+	return c
+}
+
+func parseCurve(name string) (curve.Curve, error) {
+	switch name {
+	case "secp256k1":
+		return curve.Secp256k1{}, nil
+	case "ed25519", "edwards25519":
+		return curve.Edwards25519{}, nil
+	default:
+		return nil, fmt.Errorf("unknown curve %q", name)
+	}
+}
+
+// parseIDs parses a comma separated list of party IDs.
+func parseIDs(list string) ([]party.ID, error) {
+	if list == "" {
+		return nil, errors.New("empty list of parties")
+	}
+	fields := strings.Split(list, ",")
+	ids := make([]party.ID, 0, len(fields))
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			return nil, fmt.Errorf("empty party ID in %q", list)
+		}
+		ids = append(ids, party.ID(f))
+	}
+	if !party.NewIDSlice(ids).Valid() {
+		return nil, fmt.Errorf("duplicate party ID in %q", list)
+	}
+	return ids, nil
+}
+
+// checkOutput returns an error if the share can't be written to path, before running a ceremony.
+func checkOutput(path string) error {
+	if path == "" {
+		return errors.New("missing -out")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/protocols/cmp"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// shareVersion is the version of the share file format.
+	shareVersion = 1
+	// passphraseEnv is the environment variable holding the passphrase, if no file is given.
+	passphraseEnv = "MPSIG_PASSPHRASE"
+
+	protocolCMP          = "cmp"
+	protocolFrost        = "frost"
+	protocolFrostTaproot = "frost-taproot"
+
+	// scrypt parameters, as recommended for interactive use in 2017.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// shareHeader is the public part of a share file, which can be read without the passphrase.
+type shareHeader struct {
+	Version   int
+	Protocol  string
+	Curve     string
+	ID        party.ID
+	Threshold int
+	Parties   []party.ID
+	PublicKey []byte
+	// Path is the BIP-32 path from the key generated by keygen, if the share was derived.
+	Path string `cbor:",omitempty"`
+}
+
+// shareFile is the content of a share file.
+//
+// The config of the party is encrypted with AES-256-GCM, using a key derived from the passphrase
+// with scrypt, and the encoded header as additional data, so that it can't be changed either.
+type shareFile struct {
+	Header     cbor.RawMessage
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// share is the decrypted content of a share file.
+type share struct {
+	header shareHeader
+	// config is a *cmp.Config, a *frost.Config, or a *frost.TaprootConfig.
+	config encoding.BinaryMarshaler
+}
+
+// newShare returns the share holding config, derived along path.
+func newShare(config encoding.BinaryMarshaler, path string) (*share, error) {
+	h := shareHeader{Version: shareVersion, Path: path}
+	var err error
+	switch c := config.(type) {
+	case *cmp.Config:
+		h.Protocol, h.Curve, h.ID, h.Threshold = protocolCMP, c.Group.Name(), c.ID, c.Threshold
+		h.Parties = c.PartyIDs()
+		h.PublicKey, err = c.PublicPoint().MarshalBinary()
+	case *frost.Config:
+		h.Protocol, h.Curve, h.ID, h.Threshold = protocolFrost, c.Curve().Name(), c.ID, c.Threshold
+		h.Parties = sortedIDs(c.VerificationShares.Points)
+		h.PublicKey, err = c.PublicKey.MarshalBinary()
+	case *frost.TaprootConfig:
+		h.Protocol, h.Curve, h.ID, h.Threshold = protocolFrostTaproot, curve.Secp256k1{}.Name(), c.ID, c.Threshold
+		h.Parties = sortedIDs(c.VerificationShares)
+		h.PublicKey = c.PublicKey
+	default:
+		return nil, fmt.Errorf("unexpected result %T", config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &share{header: h, config: config}, nil
+}
+
+func sortedIDs(points map[party.ID]curve.Point) []party.ID {
+	ids := make([]party.ID, 0, len(points))
+	for id := range points {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func shareKey(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// write encrypts the share to a new file at path, which must not exist yet,
+// so that a previous share is never overwritten by mistake.
+func (s *share) write(path string, passphrase []byte) error {
+	header, err := cbor.Marshal(&s.header)
+	if err != nil {
+		return err
+	}
+	plaintext, err := s.config.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	f := &shareFile{Header: header}
+	if f.Salt, f.Nonce, f.Ciphertext, err = seal(passphrase, plaintext, header); err != nil {
+		return err
+	}
+	data, err := cbor.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return createFile(path, data)
+}
+
+// seal encrypts plaintext with a key derived from passphrase and a new salt,
+// authenticating additionalData along with it.
+func seal(passphrase, plaintext, additionalData []byte) (salt, nonce, ciphertext []byte, err error) {
+	salt = make([]byte, 32)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, nil, nil, err
+	}
+	aead, err := shareKey(passphrase, salt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, nil, err
+	}
+	return salt, nonce, aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a ciphertext returned by seal.
+func open(passphrase, salt, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := shareKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	return plaintext, nil
+}
+
+// createFile writes data to a new file at path, readable only by its owner, which must not exist yet.
+func createFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// readShareFile returns the content of the share file at path, and its decoded header.
+func readShareFile(path string) (*shareFile, *shareHeader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := &shareFile{}
+	if err = cbor.Unmarshal(data, f); err != nil {
+		return nil, nil, fmt.Errorf("%s: invalid share file: %w", path, err)
+	}
+	h := &shareHeader{}
+	if err = cbor.Unmarshal(f.Header, h); err != nil {
+		return nil, nil, fmt.Errorf("%s: invalid share header: %w", path, err)
+	}
+	if h.Version != shareVersion {
+		return nil, nil, fmt.Errorf("%s: unsupported share version %d", path, h.Version)
+	}
+	return f, h, nil
+}
+
+// readShare decrypts the share file at path.
+func readShare(path string, passphrase []byte) (*share, error) {
+	f, h, err := readShareFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(passphrase, f.Salt, f.Nonce, f.Ciphertext, f.Header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	group, err := parseCurve(h.Curve)
+	if err != nil {
+		return nil, err
+	}
+	var config interface {
+		encoding.BinaryMarshaler
+		encoding.BinaryUnmarshaler
+	}
+	switch h.Protocol {
+	case protocolCMP:
+		config = cmp.EmptyConfig(group)
+	case protocolFrost:
+		config = frost.EmptyConfig(group)
+	case protocolFrostTaproot:
+		config = &frost.TaprootConfig{PrivateShare: curve.Secp256k1{}.NewScalar()}
+	default:
+		return nil, fmt.Errorf("%s: unknown protocol %q", path, h.Protocol)
+	}
+	if err = config.UnmarshalBinary(plaintext); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &share{header: *h, config: config}, nil
+}
+
+// readPassphrase reads the passphrase from file, or from the MPSIG_PASSPHRASE environment variable if file is empty.
+func readPassphrase(file string) ([]byte, error) {
+	var passphrase string
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	} else {
+		passphrase = os.Getenv(passphraseEnv)
+	}
+	if passphrase == "" {
+		return nil, errors.New("no passphrase: use -passphrase-file or set " + passphraseEnv)
+	}
+	return []byte(passphrase), nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testShare writes the share of a single party frost key to a new file, and returns its path.
+func testShare(t *testing.T, passphrase []byte) (string, *frost.Config) {
+	group := curve.Secp256k1{}
+	secret := sample.Scalar(rand.Reader, group)
+	chainKey := make([]byte, 32)
+	_, err := rand.Read(chainKey)
+	require.NoError(t, err)
+	config := &frost.Config{
+		ID:                 "a",
+		PrivateShare:       secret,
+		PublicKey:          secret.ActOnBase(),
+		ChainKey:           chainKey,
+		VerificationShares: party.NewPointMap(map[party.ID]curve.Point{"a": secret.ActOnBase()}),
+	}
+	s, err := newShare(config, "")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "a.share")
+	require.NoError(t, s.write(path, passphrase))
+	return path, config
+}
+
+func TestShareRoundTrip(t *testing.T) {
+	passphrase := []byte("correct horse battery staple")
+	path, config := testShare(t, passphrase)
+
+	s, err := readShare(path, passphrase)
+	require.NoError(t, err)
+	assert.Equal(t, protocolFrost, s.header.Protocol)
+	assert.Equal(t, curve.Secp256k1{}.Name(), s.header.Curve)
+	assert.Equal(t, party.ID("a"), s.header.ID)
+	assert.Equal(t, []party.ID{"a"}, s.header.Parties)
+	publicKey, err := config.PublicKey.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, s.header.PublicKey)
+
+	require.IsType(t, &frost.Config{}, s.config)
+	restored := s.config.(*frost.Config)
+	assert.True(t, config.PrivateShare.Equal(restored.PrivateShare))
+	assert.True(t, config.PublicKey.Equal(restored.PublicKey))
+	assert.Equal(t, config.ChainKey, restored.ChainKey)
+
+	// the header can be read without the passphrase.
+	_, header, err := readShareFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, s.header, *header)
+
+	// a share is never overwritten.
+	assert.Error(t, s.write(path, passphrase))
+}
+
+func TestShareWrongPassphrase(t *testing.T) {
+	path, _ := testShare(t, []byte("correct horse battery staple"))
+	_, err := readShare(path, []byte("wrong"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong passphrase")
+}
+
+func TestShareTamperedHeader(t *testing.T) {
+	passphrase := []byte("correct horse battery staple")
+	path, _ := testShare(t, passphrase)
+
+	f, header, err := readShareFile(path)
+	require.NoError(t, err)
+	header.Threshold++
+	f.Header, err = cbor.Marshal(header)
+	require.NoError(t, err)
+	data, err := cbor.Marshal(f)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	// the header is authenticated along with the encrypted config.
+	_, err = readShare(path, passphrase)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong passphrase or corrupted file")
+}
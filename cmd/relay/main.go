@@ -0,0 +1,90 @@
+// Command relay runs a store-and-forward server for protocol messages.
+//
+// Parties post their messages with relay.Client, and receive the ones for them from their mailbox.
+// The relay never sees any key material, and messages should be authenticated and encrypted
+// end-to-end by the parties.
+//
+// Usage:
+//
+//	relay -addr :8080 [-ttl 1h] [-max-sessions n] [-max-bytes n] [-cert cert.pem -key key.pem]
+//	relay -socket /path/to/relay.sock [-ttl 1h] [-max-sessions n] [-max-bytes n]
+//
+// With -socket, the relay listens on a Unix socket instead, for parties running as separate
+// processes on the same machine. Access to the relay is then controlled by the permissions
+// of the socket file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/transport/relay"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	ttl := flag.Duration("ttl", relay.DefaultTTL, "time after which a session without new messages is deleted")
+	cert := flag.String("cert", "", "TLS certificate file, to serve over HTTPS")
+	key := flag.String("key", "", "TLS key file, to serve over HTTPS")
+	socket := flag.String("socket", "", "Unix socket to listen on, instead of addr")
+	maxSessions := flag.Int("max-sessions", relay.DefaultMaxSessions, "maximum number of sessions")
+	maxBytes := flag.Int64("max-bytes", relay.DefaultMaxBytes, "maximum total size of the stored messages")
+	flag.Parse()
+
+	handler := relay.NewServer(*ttl)
+	handler.MaxSessions = *maxSessions
+	handler.MaxBytes = *maxBytes
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	var err error
+	switch {
+	case *socket != "":
+		log.Printf("relay: listening on %s", *socket)
+		err = serveUnix(server, *socket)
+	case *cert != "" || *key != "":
+		log.Printf("relay: listening on %s", *addr)
+		err = server.ListenAndServeTLS(*cert, *key)
+	default:
+		log.Printf("relay: listening on %s", *addr)
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Printf("relay: interrupted")
+		return
+	}
+	log.Fatal(err)
+}
+
+// serveUnix serves on a Unix socket at path, which only the owner may connect to,
+// as the relay has no authentication. The socket is removed when the relay is interrupted.
+func serveUnix(server *http.Server, path string) error {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	if err = os.Chmod(path, 0o600); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err = server.Serve(l); errors.Is(err, http.ErrServerClosed) {
+		return ctx.Err()
+	}
+	return err
+}
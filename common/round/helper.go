@@ -1,6 +1,7 @@
 package round
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math"
@@ -30,6 +31,11 @@ type Helper struct {
 	ssid []byte
 
 	hash *hash.Hash
+	// transcript contains everything written to hash, so that it can be recomputed when restoring the Helper.
+	//
+	// The first sessionLength entries make up the ssid, and the rest come from UpdateHashState.
+	transcript    []hash.BytesWithDomain
+	sessionLength int
 
 	mtx sync.Mutex
 }
@@ -62,10 +68,16 @@ func NewSession(info Info, sessionID []byte, pl *pool.Pool, auxInfo ...hash.Writ
 	}
 
 	var err error
-	h := hash.New()
+	h := &Helper{
+		info:          info,
+		Pool:          pl,
+		partyIDs:      partyIDs,
+		otherPartyIDs: partyIDs.Remove(info.SelfID),
+		hash:          hash.New(),
+	}
 
 	if sessionID != nil {
-		if err = h.WriteAny(&hash.BytesWithDomain{
+		if err = h.write(&hash.BytesWithDomain{
 			TheDomain: "Session ID",
 			Bytes:     sessionID,
 		}); err != nil {
@@ -73,7 +85,7 @@ func NewSession(info Info, sessionID []byte, pl *pool.Pool, auxInfo ...hash.Writ
 		}
 	}
 
-	if err = h.WriteAny(&hash.BytesWithDomain{
+	if err = h.write(&hash.BytesWithDomain{
 		TheDomain: "Protocol ID",
 		Bytes:     []byte(info.ProtocolID),
 	}); err != nil {
@@ -81,7 +93,7 @@ func NewSession(info Info, sessionID []byte, pl *pool.Pool, auxInfo ...hash.Writ
 	}
 
 	if info.Group != nil {
-		if err = h.WriteAny(&hash.BytesWithDomain{
+		if err = h.write(&hash.BytesWithDomain{
 			TheDomain: "Group Name",
 			Bytes:     []byte(info.Group.Name()),
 		}); err != nil {
@@ -89,11 +101,11 @@ func NewSession(info Info, sessionID []byte, pl *pool.Pool, auxInfo ...hash.Writ
 		}
 	}
 
-	if err = h.WriteAny(partyIDs); err != nil {
+	if err = h.write(partyIDs); err != nil {
 		return nil, fmt.Errorf("session: %w", err)
 	}
 
-	if err = h.WriteAny(types.ThresholdWrapper(info.Threshold)); err != nil {
+	if err = h.write(types.ThresholdWrapper(info.Threshold)); err != nil {
 		return nil, fmt.Errorf("session: %w", err)
 	}
 
@@ -101,19 +113,31 @@ func NewSession(info Info, sessionID []byte, pl *pool.Pool, auxInfo ...hash.Writ
 		if a == nil {
 			continue
 		}
-		if err = h.WriteAny(a); err != nil {
+		if err = h.write(a); err != nil {
 			return nil, fmt.Errorf("session: %w", err)
 		}
 	}
 
-	return &Helper{
-		info:          info,
-		Pool:          pl,
-		partyIDs:      partyIDs,
-		otherPartyIDs: partyIDs.Remove(info.SelfID),
-		ssid:          h.Clone().Sum(),
-		hash:          h,
-	}, nil
+	h.ssid = h.hash.Clone().Sum()
+	h.sessionLength = len(h.transcript)
+	return h, nil
+}
+
+// write adds value to the hash state, and records it in the transcript.
+func (h *Helper) write(value hash.WriterToWithDomain) error {
+	buf := new(bytes.Buffer)
+	if _, err := value.WriteTo(buf); err != nil {
+		return fmt.Errorf("hash.WriteAny: %s: %w", value.Domain(), err)
+	}
+	entry := hash.BytesWithDomain{
+		TheDomain: value.Domain(),
+		Bytes:     append([]byte{}, buf.Bytes()...),
+	}
+	if err := h.hash.WriteAny(&entry); err != nil {
+		return err
+	}
+	h.transcript = append(h.transcript, entry)
+	return nil
 }
 
 // HashForID returns a clone of the hash.Hash for this session, initialized with the given id.
@@ -133,7 +157,7 @@ func (h *Helper) HashForID(id party.ID) *hash.Hash {
 func (h *Helper) UpdateHashState(value hash.WriterToWithDomain) {
 	h.mtx.Lock()
 	defer h.mtx.Unlock()
-	_ = h.hash.WriteAny(value)
+	_ = h.write(value)
 }
 
 // BroadcastMessage constructs a Message from the broadcast Content, and sets the header correctly.
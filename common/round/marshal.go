@@ -0,0 +1,136 @@
+package round
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// SessionMarshaler is implemented by sessions whose state can be saved, in order to resume
+// the protocol later.
+//
+// Rounds holding signing nonces must not implement it: a restored round would use the same
+// nonce again, possibly for another message, which reveals the secret share.
+type SessionMarshaler interface {
+	Session
+	// MarshalSession encodes the full state of the session, secrets included, so that the
+	// protocol can be restored in the same round.
+	MarshalSession() ([]byte, error)
+}
+
+type helperMarshal struct {
+	ProtocolID       string
+	FinalRoundNumber Number
+	SelfID           party.ID
+	PartyIDs         []party.ID
+	Threshold        int
+	Group            string
+	Session          []hash.BytesWithDomain
+	Updates          []hash.BytesWithDomain
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The Pool is not included, and should be set again after unmarshalling.
+func (h *Helper) MarshalBinary() ([]byte, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	var group string
+	if h.info.Group != nil {
+		group = h.info.Group.Name()
+	}
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	return enc.Marshal(&helperMarshal{
+		ProtocolID:       h.info.ProtocolID,
+		FinalRoundNumber: h.info.FinalRoundNumber,
+		SelfID:           h.info.SelfID,
+		PartyIDs:         h.info.PartyIDs,
+		Threshold:        h.info.Threshold,
+		Group:            group,
+		Session:          h.transcript[:h.sessionLength],
+		Updates:          h.transcript[h.sessionLength:],
+	})
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// The hash state is recomputed from the recorded transcript, and the SSID must match
+// the Session information.
+func (h *Helper) UnmarshalBinary(data []byte) error {
+	hm := &helperMarshal{}
+	if err := cbor.Unmarshal(data, hm); err != nil {
+		return fmt.Errorf("helper: %w", err)
+	}
+	group, err := groupFromName(hm.Group)
+	if err != nil {
+		return fmt.Errorf("helper: %w", err)
+	}
+	// The transcript starts with the optional session ID, and then the protocol ID.
+	var sessionID []byte
+	if len(hm.Session) > 0 && hm.Session[0].TheDomain == "Session ID" {
+		sessionID = hm.Session[0].Bytes
+	}
+	info := Info{
+		ProtocolID:       hm.ProtocolID,
+		FinalRoundNumber: hm.FinalRoundNumber,
+		SelfID:           hm.SelfID,
+		PartyIDs:         hm.PartyIDs,
+		Threshold:        hm.Threshold,
+		Group:            group,
+	}
+	restored, err := NewSession(info, sessionID, nil)
+	if err != nil {
+		return fmt.Errorf("helper: %w", err)
+	}
+	if len(hm.Session) < restored.sessionLength {
+		return errors.New("helper: truncated transcript")
+	}
+	for i, entry := range hm.Session[:restored.sessionLength] {
+		if entry.TheDomain != restored.transcript[i].TheDomain || !bytes.Equal(entry.Bytes, restored.transcript[i].Bytes) {
+			return errors.New("helper: transcript doesn't match session information")
+		}
+	}
+	// the remaining entries of the session are the auxiliary information.
+	for _, entry := range hm.Session[restored.sessionLength:] {
+		if err = restored.write(entry); err != nil {
+			return fmt.Errorf("helper: %w", err)
+		}
+	}
+	restored.ssid = restored.hash.Clone().Sum()
+	restored.sessionLength = len(restored.transcript)
+	for _, entry := range hm.Updates {
+		if err = restored.write(entry); err != nil {
+			return fmt.Errorf("helper: %w", err)
+		}
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.info = restored.info
+	h.partyIDs = restored.partyIDs
+	h.otherPartyIDs = restored.otherPartyIDs
+	h.ssid = restored.ssid
+	h.hash = restored.hash
+	h.transcript = restored.transcript
+	h.sessionLength = restored.sessionLength
+	return nil
+}
+
+// groupFromName returns the curve with the given name, or nil for the empty name.
+func groupFromName(name string) (curve.Curve, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case curve.Secp256k1{}.Name():
+		return curve.Secp256k1{}, nil
+	case curve.Edwards25519{}.Name():
+		return curve.Edwards25519{}, nil
+	default:
+		return nil, fmt.Errorf("unknown group %q", name)
+	}
+}
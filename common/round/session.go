@@ -47,3 +47,14 @@ type Session interface {
 	// N returns the total number of parties participating in the protocol.
 	N() int
 }
+
+// Chain is implemented by the rounds of a protocol which, instead of returning an output,
+// continues with the first round of another protocol, with a different SSID.
+//
+// Since other parties may start the next protocol before we do, the handler then keeps the
+// messages for other sessions, and handles them once the next protocol has started.
+type Chain interface {
+	Session
+	// Chained returns true if the protocol is followed by another one.
+	Chained() bool
+}
@@ -0,0 +1,275 @@
+package byzantine
+
+import (
+	"crypto/rand"
+	"encoding"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/cronokirby/saferith"
+)
+
+// CorruptBroadcast changes the field of the broadcast content of a round, so that it is invalid,
+// but still well formed. See Corrupt.
+func CorruptBroadcast(number round.Number, field string) Attack {
+	return Attack{
+		Name:      fmt.Sprintf("corrupt broadcast %d %s", number, field),
+		Round:     number,
+		Broadcast: true,
+		Tamper: func(msg *round.Message, _ party.IDSlice) ([]*round.Message, error) {
+			m, err := corruptCopy(msg, field)
+			return []*round.Message{m}, err
+		},
+	}
+}
+
+// CorruptMessage changes the field of the point-to-point contents of a round sent to the parties in to,
+// or to every party if to is empty. See Corrupt.
+func CorruptMessage(number round.Number, field string, to ...party.ID) Attack {
+	return Attack{
+		Name:  fmt.Sprintf("corrupt message %d %s to %v", number, field, to),
+		Round: number,
+		Tamper: func(msg *round.Message, _ party.IDSlice) ([]*round.Message, error) {
+			if len(to) > 0 && !party.NewIDSlice(to).Contains(msg.To) {
+				return []*round.Message{msg}, nil
+			}
+			m, err := corruptCopy(msg, field)
+			return []*round.Message{m}, err
+		},
+	}
+}
+
+// ReplaceBroadcast sets the field of the broadcast content of a round to value.
+func ReplaceBroadcast(number round.Number, field string, value interface{}) Attack {
+	return Attack{
+		Name:      fmt.Sprintf("replace broadcast %d %s", number, field),
+		Round:     number,
+		Broadcast: true,
+		Tamper: func(msg *round.Message, _ party.IDSlice) ([]*round.Message, error) {
+			m := copyMessage(msg)
+			v, err := Field(m.Content, field)
+			if err != nil {
+				return nil, err
+			}
+			x := reflect.ValueOf(value)
+			if !x.Type().AssignableTo(v.Type()) {
+				return nil, fmt.Errorf("byzantine: can't assign %s to field %s of type %s", x.Type(), field, v.Type())
+			}
+			v.Set(x)
+			return []*round.Message{m}, nil
+		},
+	}
+}
+
+// Equivocate sends a broadcast content of a round whose field is corrupted to the parties in to,
+// and the honest one to the other parties, as separate point-to-point deliveries of the broadcast.
+func Equivocate(number round.Number, field string, to ...party.ID) Attack {
+	return Attack{
+		Name:      fmt.Sprintf("equivocate broadcast %d %s to %v", number, field, to),
+		Round:     number,
+		Broadcast: true,
+		Tamper: func(msg *round.Message, others party.IDSlice) ([]*round.Message, error) {
+			corrupted, err := corruptCopy(msg, field)
+			if err != nil {
+				return nil, err
+			}
+			messages := make([]*round.Message, 0, len(others))
+			for _, id := range others {
+				m := msg
+				if party.NewIDSlice(to).Contains(id) {
+					m = corrupted
+				}
+				m = copyMessage(m)
+				m.To = id
+				messages = append(messages, m)
+			}
+			return messages, nil
+		},
+	}
+}
+
+// copyMessage returns a copy of msg, whose content is a shallow copy of the original one.
+func copyMessage(msg *round.Message) *round.Message {
+	m := *msg
+	content := reflect.ValueOf(msg.Content)
+	if content.Kind() == reflect.Ptr {
+		c := reflect.New(content.Elem().Type())
+		c.Elem().Set(content.Elem())
+		m.Content = c.Interface().(round.Content)
+	}
+	return &m
+}
+
+func corruptCopy(msg *round.Message, field string) (*round.Message, error) {
+	m := copyMessage(msg)
+	if err := Corrupt(m.Content, field); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Field returns the exported field of a content, which must be a pointer to a struct.
+func Field(content round.Content, field string) (reflect.Value, error) {
+	v := reflect.ValueOf(content)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("byzantine: content %T is not a pointer to a struct", content)
+	}
+	f := v.Elem().FieldByName(field)
+	if !f.IsValid() || !f.CanSet() {
+		return reflect.Value{}, fmt.Errorf("byzantine: content %T has no exported field %s", content, field)
+	}
+	return f, nil
+}
+
+// Corrupt changes the field of a content so that it holds a different, but well formed, value.
+//
+// Points are shifted by the generator, scalars and numbers are incremented, byte strings have a bit flipped,
+// and VSS polynomials are replaced by another one of the same degree. Structs, such as zero-knowledge proofs,
+// have their first field which can be corrupted changed, and slices, arrays and maps their first element.
+// The values held by the content are replaced rather than modified, so that the state of the round is untouched.
+func Corrupt(content round.Content, field string) error {
+	v, err := Field(content, field)
+	if err != nil {
+		return err
+	}
+	if !corrupt(v) {
+		return fmt.Errorf("byzantine: can't corrupt field %s of type %s", field, v.Type())
+	}
+	return nil
+}
+
+// corrupt replaces the value of v, which must be settable, and returns false if it doesn't know how to.
+func corrupt(v reflect.Value) bool {
+	if v.Kind() == reflect.Invalid || (isNillable(v) && v.IsNil()) {
+		return false
+	}
+	switch x := v.Interface().(type) {
+	case curve.Point:
+		v.Set(reflect.ValueOf(x.Add(x.Curve().NewBasePoint())))
+		return true
+	case curve.Scalar:
+		one := x.Curve().NewScalar().SetNat(new(saferith.Nat).SetUint64(1))
+		v.Set(reflect.ValueOf(x.Curve().NewScalar().Set(x).Add(one)))
+		return true
+	case *polynomial.Exponent:
+		group := x.Constant().Curve()
+		var constant curve.Scalar
+		if !x.IsConstant {
+			constant = sample.Scalar(rand.Reader, group)
+		}
+		f := polynomial.NewPolynomial(group, x.Degree(), constant)
+		v.Set(reflect.ValueOf(polynomial.NewPolynomialExponent(f)))
+		return true
+	case *big.Int:
+		v.Set(reflect.ValueOf(new(big.Int).Add(x, big.NewInt(1))))
+		return true
+	case *saferith.Nat:
+		v.Set(reflect.ValueOf(new(saferith.Nat).Add(x, new(saferith.Nat).SetUint64(1), -1)))
+		return true
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(!v.Bool())
+		return true
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return false
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			c.Index(0).SetUint(c.Index(0).Uint() ^ 1)
+		} else if !corrupt(c.Index(0)) {
+			return false
+		}
+		v.Set(c)
+		return true
+	case reflect.Array:
+		return v.Len() > 0 && corrupt(v.Index(0))
+	case reflect.Map:
+		keys := v.MapKeys()
+		if len(keys) == 0 {
+			return false
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range keys {
+			c.SetMapIndex(k, v.MapIndex(k))
+		}
+		e := reflect.New(v.Type().Elem()).Elem()
+		e.Set(v.MapIndex(keys[0]))
+		if !corrupt(e) {
+			return false
+		}
+		c.SetMapIndex(keys[0], e)
+		v.Set(c)
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() && corrupt(v.Field(i)) {
+				return true
+			}
+		}
+		return false
+	case reflect.Ptr:
+		if hasExportedFields(v.Elem()) {
+			c := reflect.New(v.Elem().Type())
+			c.Elem().Set(v.Elem())
+			if corrupt(c.Elem()) {
+				v.Set(c)
+				return true
+			}
+		}
+		// values we can't look into are corrupted through their encoding
+		return corruptEncoding(v)
+	}
+	return false
+}
+
+// corruptEncoding flips a bit of the binary encoding of the value pointed to by v.
+func corruptEncoding(v reflect.Value) bool {
+	m, ok := v.Interface().(encoding.BinaryMarshaler)
+	if !ok {
+		return false
+	}
+	data, err := m.MarshalBinary()
+	if err != nil || len(data) == 0 {
+		return false
+	}
+	data[len(data)-1] ^= 1
+	c := reflect.New(v.Elem().Type())
+	u, ok := c.Interface().(encoding.BinaryUnmarshaler)
+	if !ok || u.UnmarshalBinary(data) != nil {
+		return false
+	}
+	v.Set(c)
+	return true
+}
+
+func hasExportedFields(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+func isNillable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+// Package byzantine runs protocols with misbehaving parties, in order to check that every abort path
+// works, and blames the right party.
+//
+// Wrap replaces the rounds of an adversary by rounds which tamper with chosen outgoing contents,
+// before they are marshalled and signed by the handler. Contents are modified through reflection on
+// their exported fields, so attacks can target the unexported content types of every protocol by
+// round number and field name, for instance
+//
+//	byzantine.CorruptBroadcast(3, "VSSPolynomial")
+//
+// for the VSS polynomial broadcast in round 3 of CMP keygen. An Execution then runs all parties over
+// a simulated network, and RequireCulprits checks that the honest parties aborted, blaming the adversaries.
+package byzantine
+
+import (
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// Attack tampers with the messages an adversary sends in a round.
+type Attack struct {
+	// Name describes the attack in test output.
+	Name string
+	// Round is the number of the round the messages are for, as given by Content.RoundNumber().
+	Round round.Number
+	// Broadcast selects the broadcast message of the round, instead of the point-to-point ones.
+	Broadcast bool
+	// Tamper returns the messages to send instead of msg, which is the message produced by the honest round.
+	// others contains the other parties of the protocol.
+	Tamper func(msg *round.Message, others party.IDSlice) ([]*round.Message, error)
+}
+
+// applies returns true if a tampers with msg.
+func (a Attack) applies(msg *round.Message) bool {
+	return msg.Content != nil && msg.Content.RoundNumber() == a.Round && msg.Broadcast == a.Broadcast
+}
+
+// Wrap returns a StartFunc running the protocol of start, but whose outgoing messages go through attacks.
+func Wrap(start protocol.StartFunc, attacks ...Attack) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		r, err := start(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(r, attacks), nil
+	}
+}
+
+// wrap returns r with attacks applied to the messages output by its Finalize.
+// The final rounds are returned as is, since the handler recognizes them by their type.
+func wrap(r round.Session, attacks []Attack) round.Session {
+	switch r.(type) {
+	case *round.Abort, *round.Output:
+		return r
+	}
+	s := &session{Session: r, attacks: attacks}
+	if b, ok := r.(round.BroadcastRound); ok {
+		return &broadcastSession{session: s, broadcast: b}
+	}
+	return s
+}
+
+// session is a round of an adversary.
+type session struct {
+	round.Session
+	attacks []Attack
+}
+
+// Finalize implements round.Round.
+//
+// The messages of the honest round are tampered with before they are sent to out.
+// It fails with round.ErrOutChanFull if the attacks produce more messages than out can hold.
+func (s *session) Finalize(out chan<- *round.Message) (round.Session, error) {
+	honest := make(chan *round.Message, cap(out))
+	next, err := s.Session.Finalize(honest)
+	close(honest)
+	if err != nil {
+		return next, err
+	}
+	for msg := range honest {
+		messages := []*round.Message{msg}
+		for _, a := range s.attacks {
+			if !a.applies(msg) {
+				continue
+			}
+			if messages, err = a.Tamper(msg, s.OtherPartyIDs()); err != nil {
+				return nil, err
+			}
+			break
+		}
+		for _, m := range messages {
+			select {
+			case out <- m:
+			default:
+				return nil, round.ErrOutChanFull
+			}
+		}
+	}
+	return wrap(next, s.attacks), nil
+}
+
+// Chained implements round.Chain, for the protocols which are followed by another one.
+func (s *session) Chained() bool {
+	c, ok := s.Session.(round.Chain)
+	return ok && c.Chained()
+}
+
+// broadcastSession is a round of an adversary which expects broadcast messages.
+type broadcastSession struct {
+	*session
+	broadcast round.BroadcastRound
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+func (s *broadcastSession) StoreBroadcastMessage(msg round.Message) error {
+	return s.broadcast.StoreBroadcastMessage(msg)
+}
+
+// BroadcastContent implements round.BroadcastRound.
+func (s *broadcastSession) BroadcastContent() round.BroadcastContent {
+	return s.broadcast.BroadcastContent()
+}
@@ -0,0 +1,179 @@
+package byzantine
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/cmp"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost/sign"
+	"github.com/stretchr/testify/require"
+)
+
+func identities(t *testing.T, partyIDs []party.ID) map[party.ID]*protocol.Identities {
+	keys := make(map[party.ID]protocol.IdentityKey, len(partyIDs))
+	public := make(map[party.ID]protocol.IdentityPublicKey, len(partyIDs))
+	for _, id := range partyIDs {
+		_, sk, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[id] = protocol.Ed25519Identity(sk)
+		public[id] = keys[id].Public()
+	}
+	ids := make(map[party.ID]*protocol.Identities, len(partyIDs))
+	for _, id := range partyIDs {
+		ids[id] = &protocol.Identities{Key: keys[id], Keys: public}
+	}
+	return ids
+}
+
+func TestFrostKeygen(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	adversary, honest := partyIDs[0], partyIDs[1:]
+	start := func(id party.ID) protocol.StartFunc {
+		return frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1)
+	}
+
+	for _, tc := range []struct {
+		attacks []Attack
+		// culprits is empty if the honest parties should recover from the attack.
+		culprits []party.ID
+	}{
+		{[]Attack{CorruptBroadcast(2, "Phi_i")}, []party.ID{adversary}},
+		{[]Attack{CorruptBroadcast(2, "Sigma_i")}, []party.ID{adversary}},
+		{[]Attack{CorruptBroadcast(3, "Decommitment")}, []party.ID{adversary}},
+		{[]Attack{Equivocate(2, "Commitment", honest[0])}, []party.ID{adversary}},
+		// a wrong share is complained about, and justified publicly in round 5.
+		{[]Attack{CorruptMessage(3, "F_li", honest[0])}, nil},
+		{[]Attack{CorruptMessage(3, "F_li", honest[0]), CorruptBroadcast(5, "Shares")}, []party.ID{adversary}},
+		// a complaint against an honest party is unfounded, but the adversary doesn't justify it in round 5,
+		// since it didn't complain itself.
+		{[]Attack{ReplaceBroadcast(4, "Complaints", []party.ID{honest[0]})}, []party.ID{adversary}},
+	} {
+		name := tc.attacks[0].Name
+		t.Run(name, func(t *testing.T) {
+			e := &Execution{
+				PartyIDs:   partyIDs,
+				Start:      start,
+				Attacks:    map[party.ID][]Attack{adversary: tc.attacks},
+				Identities: identities(t, partyIDs),
+			}
+			handlers, err := e.Run()
+			require.NoError(t, err)
+			if tc.culprits == nil {
+				RequireSuccess(t, handlers, e.Honest())
+			} else {
+				RequireCulprits(t, handlers, e.Honest(), tc.culprits...)
+			}
+		})
+	}
+}
+
+func TestCMPKeygen(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	adversary := partyIDs[0]
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	start := func(id party.ID) protocol.StartFunc {
+		return cmp.Keygen(curve.Secp256k1{}, id, partyIDs, 1, pl)
+	}
+
+	for _, attack := range []Attack{
+		CorruptBroadcast(3, "VSSPolynomial"),
+		CorruptBroadcast(3, "Decommitment"),
+		CorruptBroadcast(4, "Mod"),
+	} {
+		t.Run(attack.Name, func(t *testing.T) {
+			e := &Execution{
+				PartyIDs: partyIDs,
+				Start:    start,
+				Attacks:  map[party.ID][]Attack{adversary: {attack}},
+			}
+			handlers, err := e.Run()
+			require.NoError(t, err)
+			RequireCulprits(t, handlers, e.Honest(), adversary)
+		})
+	}
+}
+
+func TestFrostSign(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+	adversary := partyIDs[0]
+	message := []byte("hello")
+
+	secret := sample.Scalar(rand.Reader, group)
+	f := polynomial.NewPolynomial(group, 1, secret)
+	verificationShares := make(map[party.ID]curve.Point, len(partyIDs))
+	for _, id := range partyIDs {
+		verificationShares[id] = f.Evaluate(id.Scalar(group)).ActOnBase()
+	}
+	start := func(id party.ID) protocol.StartFunc {
+		config := &frost.Config{
+			ID:                 id,
+			Threshold:          1,
+			PublicKey:          secret.ActOnBase(),
+			PrivateShare:       f.Evaluate(id.Scalar(group)),
+			VerificationShares: party.NewPointMap(verificationShares),
+		}
+		return frost.Sign(config, partyIDs, message, sign.ProtocolDefault)
+	}
+
+	for _, attack := range []Attack{
+		CorruptBroadcast(2, "D_i"),
+		// the response doesn't match the commitments and the verification share of the adversary.
+		CorruptBroadcast(3, "Z_i"),
+	} {
+		t.Run(attack.Name, func(t *testing.T) {
+			e := &Execution{
+				PartyIDs:   partyIDs,
+				Start:      start,
+				Attacks:    map[party.ID][]Attack{adversary: {attack}},
+				Identities: identities(t, partyIDs),
+			}
+			handlers, err := e.Run()
+			require.NoError(t, err)
+			RequireCulprits(t, handlers, e.Honest(), adversary)
+		})
+	}
+}
+
+func TestCMPSign(t *testing.T) {
+	group := curve.Secp256k1{}
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	configs, partyIDs := test.GenerateConfig(group, 3, 2, rand.Reader, pl)
+	adversary := partyIDs[0]
+	message := []byte("hello")
+	start := func(id party.ID) protocol.StartFunc {
+		return cmp.Sign(configs[id], partyIDs, message, pl)
+	}
+
+	for _, attack := range []Attack{
+		// the proof that Kᵢ encrypts a small enough kᵢ.
+		CorruptMessage(2, "ProofEnc"),
+		// the proof that Γᵢ = [γᵢ]•G, where γᵢ is the plaintext of Gᵢ.
+		CorruptMessage(3, "ProofLog"),
+		// the proof that Δᵢ = [kᵢ]•Γ, where kᵢ is the plaintext of Kᵢ.
+		CorruptMessage(4, "ProofLog"),
+	} {
+		t.Run(attack.Name, func(t *testing.T) {
+			e := &Execution{
+				PartyIDs:   partyIDs,
+				Start:      start,
+				Attacks:    map[party.ID][]Attack{adversary: {attack}},
+				Identities: identities(t, partyIDs),
+			}
+			handlers, err := e.Run()
+			require.NoError(t, err)
+			RequireCulprits(t, handlers, e.Honest(), adversary)
+		})
+	}
+}
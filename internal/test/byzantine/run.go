@@ -0,0 +1,138 @@
+package byzantine
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/transport/simnet"
+	"github.com/stretchr/testify/require"
+)
+
+// Execution runs a protocol between honest parties and adversaries.
+type Execution struct {
+	// PartyIDs are the participants of the protocol.
+	PartyIDs party.IDSlice
+	// Start returns the StartFunc of party id, before it is wrapped for adversaries.
+	Start func(id party.ID) protocol.StartFunc
+	// Attacks maps every adversary to its attacks.
+	Attacks map[party.ID][]Attack
+	// Identities authenticates the messages of every party, if set.
+	// Culprits can only be identified when broadcast messages are signed.
+	Identities map[party.ID]*protocol.Identities
+	// Seed is given to the simnet.Network over which the parties run.
+	Seed int64
+}
+
+// Honest returns the parties without attacks.
+func (e *Execution) Honest() party.IDSlice {
+	var honest []party.ID
+	for _, id := range e.PartyIDs {
+		if _, ok := e.Attacks[id]; !ok {
+			honest = append(honest, id)
+		}
+	}
+	return party.NewIDSlice(honest)
+}
+
+// Adversaries returns the parties with attacks.
+func (e *Execution) Adversaries() party.IDSlice {
+	adversaries := make([]party.ID, 0, len(e.Attacks))
+	for id := range e.Attacks {
+		adversaries = append(adversaries, id)
+	}
+	return party.NewIDSlice(adversaries)
+}
+
+// ErrTimeout is the cause of the aborts of the honest parties which time out.
+var ErrTimeout = errors.New("byzantine: timed out")
+
+// Run runs the protocol over a simnet.Network until every honest party is done, and returns the handlers.
+//
+// Adversaries may leave the honest parties waiting for messages which will never arrive, for instance
+// by finishing early, since their state is inconsistent with what they sent. When the execution stalls,
+// the honest parties which are waiting time out, as if their round timeout had expired, and abort blaming
+// the parties they are missing messages from. Adversaries which are still waiting are left alone.
+func (e *Execution) Run() (map[party.ID]*protocol.MultiHandler, error) {
+	n := simnet.New(e.Seed)
+	handlers := make(map[party.ID]*protocol.MultiHandler, len(e.PartyIDs))
+	timeouts := make(map[party.ID]context.CancelCauseFunc, len(e.PartyIDs))
+	for _, id := range e.PartyIDs {
+		start := e.Start(id)
+		if attacks, ok := e.Attacks[id]; ok {
+			start = Wrap(start, attacks...)
+		}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		h, err := protocol.NewMultiHandler(start, nil, protocol.WithContext(ctx), protocol.WithIdentities(e.Identities[id]))
+		if err != nil {
+			cancel(nil)
+			return nil, err
+		}
+		handlers[id] = h
+		timeouts[id] = cancel
+		n.Add(id, h)
+	}
+	defer func() {
+		for _, cancel := range timeouts {
+			cancel(nil)
+		}
+	}()
+
+	err := n.Run()
+	if !errors.Is(err, simnet.ErrStalled) {
+		return handlers, err
+	}
+	honest := e.Honest()
+	for _, id := range n.Waiting() {
+		if !honest.Contains(id) {
+			continue
+		}
+		timeouts[id](ErrTimeout)
+		// the handler aborts asynchronously, and is done once it closes its channel.
+		for range handlers[id].Listen() {
+		}
+	}
+	return handlers, nil
+}
+
+// Blamed returns the parties blamed by a party whose protocol failed with err.
+//
+// A party which accepted the abort of another party blames the parties which the aborting party blamed,
+// as given by the protocol.AbortError, rather than the aborting party itself.
+func Blamed(err error) []party.ID {
+	var aborted *protocol.AbortError
+	if errors.As(err, &aborted) {
+		return aborted.Culprits
+	}
+	var failed protocol.Error
+	if errors.As(err, &failed) {
+		return failed.Culprits
+	}
+	return nil
+}
+
+// RequireCulprits checks that every honest party failed, blaming exactly culprits.
+func RequireCulprits(t require.TestingT, handlers map[party.ID]*protocol.MultiHandler, honest []party.ID, culprits ...party.ID) {
+	expected := sorted(culprits)
+	for _, id := range honest {
+		_, err := handlers[id].Result()
+		require.Error(t, err, "party %s should have aborted", id)
+		require.Equal(t, expected, sorted(Blamed(err)), "culprits of party %s: %v", id, err)
+	}
+}
+
+// RequireSuccess checks that every honest party obtained a result, despite the attacks.
+func RequireSuccess(t require.TestingT, handlers map[party.ID]*protocol.MultiHandler, honest []party.ID) {
+	for _, id := range honest {
+		_, err := handlers[id].Result()
+		require.NoError(t, err, "party %s", id)
+	}
+}
+
+func sorted(ids []party.ID) []party.ID {
+	s := append([]party.ID{}, ids...)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s
+}
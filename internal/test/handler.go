@@ -25,3 +25,44 @@ func HandlerLoop(id party.ID, h protocol.Handler, network *Network) {
 		}
 	}
 }
+
+// RestoringHandlerLoop is like HandlerLoop, but the handler is saved with MultiHandler.Snapshot
+// after every message it accepts, and replaced by the restored handler.
+//
+// It returns the last handler, which holds the result of the execution.
+func RestoringHandlerLoop(id party.ID, h *protocol.MultiHandler, network *Network, restore protocol.RestoreFunc) (*protocol.MultiHandler, error) {
+	key := make([]byte, protocol.SnapshotKeySize)
+	for {
+		select {
+		case msg, ok := <-h.Listen():
+			if !ok {
+				<-network.Done(id)
+				return h, nil
+			}
+			go network.Send(msg)
+
+		case msg := <-network.Next(id):
+			h.Accept(msg)
+			// all the messages produced by Accept must be sent before taking a snapshot.
+			for sent := false; !sent; {
+				select {
+				case msg, ok := <-h.Listen():
+					if !ok {
+						<-network.Done(id)
+						return h, nil
+					}
+					go network.Send(msg)
+				default:
+					sent = true
+				}
+			}
+			data, err := h.Snapshot(key)
+			if err != nil {
+				return h, err
+			}
+			if h, err = protocol.RestoreMultiHandler(restore, data, key); err != nil {
+				return h, err
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package ecdsa
+
+import (
+	"errors"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+)
+
+// DLEQProof is a Chaum-Pedersen proof that two points share the same discrete logarithm
+// with respect to two different generators, i.e. that RG = [w]G and R = [w]Y for some w.
+//
+// The proof claims to satisfy:
+//
+//	Z•G = A + e•RG
+//	Z•Y = B + e•R
+//
+// where e = H(Y, R, RG, A, B).
+type DLEQProof struct {
+	A curve.Point
+	B curve.Point
+	Z curve.Scalar
+}
+
+// EmptyDLEQProof returns a new proof with a given curve, ready to be unmarshalled.
+func EmptyDLEQProof(group curve.Curve) *DLEQProof {
+	return &DLEQProof{A: group.NewPoint(), B: group.NewPoint(), Z: group.NewScalar()}
+}
+
+// DLEQChallenge computes the challenge e = H(Y, R, RG, A, B) of a DLEQProof.
+//
+// This is exposed so that the proof can be computed jointly by several parties,
+// each holding an additive share of the witness.
+func DLEQChallenge(Y, R, RG, A, B curve.Point) curve.Scalar {
+	h := hash.New()
+	_ = h.WriteAny(Y, R, RG, A, B)
+	return sample.Scalar(h.Digest(), Y.Curve())
+}
+
+// Verify checks that RG = [w]G and R = [w]Y for the same w.
+func (p *DLEQProof) Verify(Y, R, RG curve.Point) bool {
+	if p == nil || p.A == nil || p.B == nil || p.Z == nil {
+		return false
+	}
+	if p.A.IsIdentity() || p.B.IsIdentity() {
+		return false
+	}
+	e := DLEQChallenge(Y, R, RG, p.A, p.B)
+	if !p.Z.ActOnBase().Equal(e.Act(RG).Add(p.A)) {
+		return false
+	}
+	return p.Z.Act(Y).Equal(e.Act(R).Add(p.B))
+}
+
+// AdaptorSignature is an ECDSA pre-signature, encrypted under an adaptor point Y = [y]G.
+//
+// With the same conventions as Signature, for a nonce k, we have:
+//
+//	RG = [k⁻¹]G
+//	R  = [k⁻¹]Y
+//	S  = k(m + r⋅x), where r = R|ₓ
+//
+// Proof shows that RG and R share the same discrete logarithm, so that
+// decrypting S with y yields a valid Signature with nonce point R.
+type AdaptorSignature struct {
+	// R is the nonce point of the decrypted signature.
+	R curve.Point
+	// RG is the nonce point of the pre-signature itself.
+	RG curve.Point
+	// S is the encrypted signature scalar.
+	S curve.Scalar
+	// Proof shows that log_G(RG) = log_Y(R).
+	Proof *DLEQProof
+}
+
+// EmptyAdaptorSignature returns a new pre-signature with a given curve, ready to be unmarshalled.
+func EmptyAdaptorSignature(group curve.Curve) AdaptorSignature {
+	return AdaptorSignature{
+		R:     group.NewPoint(),
+		RG:    group.NewPoint(),
+		S:     group.NewScalar(),
+		Proof: EmptyDLEQProof(group),
+	}
+}
+
+// Verify checks that the pre-signature decrypts to a valid signature of hash under the public key X,
+// once the discrete logarithm of the adaptor point Y is known.
+func (sig AdaptorSignature) Verify(X, Y curve.Point, hash []byte) bool {
+	group := X.Curve()
+
+	if sig.R == nil || sig.RG == nil || sig.S == nil {
+		return false
+	}
+	if sig.R.IsIdentity() || sig.RG.IsIdentity() || Y.IsIdentity() {
+		return false
+	}
+
+	r := sig.R.XScalar()
+	if r.IsZero() || sig.S.IsZero() {
+		return false
+	}
+
+	// RG = [S⁻¹](m•G + r•X)
+	m := curve.FromHash(group, hash)
+	sInv := group.NewScalar().Set(sig.S).Invert()
+	RG := sInv.Act(m.ActOnBase().Add(r.Act(X)))
+	if !RG.Equal(sig.RG) {
+		return false
+	}
+
+	return sig.Proof.Verify(Y, sig.R, sig.RG)
+}
+
+// Decrypt uses the discrete logarithm y of the adaptor point to produce the final Signature.
+func (sig AdaptorSignature) Decrypt(y curve.Scalar) *Signature {
+	yInv := y.Curve().NewScalar().Set(y).Invert()
+	return &Signature{
+		R: sig.R,
+		S: yInv.Mul(sig.S),
+	}
+}
+
+// RecoverDecryptionKey extracts the discrete logarithm y of the adaptor point Y,
+// from a Signature that was decrypted from this pre-signature.
+//
+// Since the signature scalar may have been negated after decryption (e.g. to produce
+// a low-S signature), both possibilities are checked against Y.
+func (sig AdaptorSignature) RecoverDecryptionKey(final *Signature, Y curve.Point) (curve.Scalar, error) {
+	if final == nil || final.S == nil || final.S.IsZero() {
+		return nil, errors.New("ecdsa: invalid signature")
+	}
+	if !final.R.XScalar().Equal(sig.R.XScalar()) {
+		return nil, errors.New("ecdsa: signature doesn't match adaptor signature")
+	}
+	group := Y.Curve()
+	// y = S' ⋅ s⁻¹
+	y := group.NewScalar().Set(final.S).Invert().Mul(sig.S)
+	if y.ActOnBase().Equal(Y) {
+		return y, nil
+	}
+	y.Negate()
+	if y.ActOnBase().Equal(Y) {
+		return y, nil
+	}
+	return nil, errors.New("ecdsa: recovered key doesn't match adaptor point")
+}
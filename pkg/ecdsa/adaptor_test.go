@@ -0,0 +1,64 @@
+package ecdsa
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAdaptorSignature creates an adaptor signature with the full secret key x, for Y = [y]G.
+func newAdaptorSignature(x curve.Scalar, Y curve.Point, hash []byte) *AdaptorSignature {
+	group := x.Curve()
+
+	k := sample.Scalar(rand.Reader, group)
+	m := curve.FromHash(group, hash)
+	kInv := group.NewScalar().Set(k).Invert()
+	RG := kInv.ActOnBase()
+	R := kInv.Act(Y)
+	r := R.XScalar()
+	s := r.Mul(x).Add(m).Mul(k)
+
+	a := sample.Scalar(rand.Reader, group)
+	A := a.ActOnBase()
+	B := a.Act(Y)
+	e := DLEQChallenge(Y, R, RG, A, B)
+	z := e.Mul(kInv).Add(a)
+	return &AdaptorSignature{
+		R:     R,
+		RG:    RG,
+		S:     s,
+		Proof: &DLEQProof{A: A, B: B, Z: z},
+	}
+}
+
+func TestAdaptorSignature(t *testing.T) {
+	group := curve.Secp256k1{}
+
+	m := []byte("hello")
+	x := sample.Scalar(rand.Reader, group)
+	X := x.ActOnBase()
+	y := sample.Scalar(rand.Reader, group)
+	Y := y.ActOnBase()
+
+	preSignature := newAdaptorSignature(x, Y, m)
+	require.True(t, preSignature.Verify(X, Y, m), "expected valid adaptor signature")
+	assert.False(t, preSignature.Verify(X, X, m), "expected invalid adaptor point")
+	assert.False(t, preSignature.Verify(X, Y, []byte("world")), "expected invalid message")
+
+	signature := preSignature.Decrypt(y)
+	require.True(t, signature.Verify(X, m), "expected valid decrypted signature")
+
+	recovered, err := preSignature.RecoverDecryptionKey(signature, Y)
+	require.NoError(t, err)
+	assert.True(t, recovered.Equal(y))
+
+	// a low-S normalized signature still reveals y
+	signature.S.Negate()
+	recovered, err = preSignature.RecoverDecryptionKey(signature, Y)
+	require.NoError(t, err)
+	assert.True(t, recovered.Equal(y))
+}
@@ -2,9 +2,11 @@ package polynomial
 
 import (
 	"crypto/rand"
+	"errors"
 
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/fxamacker/cbor/v2"
 )
 
 // Polynomial represents f(X) = a₀ + a₁⋅X + … + aₜ⋅Xᵗ.
@@ -59,3 +61,35 @@ func (p *Polynomial) Constant() curve.Scalar {
 func (p *Polynomial) Degree() uint32 {
 	return uint32(len(p.coefficients)) - 1
 }
+
+// EmptyPolynomial creates an empty Polynomial with a fixed group, ready to be unmarshalled.
+func EmptyPolynomial(group curve.Curve) *Polynomial {
+	return &Polynomial{group: group}
+}
+
+func (p *Polynomial) MarshalBinary() ([]byte, error) {
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	return enc.Marshal(p.coefficients)
+}
+
+func (p *Polynomial) UnmarshalBinary(data []byte) error {
+	if p == nil || p.group == nil {
+		return errors.New("can't unmarshal Polynomial with no group")
+	}
+	var rawCoefficients []cbor.RawMessage
+	if err := cbor.Unmarshal(data, &rawCoefficients); err != nil {
+		return err
+	}
+	if len(rawCoefficients) == 0 {
+		return errors.New("can't unmarshal Polynomial with no coefficients")
+	}
+	coefficients := make([]curve.Scalar, len(rawCoefficients))
+	for i, raw := range rawCoefficients {
+		coefficients[i] = p.group.NewScalar()
+		if err := cbor.Unmarshal(raw, coefficients[i]); err != nil {
+			return err
+		}
+	}
+	p.coefficients = coefficients
+	return nil
+}
@@ -0,0 +1,298 @@
+package observe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the histograms of Metrics.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60}
+
+// Names of the metrics.
+const (
+	MessagesReceived     = "mpsig_messages_received_total"
+	MessageBytesReceived = "mpsig_message_bytes_received_total"
+	MessagesSent         = "mpsig_messages_sent_total"
+	MessageBytesSent     = "mpsig_message_bytes_sent_total"
+	MessagesInvalid      = "mpsig_messages_invalid_total"
+	MessagesRejected     = "mpsig_messages_rejected_total"
+	SessionsAborted      = "mpsig_sessions_aborted_total"
+	SessionsFinished     = "mpsig_sessions_finished_total"
+	RoundDuration        = "mpsig_round_duration_seconds"
+	FinalizeDuration     = "mpsig_round_finalize_duration_seconds"
+	VerifyDuration       = "mpsig_message_verification_duration_seconds"
+)
+
+var help = map[string]string{
+	MessagesReceived:     "Number of messages accepted by handlers.",
+	MessageBytesReceived: "Size of the content of the messages accepted by handlers.",
+	MessagesSent:         "Number of messages sent by handlers.",
+	MessageBytesSent:     "Size of the content of the messages sent by handlers.",
+	MessagesInvalid:      "Number of messages which failed verification.",
+	MessagesRejected:     "Number of messages dropped by handlers, by reason.",
+	SessionsAborted:      "Number of protocol executions which aborted.",
+	SessionsFinished:     "Number of protocol executions which output a result.",
+	RoundDuration:        "Time between the start of a round and the end of its finalization.",
+	FinalizeDuration:     "Time spent finalizing a round, once all its messages were received.",
+	VerifyDuration:       "Time spent verifying and storing a message.",
+}
+
+// Metrics is a protocol.Observer counting messages, sessions and the duration of rounds, by protocol.
+//
+// It implements http.Handler, serving the metrics in the Prometheus text format, so that it can be scraped directly.
+type Metrics struct {
+	buckets    []float64
+	mtx        sync.Mutex
+	counters   map[series]float64
+	histograms map[series]*histogram
+	started    map[roundKey]time.Time
+}
+
+var _ protocol.Observer = (*Metrics)(nil)
+
+// series is a metric with its labels, formatted as in the exposition format.
+type series struct {
+	name, labels string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewMetrics returns an empty Metrics, whose histograms use the given buckets, or DefaultBuckets if none are given.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets:    buckets,
+		counters:   map[series]float64{},
+		histograms: map[series]*histogram{},
+		started:    map[roundKey]time.Time{},
+	}
+}
+
+// newSeries formats labels, given as name/value pairs, sorted by name.
+func newSeries(name string, labels ...string) series {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	sort.Strings(pairs)
+	return series{name: name, labels: strings.Join(pairs, ",")}
+}
+
+func messageLabels(s protocol.SessionInfo, msg *protocol.Message) []string {
+	return []string{
+		"protocol", s.ProtocolID,
+		"round", strconv.Itoa(int(msg.RoundNumber)),
+		"broadcast", strconv.FormatBool(msg.Broadcast),
+	}
+}
+
+func roundLabels(s protocol.SessionInfo, number round.Number) []string {
+	return []string{"protocol", s.ProtocolID, "round", strconv.Itoa(int(number))}
+}
+
+func (m *Metrics) add(value float64, name string, labels ...string) {
+	m.counters[newSeries(name, labels...)] += value
+}
+
+func (m *Metrics) observe(d time.Duration, name string, labels ...string) {
+	key := newSeries(name, labels...)
+	h := m.histograms[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.histograms[key] = h
+	}
+	v := d.Seconds()
+	for i, bound := range m.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Counter returns the value of a counter, with labels given as name/value pairs.
+func (m *Metrics) Counter(name string, labels ...string) float64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.counters[newSeries(name, labels...)]
+}
+
+// Histogram returns the number of observations of a histogram and their sum in seconds,
+// with labels given as name/value pairs.
+func (m *Metrics) Histogram(name string, labels ...string) (uint64, float64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	h := m.histograms[newSeries(name, labels...)]
+	if h == nil {
+		return 0, 0
+	}
+	return h.count, h.sum
+}
+
+// RoundStarted implements protocol.Observer.
+func (m *Metrics) RoundStarted(s protocol.SessionInfo, number round.Number) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.started[newRoundKey(s, number)] = time.Now()
+}
+
+// RoundFinished implements protocol.Observer.
+func (m *Metrics) RoundFinished(s protocol.SessionInfo, number round.Number, finalize time.Duration) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	labels := roundLabels(s, number)
+	m.observe(finalize, FinalizeDuration, labels...)
+	key := newRoundKey(s, number)
+	if start, ok := m.started[key]; ok {
+		m.observe(time.Since(start), RoundDuration, labels...)
+		delete(m.started, key)
+	}
+}
+
+// MessageReceived implements protocol.Observer.
+func (m *Metrics) MessageReceived(s protocol.SessionInfo, msg *protocol.Message) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.add(1, MessagesReceived, messageLabels(s, msg)...)
+	m.add(float64(len(msg.Data)), MessageBytesReceived, "protocol", s.ProtocolID)
+}
+
+// MessageRejected implements protocol.Observer.
+func (m *Metrics) MessageRejected(s protocol.SessionInfo, _ *protocol.Message, reason protocol.RejectReason) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.add(1, MessagesRejected, "protocol", s.ProtocolID, "reason", reason.String())
+}
+
+// MessageSent implements protocol.Observer.
+func (m *Metrics) MessageSent(s protocol.SessionInfo, msg *protocol.Message) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.add(1, MessagesSent, messageLabels(s, msg)...)
+	m.add(float64(len(msg.Data)), MessageBytesSent, "protocol", s.ProtocolID)
+}
+
+// MessageVerified implements protocol.Observer.
+func (m *Metrics) MessageVerified(s protocol.SessionInfo, msg *protocol.Message, d time.Duration, err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	labels := roundLabels(s, msg.RoundNumber)
+	m.observe(d, VerifyDuration, labels...)
+	if err != nil {
+		m.add(1, MessagesInvalid, labels...)
+	}
+}
+
+// Aborted implements protocol.Observer.
+func (m *Metrics) Aborted(s protocol.SessionInfo, _ protocol.Error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.add(1, SessionsAborted, "protocol", s.ProtocolID)
+	m.forget(s)
+}
+
+// Finished implements protocol.Observer.
+func (m *Metrics) Finished(s protocol.SessionInfo, _ interface{}) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.add(1, SessionsFinished, "protocol", s.ProtocolID)
+	m.forget(s)
+}
+
+// forget deletes the start times of the rounds of a session which is done.
+func (m *Metrics) forget(s protocol.SessionInfo) {
+	for key := range m.started {
+		if key.sameSession(s) {
+			delete(m.started, key)
+		}
+	}
+}
+
+// WriteTo writes all metrics to w in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mtx.Lock()
+	buf := new(bytes.Buffer)
+	typed := map[string]bool{}
+	header := func(name, kind string) {
+		if !typed[name] {
+			typed[name] = true
+			fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help[name], name, kind)
+		}
+	}
+	withLabel := func(labels, extra string) string {
+		if labels == "" {
+			return "{" + extra + "}"
+		}
+		return "{" + labels + "," + extra + "}"
+	}
+
+	counters := make([]series, 0, len(m.counters))
+	for s := range m.counters {
+		counters = append(counters, s)
+	}
+	sortSeries(counters)
+	for _, s := range counters {
+		header(s.name, "counter")
+		fmt.Fprintf(buf, "%s{%s} %s\n", s.name, s.labels, formatFloat(m.counters[s]))
+	}
+
+	histograms := make([]series, 0, len(m.histograms))
+	for s := range m.histograms {
+		histograms = append(histograms, s)
+	}
+	sortSeries(histograms)
+	for _, s := range histograms {
+		header(s.name, "histogram")
+		h := m.histograms[s]
+		for i, bound := range m.buckets {
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", s.name, withLabel(s.labels, `le="`+formatFloat(bound)+`"`), h.counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", s.name, withLabel(s.labels, `le="+Inf"`), h.count)
+		fmt.Fprintf(buf, "%s_sum{%s} %s\n", s.name, s.labels, formatFloat(h.sum))
+		fmt.Fprintf(buf, "%s_count{%s} %d\n", s.name, s.labels, h.count)
+	}
+	m.mtx.Unlock()
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP implements http.Handler.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = m.WriteTo(w)
+}
+
+func sortSeries(s []series) {
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].name != s[j].name {
+			return s[i].name < s[j].name
+		}
+		return s[i].labels < s[j].labels
+	})
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
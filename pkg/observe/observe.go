@@ -0,0 +1,84 @@
+// Package observe contains implementations of protocol.Observer, which export Prometheus-style metrics
+// and OpenTelemetry-style traces of protocol executions.
+//
+// Neither depends on a client library: Metrics is served in the Prometheus text format, and the spans
+// of a Tracer are given to a SpanExporter, which can forward them to any backend.
+package observe
+
+import (
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// multi notifies several observers.
+type multi []protocol.Observer
+
+// Multi returns an Observer notifying all the given observers, in order.
+func Multi(observers ...protocol.Observer) protocol.Observer {
+	return multi(observers)
+}
+
+func (m multi) RoundStarted(s protocol.SessionInfo, number round.Number) {
+	for _, o := range m {
+		o.RoundStarted(s, number)
+	}
+}
+
+func (m multi) RoundFinished(s protocol.SessionInfo, number round.Number, finalize time.Duration) {
+	for _, o := range m {
+		o.RoundFinished(s, number, finalize)
+	}
+}
+
+func (m multi) MessageReceived(s protocol.SessionInfo, msg *protocol.Message) {
+	for _, o := range m {
+		o.MessageReceived(s, msg)
+	}
+}
+
+func (m multi) MessageRejected(s protocol.SessionInfo, msg *protocol.Message, reason protocol.RejectReason) {
+	for _, o := range m {
+		o.MessageRejected(s, msg, reason)
+	}
+}
+
+func (m multi) MessageSent(s protocol.SessionInfo, msg *protocol.Message) {
+	for _, o := range m {
+		o.MessageSent(s, msg)
+	}
+}
+
+func (m multi) MessageVerified(s protocol.SessionInfo, msg *protocol.Message, d time.Duration, err error) {
+	for _, o := range m {
+		o.MessageVerified(s, msg, d, err)
+	}
+}
+
+func (m multi) Aborted(s protocol.SessionInfo, err protocol.Error) {
+	for _, o := range m {
+		o.Aborted(s, err)
+	}
+}
+
+func (m multi) Finished(s protocol.SessionInfo, result interface{}) {
+	for _, o := range m {
+		o.Finished(s, result)
+	}
+}
+
+// roundKey identifies a round of a protocol execution by a party.
+type roundKey struct {
+	self, protocol, ssid string
+	number               round.Number
+}
+
+func newRoundKey(s protocol.SessionInfo, number round.Number) roundKey {
+	return roundKey{self: string(s.SelfID), protocol: s.ProtocolID, ssid: string(s.SSID), number: number}
+}
+
+// sameSession returns true if k is a round of the protocol execution s.
+func (k roundKey) sameSession(s protocol.SessionInfo) bool {
+	return k.self == string(s.SelfID) && k.protocol == s.ProtocolID && k.ssid == string(s.SSID)
+}
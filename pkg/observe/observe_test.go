@@ -0,0 +1,114 @@
+package observe
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObservers(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	metrics := NewMetrics()
+	recorder := &SpanRecorder{}
+	observer := Multi(metrics, NewTracer(recorder))
+
+	network := test.NewNetwork(partyIDs)
+	var protocolID string
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithObserver(observer))
+			require.NoError(t, err)
+			mtx.Lock()
+			protocolID = h.ProtocolID()
+			mtx.Unlock()
+			test.HandlerLoop(id, h, network)
+			_, err = h.Result()
+			require.NoError(t, err)
+		}(id)
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 3, metrics.Counter(SessionsFinished, "protocol", protocolID))
+	assert.Zero(t, metrics.Counter(SessionsAborted, "protocol", protocolID))
+	// each party receives the round 2 broadcast of the two others.
+	assert.EqualValues(t, 6, metrics.Counter(MessagesReceived, "protocol", protocolID, "round", "2", "broadcast", "true"))
+	assert.EqualValues(t, 3, metrics.Counter(MessagesSent, "protocol", protocolID, "round", "2", "broadcast", "true"))
+	assert.NotZero(t, metrics.Counter(MessageBytesSent, "protocol", protocolID))
+	count, _ := metrics.Histogram(RoundDuration, "protocol", protocolID, "round", "1")
+	assert.EqualValues(t, 3, count)
+	count, _ = metrics.Histogram(VerifyDuration, "protocol", protocolID, "round", "2")
+	assert.EqualValues(t, 6, count)
+
+	buf := new(bytes.Buffer)
+	_, err := metrics.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "# TYPE "+RoundDuration+" histogram\n")
+	assert.Contains(t, buf.String(), RoundDuration+`_bucket{protocol="`+protocolID+`",round="1",le="+Inf"} 3`+"\n")
+	assert.Contains(t, buf.String(), SessionsFinished+`{protocol="`+protocolID+`"} 3`+"\n")
+
+	spans := recorder.Spans()
+	roots := map[[8]byte]*Span{}
+	for _, span := range spans {
+		if span.ParentSpanID == ([8]byte{}) {
+			roots[span.SpanID] = span
+		}
+	}
+	require.Len(t, roots, 3, "there should be a root span per party")
+	byID := map[[8]byte]*Span{}
+	for _, span := range spans {
+		byID[span.SpanID] = span
+		assert.Equal(t, spans[0].TraceID, span.TraceID, "all parties should share the same trace")
+		assert.False(t, span.End.Before(span.Start))
+		assert.NoError(t, span.Err)
+	}
+	finalized := 0
+	for _, span := range spans {
+		if span.Name != "finalize" {
+			continue
+		}
+		finalized++
+		parent := byID[span.ParentSpanID]
+		require.NotNil(t, parent)
+		assert.Contains(t, parent.Attributes, AttributeRound)
+		assert.Contains(t, roots, parent.ParentSpanID)
+	}
+	assert.NotZero(t, finalized)
+}
+
+func TestObserverAbort(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	metrics := NewMetrics()
+	recorder := &SpanRecorder{}
+	observer := Multi(metrics, NewTracer(recorder))
+
+	h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, partyIDs[0], partyIDs, 1), nil, protocol.WithObserver(observer))
+	require.NoError(t, err)
+	h.Stop()
+	for range h.Listen() {
+	}
+	h.Accept(&protocol.Message{From: partyIDs[1], Protocol: h.ProtocolID(), SSID: h.SSID(), RoundNumber: 2, Data: []byte{}})
+	assert.EqualValues(t, 1, metrics.Counter(MessagesRejected, "protocol", h.ProtocolID(), "reason", "finished"))
+
+	assert.EqualValues(t, 1, metrics.Counter(SessionsAborted, "protocol", h.ProtocolID()))
+	// the other parties are notified of the abort with a message for the round which failed.
+	assert.EqualValues(t, 1, metrics.Counter(MessagesSent, "protocol", h.ProtocolID(), "round", "2", "broadcast", "false"))
+
+	spans := recorder.Spans()
+	require.NotEmpty(t, spans)
+	root := spans[len(spans)-1]
+	assert.Equal(t, "protocol "+h.ProtocolID(), root.Name)
+	assert.Error(t, root.Err)
+	assert.Equal(t, []string{string(partyIDs[0])}, root.Attributes[AttributeCulprits])
+}
@@ -0,0 +1,244 @@
+package observe
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// Span is a timed operation of a protocol execution, modelled after OpenTelemetry spans.
+//
+// A Tracer produces a root span per protocol execution, with a child span per round.
+// The child spans of a round measure its finalization and the verification of each message,
+// and the messages it sends and receives are recorded as events.
+type Span struct {
+	// TraceID is derived from the SSID, so that the spans of all parties end up in the same trace.
+	TraceID [16]byte
+	SpanID  [8]byte
+	// ParentSpanID is zero for the root span.
+	ParentSpanID [8]byte
+	Name         string
+	Start, End   time.Time
+	Attributes   map[string]interface{}
+	Events       []Event
+	// Err is the error the operation failed with, or nil.
+	Err error
+}
+
+// Event is something which happened at a point in time during a span.
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+// SpanExporter receives the spans of a Tracer once they have ended.
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+// Names of the attributes of spans and events.
+const (
+	AttributeProtocol  = "mpsig.protocol"
+	AttributeSSID      = "mpsig.ssid"
+	AttributeSelf      = "mpsig.self"
+	AttributeRound     = "mpsig.round"
+	AttributeFrom      = "mpsig.from"
+	AttributeTo        = "mpsig.to"
+	AttributeBroadcast = "mpsig.broadcast"
+	AttributeSize      = "mpsig.size"
+	AttributeCulprits  = "mpsig.culprits"
+	AttributeReason    = "mpsig.reject_reason"
+)
+
+// Tracer is a protocol.Observer which records the execution of protocols as spans.
+type Tracer struct {
+	exporter SpanExporter
+	mtx      sync.Mutex
+	// sessions is keyed by the roundKey of round 0.
+	sessions map[roundKey]*trace
+}
+
+var _ protocol.Observer = (*Tracer)(nil)
+
+// trace holds the open spans of a protocol execution.
+type trace struct {
+	root  *Span
+	round *Span
+}
+
+// NewTracer returns a Tracer giving its spans to exporter.
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{exporter: exporter, sessions: map[roundKey]*trace{}}
+}
+
+func (t *Tracer) newSpan(parent *Span, name string, start time.Time) *Span {
+	span := &Span{Name: name, Start: start, Attributes: map[string]interface{}{}}
+	_, _ = rand.Read(span.SpanID[:])
+	if parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	}
+	return span
+}
+
+func (t *Tracer) end(span *Span, end time.Time, err error) {
+	span.End = end
+	span.Err = err
+	t.exporter.ExportSpan(span)
+}
+
+// trace returns the open spans of the protocol execution s, starting its root span if needed.
+func (t *Tracer) trace(s protocol.SessionInfo) *trace {
+	key := newRoundKey(s, 0)
+	tr := t.sessions[key]
+	if tr == nil {
+		root := t.newSpan(nil, "protocol "+s.ProtocolID, time.Now())
+		digest := sha256.Sum256(s.SSID)
+		copy(root.TraceID[:], digest[:])
+		root.Attributes[AttributeProtocol] = s.ProtocolID
+		root.Attributes[AttributeSSID] = fmt.Sprintf("%x", s.SSID)
+		root.Attributes[AttributeSelf] = string(s.SelfID)
+		tr = &trace{root: root}
+		t.sessions[key] = tr
+	}
+	return tr
+}
+
+// RoundStarted implements protocol.Observer.
+func (t *Tracer) RoundStarted(s protocol.SessionInfo, number round.Number) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	tr := t.trace(s)
+	if tr.round != nil {
+		t.end(tr.round, time.Now(), nil)
+	}
+	tr.round = t.newSpan(tr.root, fmt.Sprintf("round %d", number), time.Now())
+	tr.round.Attributes[AttributeRound] = int(number)
+}
+
+// RoundFinished implements protocol.Observer.
+func (t *Tracer) RoundFinished(s protocol.SessionInfo, number round.Number, finalize time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	tr := t.trace(s)
+	if tr.round == nil {
+		return
+	}
+	now := time.Now()
+	span := t.newSpan(tr.round, "finalize", now.Add(-finalize))
+	t.end(span, now, nil)
+	t.end(tr.round, now, nil)
+	tr.round = nil
+}
+
+// event records an event about msg on the current round, with the given attributes in addition to the ones of msg.
+func (t *Tracer) event(s protocol.SessionInfo, name string, msg *protocol.Message, attributes map[string]interface{}) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	tr := t.trace(s)
+	span := tr.round
+	if span == nil {
+		span = tr.root
+	}
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	if msg != nil {
+		attributes[AttributeRound] = int(msg.RoundNumber)
+		attributes[AttributeFrom] = string(msg.From)
+		attributes[AttributeTo] = string(msg.To)
+		attributes[AttributeBroadcast] = msg.Broadcast
+		attributes[AttributeSize] = len(msg.Data)
+	}
+	span.Events = append(span.Events, Event{Name: name, Time: time.Now(), Attributes: attributes})
+}
+
+// MessageReceived implements protocol.Observer.
+func (t *Tracer) MessageReceived(s protocol.SessionInfo, msg *protocol.Message) {
+	t.event(s, "message received", msg, nil)
+}
+
+// MessageRejected implements protocol.Observer.
+func (t *Tracer) MessageRejected(s protocol.SessionInfo, msg *protocol.Message, reason protocol.RejectReason) {
+	t.event(s, "message rejected", msg, map[string]interface{}{AttributeReason: reason.String()})
+}
+
+// MessageSent implements protocol.Observer.
+func (t *Tracer) MessageSent(s protocol.SessionInfo, msg *protocol.Message) {
+	t.event(s, "message sent", msg, nil)
+}
+
+// MessageVerified implements protocol.Observer.
+func (t *Tracer) MessageVerified(s protocol.SessionInfo, msg *protocol.Message, d time.Duration, err error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	tr := t.trace(s)
+	parent := tr.round
+	if parent == nil {
+		parent = tr.root
+	}
+	now := time.Now()
+	span := t.newSpan(parent, "verify", now.Add(-d))
+	span.Attributes[AttributeRound] = int(msg.RoundNumber)
+	span.Attributes[AttributeFrom] = string(msg.From)
+	span.Attributes[AttributeBroadcast] = msg.Broadcast
+	t.end(span, now, err)
+}
+
+// Aborted implements protocol.Observer.
+func (t *Tracer) Aborted(s protocol.SessionInfo, err protocol.Error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	tr := t.trace(s)
+	if len(err.Culprits) > 0 {
+		culprits := make([]string, 0, len(err.Culprits))
+		for _, id := range err.Culprits {
+			culprits = append(culprits, string(id))
+		}
+		tr.root.Attributes[AttributeCulprits] = culprits
+	}
+	t.close(s, tr, err)
+}
+
+// Finished implements protocol.Observer.
+func (t *Tracer) Finished(s protocol.SessionInfo, _ interface{}) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.close(s, t.trace(s), nil)
+}
+
+// close ends the spans of a protocol execution.
+func (t *Tracer) close(s protocol.SessionInfo, tr *trace, err error) {
+	now := time.Now()
+	if tr.round != nil {
+		t.end(tr.round, now, err)
+	}
+	t.end(tr.root, now, err)
+	delete(t.sessions, newRoundKey(s, 0))
+}
+
+// SpanRecorder is a SpanExporter keeping the spans in memory.
+type SpanRecorder struct {
+	mtx   sync.Mutex
+	spans []*Span
+}
+
+// ExportSpan implements SpanExporter.
+func (r *SpanRecorder) ExportSpan(span *Span) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Spans returns the spans exported so far, in the order in which they ended.
+func (r *SpanRecorder) Spans() []*Span {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]*Span{}, r.spans...)
+}
@@ -102,3 +102,61 @@ func (m *PointMap) UnmarshalBinary(data []byte) error {
 	}
 	return nil
 }
+
+// ScalarMap is a map from party ID's to scalars, to be easy to marshal.
+//
+// When unmarshalling, EmptyScalarMap must be called first, to provide a group
+// to use to unmarshal the scalars.
+type ScalarMap struct {
+	group   curve.Curve
+	Scalars map[ID]curve.Scalar
+}
+
+// NewScalarMap creates a ScalarMap from a map of scalars.
+func NewScalarMap(scalars map[ID]curve.Scalar) *ScalarMap {
+	var group curve.Curve
+	for _, v := range scalars {
+		group = v.Curve()
+		break
+	}
+	return &ScalarMap{group: group, Scalars: scalars}
+}
+
+// EmptyScalarMap creates an empty ScalarMap with a fixed group, ready to be unmarshalled.
+func EmptyScalarMap(group curve.Curve) *ScalarMap {
+	return &ScalarMap{group: group}
+}
+
+func (m *ScalarMap) MarshalBinary() ([]byte, error) {
+	enc, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+	scalarBytes := make(map[ID]cbor.RawMessage, len(m.Scalars))
+	for k, v := range m.Scalars {
+		scalarBytes[k], err = enc.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return enc.Marshal(scalarBytes)
+}
+
+func (m *ScalarMap) UnmarshalBinary(data []byte) error {
+	if m.group == nil {
+		return errors.New("ScalarMap.UnmarshalBinary called without setting a group")
+	}
+	scalarBytes := make(map[ID]cbor.RawMessage)
+	if err := cbor.Unmarshal(data, &scalarBytes); err != nil {
+		return err
+	}
+	m.Scalars = make(map[ID]curve.Scalar, len(scalarBytes))
+	for k, v := range scalarBytes {
+		scalar := m.group.NewScalar()
+		if err := cbor.Unmarshal(v, scalar); err != nil {
+			return err
+		}
+		m.Scalars[k] = scalar
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// AbortError is the error of a protocol aborted by another party, as given by its MessageAbort.
+//
+// Result() returns it wrapped in an Error naming the aborting party, since the reasons
+// given in the abort can't always be checked by the other parties.
+type AbortError struct {
+	// From is the party which aborted the protocol.
+	From party.ID
+	// RoundNumber is the round in which the protocol failed for From.
+	RoundNumber round.Number
+	// Culprits are the parties blamed by From.
+	Culprits []party.ID
+	// Hash is the hash of the message which From failed to verify, if any.
+	Hash []byte
+	// Reason is the error of From.
+	Reason string
+}
+
+// Error implements error.
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("round %d: aborted by party %s with error: %q", e.RoundNumber, e.From, e.Reason)
+}
+
+// abortContent is the Data of a MessageAbort. The round which failed is given by Message.RoundNumber,
+// and the SSID is covered by the signature of the message.
+type abortContent struct {
+	Reason   string
+	Culprits []party.ID
+	Hash     []byte
+}
+
+// messageError is returned when msg can't be verified, so that our abort can point to it.
+type messageError struct {
+	msg *Message
+	err error
+}
+
+func (e *messageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *messageError) Unwrap() error {
+	return e.err
+}
+
+// abortMessage returns the MessageAbort telling the other parties why we aborted the current round.
+func (h *MultiHandler) abortMessage() *Message {
+	r := h.currentRound
+	content := abortContent{
+		Reason:   h.err.Err.Error(),
+		Culprits: h.err.Culprits,
+	}
+	var invalid *messageError
+	if errors.As(h.err.Err, &invalid) {
+		content.Hash = invalid.msg.Hash()
+	}
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	data, _ := enc.Marshal(content)
+	msg := &Message{
+		SSID:        r.SSID(),
+		From:        r.SelfID(),
+		Protocol:    r.ProtocolID(),
+		RoundNumber: r.Number(),
+		Data:        data,
+		// our view of the previous broadcast round, without which the abort can't be refuted.
+		BroadcastVerification: h.broadcastHashes[r.Number()-1],
+		Type:                  MessageAbort,
+	}
+	// without identities, the abort is only as authentic as the transport.
+	if h.identities != nil {
+		_ = h.identities.sign(msg)
+	}
+	return msg
+}
+
+// acceptAbort handles a MessageAbort sent by another party.
+//
+// The abort is refused if we can tell that it is groundless, in which case we keep going,
+// and name its sender as a culprit if the protocol fails later on.
+func (h *MultiHandler) acceptAbort(msg *Message) RejectReason {
+	for _, id := range h.groundless {
+		if id == msg.From {
+			return RejectDuplicate
+		}
+	}
+	h.observer.MessageReceived(h.info(), msg)
+	var content abortContent
+	if err := cbor.Unmarshal(msg.Data, &content); err != nil || h.refutes(msg, &content) {
+		h.groundless = append(h.groundless, msg.From)
+		return RejectGroundlessAbort
+	}
+	h.abort(&AbortError{
+		From:        msg.From,
+		RoundNumber: msg.RoundNumber,
+		Culprits:    content.Culprits,
+		Hash:        content.Hash,
+		Reason:      content.Reason,
+	}, msg.From)
+	return RejectNone
+}
+
+// refutes returns true if the abort is groundless: either it blames parties who don't participate,
+// or the message it claims to be invalid is a broadcast message which we verified successfully, or sent ourselves.
+// Since all parties receive the same broadcast messages, and verify them in the same way,
+// an honest party can't have failed to verify it, unless it received different messages in the previous
+// broadcast round, which is why the BroadcastVerification of the abort must match ours.
+//
+// Point-to-point messages can only be verified by their recipient, so aborts pointing to them are accepted.
+func (h *MultiHandler) refutes(msg *Message, content *abortContent) bool {
+	r := h.currentRound
+	for _, id := range content.Culprits {
+		if !r.PartyIDs().Contains(id) {
+			return true
+		}
+	}
+	number := msg.RoundNumber
+	// the broadcast messages of the rounds we reached have all been verified.
+	if len(content.Hash) == 0 || number > r.Number() || !bytes.Equal(msg.BroadcastVerification, h.broadcastHashes[number-1]) {
+		return false
+	}
+	for _, id := range content.Culprits {
+		if msg := h.broadcast[number][id]; msg != nil && bytes.Equal(msg.Hash(), content.Hash) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package protocol_test
+
+import (
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// abortOf returns the MessageAbort sent by h once it is done.
+func abortOf(t *testing.T, h *protocol.MultiHandler) *protocol.Message {
+	for msg := range h.Listen() {
+		if msg.Type == protocol.MessageAbort {
+			return msg
+		}
+	}
+	t.Fatal("no abort was sent")
+	return nil
+}
+
+func TestAbort(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+	ids := identities(t, partyIDs)
+	start := func(id party.ID) (*protocol.MultiHandler, *protocol.Message) {
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithIdentities(ids[id]))
+		require.NoError(t, err)
+		return h, <-h.Listen()
+	}
+	sign := func(msg *protocol.Message, id party.ID) *protocol.Message {
+		var err error
+		msg.Signature, err = ids[id].Key.Sign(msg.Hash())
+		require.NoError(t, err)
+		return msg
+	}
+	rejected := func(h *protocol.MultiHandler, msg *protocol.Message) protocol.RejectReason {
+		var err *protocol.RejectError
+		require.ErrorAs(t, h.AcceptWithError(msg), &err)
+		return err.Reason
+	}
+
+	// b receives an invalid broadcast message from a, and aborts.
+	hb, _ := start(b)
+	_, broadcast := start(a)
+	invalid := *broadcast
+	invalid.Data = []byte{0xff}
+	require.NoError(t, hb.AcceptWithError(sign(&invalid, a)))
+	abort := abortOf(t, hb)
+	assert.EqualValues(t, 2, abort.RoundNumber)
+
+	// c received another message from a, so it can't refute the abort.
+	hc, _ := start(c)
+	require.NoError(t, hc.AcceptWithError(broadcast))
+	legacy := &protocol.Message{SSID: abort.SSID, From: b, Protocol: abort.Protocol, Data: []byte("aborted")}
+	assert.Equal(t, protocol.RejectInvalidRound, rejected(hc, sign(legacy, b)), "aborts for round 0 are not accepted")
+	forged := *abort
+	forged.From = a
+	assert.Equal(t, protocol.RejectInvalidSignature, rejected(hc, &forged), "aborts must be signed by their sender")
+	require.NoError(t, hc.AcceptWithError(abort))
+	_, err := hc.Result()
+	require.Error(t, err)
+	assert.Equal(t, []party.ID{b}, err.(protocol.Error).Culprits)
+	var aborted *protocol.AbortError
+	require.ErrorAs(t, err, &aborted)
+	assert.Equal(t, b, aborted.From)
+	assert.Equal(t, []party.ID{a}, aborted.Culprits)
+	assert.Equal(t, invalid.Hash(), aborted.Hash)
+
+	// b claims that the broadcast message of a is invalid, but c verified the same one.
+	hb, _ = start(b)
+	hc, _ = start(c)
+	require.NoError(t, hb.AcceptWithError(broadcast))
+	require.NoError(t, hc.AcceptWithError(broadcast))
+	data, err := cbor.Marshal(struct {
+		Reason   string
+		Culprits []party.ID
+		Hash     []byte
+	}{"invalid broadcast", []party.ID{a}, broadcast.Hash()})
+	require.NoError(t, err)
+	groundless := sign(&protocol.Message{SSID: broadcast.SSID, From: b, Protocol: broadcast.Protocol, RoundNumber: 2, Data: data, Type: protocol.MessageAbort}, b)
+	assert.Equal(t, protocol.RejectGroundlessAbort, rejected(hc, groundless))
+	assert.Equal(t, protocol.RejectDuplicate, rejected(hc, groundless))
+	// c keeps going, and blames b once the protocol fails.
+	_, err = hc.Result()
+	assert.EqualError(t, err, "protocol: not finished")
+	hc.Stop()
+	_, err = hc.Result()
+	require.Error(t, err)
+	assert.Equal(t, []party.ID{c, b}, err.(protocol.Error).Culprits)
+
+	// an abort blaming a party which doesn't participate is groundless as well.
+	data, err = cbor.Marshal(struct{ Culprits []party.ID }{[]party.ID{"unknown"}})
+	require.NoError(t, err)
+	unknown := sign(&protocol.Message{SSID: broadcast.SSID, From: b, Protocol: broadcast.Protocol, RoundNumber: 2, Data: data, Type: protocol.MessageAbort}, b)
+	hc, _ = start(c)
+	assert.Equal(t, protocol.RejectGroundlessAbort, rejected(hc, unknown))
+}
+
+func TestAbortAfterEquivocation(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+	ids := identities(t, partyIDs)
+	start := func(id party.ID) (*protocol.MultiHandler, *protocol.Message) {
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithIdentities(ids[id]))
+		require.NoError(t, err)
+		return h, <-h.Listen()
+	}
+	// broadcastOf returns the broadcast message sent by h in the given round.
+	broadcastOf := func(h *protocol.MultiHandler, number int) *protocol.Message {
+		for msg := range h.Listen() {
+			if msg.Broadcast && int(msg.RoundNumber) == number {
+				return msg
+			}
+		}
+		t.Fatal("no broadcast was sent")
+		return nil
+	}
+
+	// a equivocates in round 2, so that b and c reach round 3 with different views.
+	_, toB := start(a)
+	_, toC := start(a)
+	hb, fromB := start(b)
+	hc, fromC := start(c)
+	require.NoError(t, hb.AcceptWithError(toB))
+	require.NoError(t, hb.AcceptWithError(fromC))
+	require.NoError(t, hc.AcceptWithError(toC))
+	require.NoError(t, hc.AcceptWithError(fromB))
+	viewB := broadcastOf(hb, 3).BroadcastVerification
+	broadcastC := broadcastOf(hc, 3)
+	require.NotEqual(t, viewB, broadcastC.BroadcastVerification)
+
+	// b fails to verify the round 3 broadcast of c, which c sent itself. Since b saw different
+	// broadcasts in round 2, the abort is honest, and c must accept it instead of blaming b.
+	data, err := cbor.Marshal(struct {
+		Reason   string
+		Culprits []party.ID
+		Hash     []byte
+	}{"invalid broadcast", []party.ID{c}, broadcastC.Hash()})
+	require.NoError(t, err)
+	abort := &protocol.Message{
+		SSID:                  broadcastC.SSID,
+		From:                  b,
+		Protocol:              broadcastC.Protocol,
+		RoundNumber:           3,
+		Data:                  data,
+		BroadcastVerification: viewB,
+		Type:                  protocol.MessageAbort,
+	}
+	abort.Signature, err = ids[b].Key.Sign(abort.Hash())
+	require.NoError(t, err)
+	require.NoError(t, hc.AcceptWithError(abort))
+	_, err = hc.Result()
+	var aborted *protocol.AbortError
+	require.ErrorAs(t, err, &aborted)
+	assert.Equal(t, b, aborted.From)
+	assert.Equal(t, []party.ID{c}, aborted.Culprits)
+}
@@ -0,0 +1,163 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// errBroadcastVerification is returned when parties disagree on the messages of a broadcast round.
+var errBroadcastVerification = errors.New("broadcast verification failed")
+
+// contest is called when the BroadcastVerification of some message for the round following
+// the broadcast round `number` doesn't match ours.
+//
+// Without identities, we can only abort, since we don't know who caused the mismatch.
+// Otherwise, we send the signed broadcast messages we received to all parties, and wait
+// for theirs. Since all these messages are signed, a party who sent different messages
+// to different parties is exposed by two valid signatures on different messages.
+// If everybody received the same messages, then the parties whose BroadcastVerification
+// doesn't match lied about it.
+func (h *MultiHandler) contest(number round.Number) {
+	if h.identities == nil {
+		h.abort(errBroadcastVerification)
+		return
+	}
+	if h.contested == number {
+		return
+	}
+	h.contested = number
+	h.checkEvidence(number)
+}
+
+// acceptEvidence handles a MessageBroadcastEvidence sent by another party.
+func (h *MultiHandler) acceptEvidence(msg *Message) {
+	number := msg.RoundNumber
+	if h.evidence[number] == nil {
+		h.evidence[number] = make(map[party.ID][]*Message, h.currentRound.N())
+	}
+	messages, err := h.parseEvidence(msg)
+	if err != nil {
+		h.abort(fmt.Errorf("round %d: invalid broadcast evidence: %w", number, err), msg.From)
+		return
+	}
+	h.evidence[number][msg.From] = messages
+	h.checkEvidence(number)
+}
+
+// parseEvidence returns the broadcast messages contained in msg, after checking that there is one
+// for every party, signed by its sender.
+func (h *MultiHandler) parseEvidence(msg *Message) ([]*Message, error) {
+	r := h.currentRound
+	if h.broadcast[msg.RoundNumber] == nil {
+		return nil, errors.New("not a broadcast round")
+	}
+	var messages []*Message
+	if err := cbor.Unmarshal(msg.Data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	if len(messages) != r.N() {
+		return nil, fmt.Errorf("got %d messages, expected %d", len(messages), r.N())
+	}
+	seen := make(map[party.ID]bool, len(messages))
+	for _, m := range messages {
+		if m == nil || !m.Broadcast || m.Type != MessageRound || m.RoundNumber != msg.RoundNumber {
+			return nil, errors.New("not a broadcast message")
+		}
+		if m.Protocol != r.ProtocolID() || !bytes.Equal(m.SSID, r.SSID()) {
+			return nil, errors.New("message from another session")
+		}
+		if !r.PartyIDs().Contains(m.From) || seen[m.From] {
+			return nil, fmt.Errorf("unexpected message from %s", m.From)
+		}
+		seen[m.From] = true
+		if !h.identities.verify(m) {
+			return nil, fmt.Errorf("invalid signature for message from %s", m.From)
+		}
+	}
+	return messages, nil
+}
+
+// checkEvidence compares the broadcast messages of the given round received by other parties with ours,
+// and aborts if some party can be blamed.
+//
+// We send our own messages once we've received all of them, if we are contesting this round
+// or if somebody else is. Evidence is only sent by parties who received our messages for the
+// next round, so we always have all the broadcast messages by the time it arrives.
+func (h *MultiHandler) checkEvidence(number round.Number) {
+	r := h.currentRound
+	for _, messages := range h.evidence[number] {
+		for _, m := range messages {
+			ours := h.broadcast[number][m.From]
+			if ours != nil && !bytes.Equal(ours.Hash(), m.Hash()) {
+				h.abort(fmt.Errorf("round %d: party %s broadcast different messages", number, m.From), m.From)
+				return
+			}
+		}
+	}
+
+	received := h.evidence[number]
+	if (h.contested == number || len(received) > 0) && h.broadcastHashes[number] != nil && received[r.SelfID()] == nil {
+		if err := h.sendEvidence(number); err != nil {
+			h.abort(err, r.SelfID())
+			return
+		}
+		received = h.evidence[number]
+	}
+
+	if h.contested != number {
+		return
+	}
+	for _, id := range r.OtherPartyIDs() {
+		if received[id] == nil {
+			return
+		}
+	}
+	// everybody received the same broadcast messages, so the parties who sent us a different
+	// BroadcastVerification lied about it.
+	var culprits []party.ID
+	for _, id := range r.OtherPartyIDs() {
+		for _, msg := range []*Message{h.messages[number+1][id], h.broadcast[number+1][id]} {
+			if msg != nil && !bytes.Equal(msg.BroadcastVerification, h.broadcastHashes[number]) {
+				culprits = append(culprits, id)
+				break
+			}
+		}
+	}
+	h.abort(fmt.Errorf("round %d: %w", number+1, errBroadcastVerification), culprits...)
+}
+
+// sendEvidence sends the broadcast messages we received for the given round to all parties.
+func (h *MultiHandler) sendEvidence(number round.Number) error {
+	r := h.currentRound
+	messages := make([]*Message, 0, r.N())
+	for _, id := range r.PartyIDs() {
+		messages = append(messages, h.broadcast[number][id])
+	}
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	data, err := enc.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal broadcast evidence: %w", err)
+	}
+	msg := &Message{
+		SSID:        r.SSID(),
+		From:        r.SelfID(),
+		Protocol:    r.ProtocolID(),
+		RoundNumber: number,
+		Data:        data,
+		Type:        MessageBroadcastEvidence,
+	}
+	if err = h.identities.sign(msg); err != nil {
+		return err
+	}
+	if h.evidence[number] == nil {
+		h.evidence[number] = make(map[party.ID][]*Message, r.N())
+	}
+	h.evidence[number][r.SelfID()] = messages
+	h.send(msg)
+	return nil
+}
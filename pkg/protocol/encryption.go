@@ -0,0 +1,98 @@
+package protocol
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+)
+
+// validateEncryption checks that every participant has an encryption key, and that ours matches EncryptionKey.
+func (i *Identities) validateEncryption(self party.ID, partyIDs []party.ID) error {
+	if i.EncryptionKey == nil || i.EncryptionKey.IsZero() {
+		return errors.New("protocol: invalid encryption key")
+	}
+	for _, id := range partyIDs {
+		key := i.EncryptionKeys[id]
+		if key == nil || key.IsIdentity() {
+			return fmt.Errorf("protocol: missing encryption key of party %s", id)
+		}
+		if key.Curve().Name() != i.EncryptionKey.Curve().Name() {
+			return fmt.Errorf("protocol: encryption key of party %s is on the wrong curve", id)
+		}
+	}
+	if !i.EncryptionKey.ActOnBase().Equal(i.EncryptionKeys[self]) {
+		return fmt.Errorf("protocol: encryption key doesn't match the encryption key of party %s", self)
+	}
+	return nil
+}
+
+// encrypts returns true if the content of msg is encrypted to its recipient.
+//
+// Only point-to-point messages are encrypted, since the others are meant to be seen by everyone.
+func (i *Identities) encrypts(msg *Message) bool {
+	return i != nil && i.EncryptionKey != nil && !msg.Broadcast && msg.To != "" && msg.RoundNumber != 0
+}
+
+// encrypt replaces the content of msg with its encryption to msg.To.
+func (i *Identities) encrypt(msg *Message) error {
+	aead, err := i.channel(msg.From, msg.To, msg.To)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(msg.Data)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	msg.Data = aead.Seal(nonce, nonce, msg.Data, messageHeader(msg))
+	return nil
+}
+
+// decrypt returns the content of msg, which was encrypted by msg.From.
+func (i *Identities) decrypt(msg *Message) ([]byte, error) {
+	aead, err := i.channel(msg.From, msg.To, msg.From)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Data) < aead.NonceSize() {
+		return nil, errors.New("failed to decrypt message: ciphertext is too short")
+	}
+	nonce, ciphertext := msg.Data[:aead.NonceSize()], msg.Data[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, messageHeader(msg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message: %w", err)
+	}
+	return data, nil
+}
+
+// channel returns the cipher for messages from one party to another, keyed with the
+// Diffie-Hellman secret between our encryption key and the one of peer.
+//
+// Each direction uses a different key.
+func (i *Identities) channel(from, to, peer party.ID) (cipher.AEAD, error) {
+	shared := i.EncryptionKey.Act(i.EncryptionKeys[peer])
+	h := hash.New(hash.BytesWithDomain{TheDomain: "Message Encryption", Bytes: []byte{}})
+	if err := h.WriteAny(shared, from, to); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(h.Sum()[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// messageHeader binds an encrypted message to its session, round, sender and recipient.
+func messageHeader(msg *Message) []byte {
+	return hash.New(
+		hash.BytesWithDomain{TheDomain: "SSID", Bytes: msg.SSID},
+		msg.From,
+		msg.To,
+		hash.BytesWithDomain{TheDomain: "Protocol", Bytes: []byte(msg.Protocol)},
+		msg.RoundNumber,
+	).Sum()
+}
@@ -0,0 +1,55 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryption(t *testing.T) {
+	for _, group := range []curve.Curve{curve.Secp256k1{}, curve.Edwards25519{}} {
+		partyIDs := []party.ID{"a", "b", "c"}
+		public := make(map[party.ID]curve.Point, len(partyIDs))
+		identities := make(map[party.ID]*Identities, len(partyIDs))
+		for _, id := range partyIDs {
+			secret := sample.Scalar(rand.Reader, group)
+			public[id] = secret.ActOnBase()
+			identities[id] = &Identities{EncryptionKey: secret, EncryptionKeys: public}
+		}
+		for _, id := range partyIDs {
+			require.NoError(t, identities[id].validateEncryption(id, partyIDs))
+		}
+		assert.Error(t, identities["a"].validateEncryption("b", partyIDs))
+
+		content := []byte("share")
+		msg := &Message{SSID: []byte("ssid"), From: "a", To: "b", Protocol: "test", RoundNumber: 2, Data: content}
+		require.True(t, identities["a"].encrypts(msg))
+		require.NoError(t, identities["a"].encrypt(msg))
+		assert.NotContains(t, string(msg.Data), string(content))
+
+		data, err := identities["b"].decrypt(msg)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+
+		// another party can't decrypt it, even by changing the recipient.
+		_, err = identities["c"].decrypt(msg)
+		assert.Error(t, err)
+		replayed := *msg
+		replayed.To = "c"
+		_, err = identities["c"].decrypt(&replayed)
+		assert.Error(t, err)
+
+		// the ciphertext is bound to the round.
+		replayed = *msg
+		replayed.RoundNumber = 3
+		_, err = identities["b"].decrypt(&replayed)
+		assert.Error(t, err)
+
+		assert.False(t, identities["a"].encrypts(&Message{From: "a", Broadcast: true, RoundNumber: 2}))
+	}
+}
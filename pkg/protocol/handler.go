@@ -2,9 +2,12 @@ package protocol
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
 	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
@@ -45,24 +48,147 @@ type MultiHandler struct {
 	broadcastHashes map[round.Number][]byte
 	out             chan *Message
 	mtx             sync.Mutex
+
+	// roundTimeout is the maximum duration of a single round, or 0 if rounds never time out.
+	roundTimeout time.Duration
+	// roundTimer aborts the current round once roundTimeout has elapsed.
+	roundTimer *time.Timer
+	// stopContext detaches the handler from its context, once the protocol is done.
+	stopContext func() bool
+	// identities authenticates messages, or is nil if this is left to the transport.
+	identities *Identities
+	// contested is the broadcast round whose messages we disagree on with some party, or 0.
+	contested round.Number
+	// evidence holds the broadcast messages of a contested round, as received by each party.
+	evidence map[round.Number]map[party.ID][]*Message
+	// pending holds the messages for the protocol following the current one, when it is a round.Chain.
+	pending []*Message
+	// observer is notified of the events of the execution.
+	observer Observer
+	// dropped counts the messages which were not accepted, by reason.
+	dropped map[RejectReason]uint64
+	// sent holds the messages we sent for each round, so that they can be sent again.
+	sent map[round.Number][]*Message
+	// resent counts the requests of each party to send a round again.
+	resent map[party.ID]map[round.Number]int
+	// groundless contains the parties who sent an abort we could refute, and are blamed if the protocol fails.
+	groundless []party.ID
+}
+
+// maxPending bounds the number of messages kept per party for the protocol following the current one.
+// Other parties can't get past its first round without our messages, so they send at most a broadcast,
+// a point-to-point message, and an abort.
+//
+// The SSID of the next protocol is only known once it starts, so a party may have sent messages
+// for other sessions as well. When its buffer is full, these are evicted first.
+const maxPending = 4
+
+// Option configures a MultiHandler, when it is created by NewMultiHandler or RestoreMultiHandler.
+type Option func(*options)
+
+type options struct {
+	ctx          context.Context
+	roundTimeout time.Duration
+	identities   *Identities
+	observer     Observer
+}
+
+// WithContext aborts the protocol when ctx is done.
+//
+// The resulting Error names the parties whose messages for the current round never arrived.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithRoundTimeout aborts the protocol when a round doesn't complete within roundTimeout,
+// as WithContext does. A roundTimeout of 0, the default, means that rounds never time out.
+func WithRoundTimeout(roundTimeout time.Duration) Option {
+	return func(o *options) {
+		o.roundTimeout = roundTimeout
+	}
+}
+
+// WithIdentities signs every message with the identity key of its sender, and makes CanAccept
+// reject messages which aren't.
+//
+// identities must contain the identity public key of every participant.
+// By default, messages are not signed, and the transport must authenticate them.
+func WithIdentities(identities *Identities) Option {
+	return func(o *options) {
+		o.identities = identities
+	}
+}
+
+// WithObserver notifies observer of the events of the protocol execution, starting with the first round.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.observer == nil {
+		o.observer = nopObserver{}
+	}
+	return o
 }
 
 // NewMultiHandler expects a StartFunc for the desired protocol. It returns a handler that the user can interact with.
-func NewMultiHandler(create StartFunc, sessionID []byte) (*MultiHandler, error) {
+func NewMultiHandler(create StartFunc, sessionID []byte, opts ...Option) (*MultiHandler, error) {
+	o := newOptions(opts)
 	r, err := create(sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("protocol: failed to create round: %w", err)
 	}
-	h := &MultiHandler{
+	h, err := newMultiHandler(r, o)
+	if err != nil {
+		return nil, err
+	}
+	h.start(o.ctx)
+	return h, nil
+}
+
+func newMultiHandler(r round.Session, o *options) (*MultiHandler, error) {
+	if o.identities != nil {
+		if err := o.identities.validate(r.SelfID(), r.PartyIDs()); err != nil {
+			return nil, err
+		}
+	}
+	return &MultiHandler{
 		currentRound:    r,
 		rounds:          map[round.Number]round.Session{r.Number(): r},
 		messages:        newQueue(r.OtherPartyIDs(), r.FinalRoundNumber()),
 		broadcast:       newQueue(r.OtherPartyIDs(), r.FinalRoundNumber()),
 		broadcastHashes: map[round.Number][]byte{},
+		evidence:        map[round.Number]map[party.ID][]*Message{},
 		out:             make(chan *Message, 2*r.N()),
-	}
+		roundTimeout:    o.roundTimeout,
+		identities:      o.identities,
+		observer:        o.observer,
+		dropped:         map[RejectReason]uint64{},
+		sent:            map[round.Number][]*Message{},
+		resent:          map[party.ID]map[round.Number]int{},
+	}, nil
+}
+
+// start runs the current round as far as possible, and aborts the protocol once ctx is done.
+func (h *MultiHandler) start(ctx context.Context) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.observer.RoundStarted(h.info(), h.currentRound.Number())
+	h.resetRoundTimer()
 	h.finalize()
-	return h, nil
+	if !h.done() {
+		h.stopContext = context.AfterFunc(ctx, func() {
+			h.cancel(context.Cause(ctx))
+		})
+	}
 }
 
 // Result returns the protocol result if the protocol completed successfully. Otherwise an error is returned.
@@ -78,6 +204,20 @@ func (h *MultiHandler) Result() (interface{}, error) {
 	return nil, errors.New("protocol: not finished")
 }
 
+// SSID returns the session identifier of the protocol execution, which is included in all its messages.
+func (h *MultiHandler) SSID() []byte {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.currentRound.SSID()
+}
+
+// ProtocolID returns the identifier of the protocol being executed, which is included in all its messages.
+func (h *MultiHandler) ProtocolID() string {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.currentRound.ProtocolID()
+}
+
 // Listen returns a channel with outgoing messages that must be sent to other parties.
 // The message received should be _reliably_ broadcast if msg.Broadcast is true.
 // The channel is closed when either an error occurs or the protocol detects an error.
@@ -89,42 +229,85 @@ func (h *MultiHandler) Listen() <-chan *Message {
 
 // CanAccept returns true if the message is designated for this protocol protocol execution.
 func (h *MultiHandler) CanAccept(msg *Message) bool {
+	return h.check(msg) == RejectNone
+}
+
+// check returns the reason why msg can't be accepted, or RejectNone.
+func (h *MultiHandler) check(msg *Message) RejectReason {
 	r := h.currentRound
 	if msg == nil {
-		return false
+		return RejectNilMessage
 	}
 	// are we the intended recipient
 	if !msg.IsFor(r.SelfID()) {
-		return false
-	}
-	// is the protocol ID correct
-	if msg.Protocol != r.ProtocolID() {
-		return false
-	}
-	// check for same SSID
-	if !bytes.Equal(msg.SSID, r.SSID()) {
-		return false
+		return RejectWrongRecipient
+	}
+	// is the message for the current protocol, or possibly for the one following it
+	if msg.Protocol != r.ProtocolID() || !bytes.Equal(msg.SSID, r.SSID()) {
+		switch {
+		case !chained(r) && msg.Protocol != r.ProtocolID():
+			return RejectWrongProtocol
+		case !chained(r):
+			return RejectWrongSSID
+		case !r.PartyIDs().Contains(msg.From):
+			return RejectUnknownSender
+		case msg.Data == nil:
+			return RejectNilData
+		case h.identities != nil && !h.identities.verify(msg):
+			return RejectInvalidSignature
+		default:
+			return RejectNone
+		}
 	}
+
 	// do we know the sender
 	if !r.PartyIDs().Contains(msg.From) {
-		return false
+		return RejectUnknownSender
 	}
 
 	// data is cannot be nil
 	if msg.Data == nil {
-		return false
+		return RejectNilData
 	}
 
 	// check if message for unexpected round
 	if msg.RoundNumber > r.FinalRoundNumber() {
-		return false
+		return RejectInvalidRound
 	}
 
-	if msg.RoundNumber < r.Number() && msg.RoundNumber > 0 {
-		return false
+	switch msg.Type {
+	case MessageRound:
+		if msg.RoundNumber == 0 {
+			return RejectInvalidRound
+		}
+		if msg.RoundNumber < r.Number() {
+			return RejectStaleRound
+		}
+	case MessageAbort:
+		// the failing round may be a previous one, if we are ahead of the sender
+		if msg.RoundNumber == 0 {
+			return RejectInvalidRound
+		}
+	case MessageBroadcastEvidence:
+		// evidence is about a previous round, and can only be checked with signed messages
+		if msg.RoundNumber == 0 || h.identities == nil {
+			return RejectInvalidType
+		}
+	case MessageResendRequest:
+		// we can be asked for the messages of any previous round, but only by their recipient
+		if msg.To != r.SelfID() {
+			return RejectInvalidType
+		}
+	default:
+		return RejectInvalidType
 	}
 
-	return true
+	// is the message signed by the sender
+	if h.identities != nil && !h.identities.verify(msg) {
+		return RejectInvalidSignature
+	}
+
+	return RejectNone
 }
 
 // Accept tries to process the given message. If an abort occurs, the channel returned by Listen() is closed,
@@ -132,38 +315,92 @@ func (h *MultiHandler) CanAccept(msg *Message) bool {
 //
 // This function may be called concurrently from different threads but may block until all previous calls have finished.
 func (h *MultiHandler) Accept(msg *Message) {
+	_ = h.AcceptWithError(msg)
+}
+
+// AcceptWithError is like Accept, but returns a *RejectError explaining why the message was dropped, if it was.
+// Dropped messages are counted by reason, see Dropped.
+//
+// A nil error only means that the message was processed: if it made the protocol abort,
+// the error is returned by Result().
+func (h *MultiHandler) AcceptWithError(msg *Message) error {
 	h.mtx.Lock()
 	defer h.mtx.Unlock()
 
-	// exit early if the message is bad, or if we are already done
-	if !h.CanAccept(msg) || h.err != nil || h.result != nil || h.duplicate(msg) {
-		return
+	if reason := h.accept(msg); reason != RejectNone {
+		h.dropped[reason]++
+		h.observer.MessageRejected(h.info(), msg, reason)
+		return newRejectError(reason, msg)
 	}
+	return nil
+}
 
-	// a msg with roundNumber 0 is considered an abort from another party
-	if msg.RoundNumber == 0 {
-		h.abort(fmt.Errorf("aborted by other party with error: \"%s\"", msg.Data), msg.From)
-		return
+// Dropped returns the number of messages dropped by AcceptWithError and Accept so far, by reason.
+func (h *MultiHandler) Dropped() map[RejectReason]uint64 {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	dropped := make(map[RejectReason]uint64, len(h.dropped))
+	for reason, count := range h.dropped {
+		dropped[reason] = count
+	}
+	return dropped
+}
+
+// accept processes msg, and returns the reason why it was dropped, or RejectNone.
+func (h *MultiHandler) accept(msg *Message) RejectReason {
+	// exit early if we are already done, or if the message is bad
+	if h.done() && msg != nil {
+		return RejectFinished
+	}
+	if reason := h.check(msg); reason != RejectNone {
+		return reason
+	}
+
+	if msg.Protocol != h.currentRound.ProtocolID() || !bytes.Equal(msg.SSID, h.currentRound.SSID()) {
+		return h.keepPending(msg)
+	}
+
+	if msg.Type == MessageResendRequest {
+		return h.acceptResendRequest(msg)
 	}
 
+	if msg.Type == MessageAbort {
+		return h.acceptAbort(msg)
+	}
+
+	if msg.Type == MessageBroadcastEvidence {
+		if h.evidence[msg.RoundNumber][msg.From] != nil {
+			return RejectDuplicate
+		}
+		h.observer.MessageReceived(h.info(), msg)
+		h.acceptEvidence(msg)
+		return RejectNone
+	}
+
+	if reason := h.duplicate(msg); reason != RejectNone {
+		return reason
+	}
+	h.observer.MessageReceived(h.info(), msg)
+
 	h.store(msg)
 	if h.currentRound.Number() != msg.RoundNumber {
-		return
+		return RejectNone
 	}
 
 	if msg.Broadcast {
 		if err := h.verifyBroadcastMessage(msg); err != nil {
 			h.abort(err, msg.From)
-			return
+			return RejectNone
 		}
 	} else {
 		if err := h.verifyMessage(msg); err != nil {
 			h.abort(err, msg.From)
-			return
+			return RejectNone
 		}
 	}
 
 	h.finalize()
+	return RejectNone
 }
 
 func (h *MultiHandler) verifyBroadcastMessage(msg *Message) error {
@@ -173,14 +410,17 @@ func (h *MultiHandler) verifyBroadcastMessage(msg *Message) error {
 	}
 
 	// try to convert the raw message into a round.Message
-	roundMsg, err := getRoundMessage(msg, r)
+	roundMsg, err := h.getRoundMessage(msg, r)
 	if err != nil {
-		return err
+		return &messageError{msg: msg, err: err}
 	}
 
 	// store the broadcast message for this round
-	if err = r.(round.BroadcastRound).StoreBroadcastMessage(roundMsg); err != nil {
-		return fmt.Errorf("round %d: %w", r.Number(), err)
+	start := time.Now()
+	err = r.(round.BroadcastRound).StoreBroadcastMessage(roundMsg)
+	h.observer.MessageVerified(h.info(), msg, time.Since(start), err)
+	if err != nil {
+		return &messageError{msg: msg, err: fmt.Errorf("round %d: %w", r.Number(), err)}
 	}
 
 	// if the round only expected a broadcast message, we can safely return
@@ -213,18 +453,19 @@ func (h *MultiHandler) verifyMessage(msg *Message) error {
 		}
 	}
 
-	roundMsg, err := getRoundMessage(msg, r)
+	roundMsg, err := h.getRoundMessage(msg, r)
 	if err != nil {
-		return err
+		return &messageError{msg: msg, err: err}
 	}
 
 	// verify message for round
-	if err = r.VerifyMessage(roundMsg); err != nil {
-		return fmt.Errorf("round %d: %w", r.Number(), err)
+	start := time.Now()
+	if err = r.VerifyMessage(roundMsg); err == nil {
+		err = r.StoreMessage(roundMsg)
 	}
-
-	if err = r.StoreMessage(roundMsg); err != nil {
-		return fmt.Errorf("round %d: %w", r.Number(), err)
+	h.observer.MessageVerified(h.info(), msg, time.Since(start), err)
+	if err != nil {
+		return &messageError{msg: msg, err: fmt.Errorf("round %d: %w", r.Number(), err)}
 	}
 
 	return nil
@@ -236,12 +477,13 @@ func (h *MultiHandler) finalize() {
 		return
 	}
 	if !h.checkBroadcastHash() {
-		h.abort(errors.New("broadcast verification failed"))
+		h.contest(h.currentRound.Number() - 1)
 		return
 	}
 
 	out := make(chan *round.Message, h.currentRound.N()+1)
 	// since we pass a large enough channel, we should never get an error
+	start := time.Now()
 	r, err := h.currentRound.Finalize(out)
 	close(out)
 	// either we got an error due to some problem on our end (sampling etc)
@@ -250,6 +492,7 @@ func (h *MultiHandler) finalize() {
 		h.abort(err, h.currentRound.SelfID())
 		return
 	}
+	h.observer.RoundFinished(h.info(), h.currentRound.Number(), time.Since(start))
 
 	// forward messages with the correct header.
 	enc, _ := cbor.CanonicalEncOptions().EncMode()
@@ -268,10 +511,28 @@ func (h *MultiHandler) finalize() {
 			Broadcast:             roundMsg.Broadcast,
 			BroadcastVerification: h.broadcastHashes[r.Number()-1],
 		}
+		if h.identities.encrypts(msg) {
+			if err = h.identities.encrypt(msg); err != nil {
+				h.abort(err, r.SelfID())
+				return
+			}
+		}
+		if h.identities != nil {
+			if err = h.identities.sign(msg); err != nil {
+				h.abort(err, r.SelfID())
+				return
+			}
+		}
 		if msg.Broadcast {
 			h.store(msg)
 		}
-		h.out <- msg
+		h.send(msg)
+	}
+
+	// the protocol was followed by another one, which starts over from its first round.
+	if r.ProtocolID() != h.currentRound.ProtocolID() || !bytes.Equal(r.SSID(), h.currentRound.SSID()) {
+		h.chain(r)
+		return
 	}
 
 	roundNumber := r.Number()
@@ -281,6 +542,7 @@ func (h *MultiHandler) finalize() {
 	}
 	h.rounds[roundNumber] = r
 	h.currentRound = r
+	h.resetRoundTimer()
 
 	// either we get the current round, the next one, or one of the two final ones
 	switch R := r.(type) {
@@ -291,9 +553,11 @@ func (h *MultiHandler) finalize() {
 	// We have the result
 	case *round.Output:
 		h.result = R.Result
+		h.observer.Finished(h.info(), h.result)
 		h.abort(nil)
 		return
 	default:
+		h.observer.RoundStarted(h.info(), roundNumber)
 	}
 
 	if _, ok := r.(round.BroadcastRound); ok {
@@ -326,19 +590,89 @@ func (h *MultiHandler) finalize() {
 	h.finalize()
 }
 
+// chain replaces the finished protocol by r, the first round of the one following it,
+// and handles the messages which were received for it in the meantime.
+func (h *MultiHandler) chain(r round.Session) {
+	h.currentRound = r
+	h.rounds = map[round.Number]round.Session{r.Number(): r}
+	h.messages = newQueue(r.OtherPartyIDs(), r.FinalRoundNumber())
+	h.broadcast = newQueue(r.OtherPartyIDs(), r.FinalRoundNumber())
+	h.broadcastHashes = map[round.Number][]byte{}
+	h.evidence = map[round.Number]map[party.ID][]*Message{}
+	h.sent = map[round.Number][]*Message{}
+	h.resent = map[party.ID]map[round.Number]int{}
+	h.contested = 0
+	h.observer.RoundStarted(h.info(), r.Number())
+	h.resetRoundTimer()
+	h.finalize()
+
+	pending := h.pending
+	h.pending = nil
+	for _, msg := range pending {
+		if reason := h.accept(msg); reason != RejectNone {
+			h.dropped[reason]++
+			h.observer.MessageRejected(h.info(), msg, reason)
+		}
+	}
+}
+
+// keepPending stores a message for the protocol following the current one, until it starts.
+//
+// If the sender already has maxPending messages kept, one of them is evicted: preferably one for
+// another session than msg, and otherwise the oldest, so that the buffer ends up holding the
+// latest messages of the sender, which are the ones for the session it is running.
+func (h *MultiHandler) keepPending(msg *Message) RejectReason {
+	count, evict := 0, -1
+	for i, m := range h.pending {
+		if m.From != msg.From {
+			continue
+		}
+		count++
+		if evict < 0 || (bytes.Equal(h.pending[evict].SSID, msg.SSID) && !bytes.Equal(m.SSID, msg.SSID)) {
+			evict = i
+		}
+	}
+	if count >= maxPending {
+		evicted := h.pending[evict]
+		h.pending = append(h.pending[:evict], h.pending[evict+1:]...)
+		h.dropped[RejectBufferFull]++
+		h.observer.MessageRejected(h.info(), evicted, RejectBufferFull)
+	}
+	h.pending = append(h.pending, msg)
+	return RejectNone
+}
+
+// chained returns true if r is followed by another protocol.
+func chained(r round.Session) bool {
+	c, ok := r.(round.Chain)
+	return ok && c.Chained()
+}
+
 func (h *MultiHandler) abort(err error, culprits ...party.ID) {
+	if h.roundTimer != nil {
+		h.roundTimer.Stop()
+	}
+	if h.stopContext != nil {
+		h.stopContext()
+	}
 	if err != nil {
+		// the parties who sent a groundless abort stopped participating, and are blamed as well.
+		culprits = culprits[:len(culprits):len(culprits)]
+		for _, id := range h.groundless {
+			if !slices.Contains(culprits, id) {
+				culprits = append(culprits, id)
+			}
+		}
 		h.err = &Error{
 			Culprits: culprits,
 			Err:      err,
 		}
+		msg := h.abortMessage()
+		h.observer.Aborted(h.info(), *h.err)
+		h.record(msg)
 		select {
-		case h.out <- &Message{
-			SSID:     h.currentRound.SSID(),
-			From:     h.currentRound.SelfID(),
-			Protocol: h.currentRound.ProtocolID(),
-			Data:     []byte(h.err.Error()),
-		}:
+		case h.out <- msg:
+			h.observer.MessageSent(h.info(), msg)
 		default:
 		}
 
@@ -348,11 +682,75 @@ func (h *MultiHandler) abort(err error, culprits ...party.ID) {
 
 // Stop cancels the current execution of the protocol, and alerts the other users.
 func (h *MultiHandler) Stop() {
-	if h.err != nil || h.result != nil {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if !h.done() {
 		h.abort(errors.New("aborted by user"), h.currentRound.SelfID())
 	}
 }
 
+// done returns true if the protocol has either completed or aborted.
+func (h *MultiHandler) done() bool {
+	return h.err != nil || h.result != nil
+}
+
+// cancel aborts the protocol once its context is done.
+func (h *MultiHandler) cancel(err error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.done() {
+		return
+	}
+	h.abort(fmt.Errorf("round %d: %w", h.currentRound.Number(), err), h.missing()...)
+}
+
+// resetRoundTimer starts the timer for the current round, replacing the previous one.
+func (h *MultiHandler) resetRoundTimer() {
+	if h.roundTimeout <= 0 {
+		return
+	}
+	if h.roundTimer != nil {
+		h.roundTimer.Stop()
+	}
+	number := h.currentRound.Number()
+	h.roundTimer = time.AfterFunc(h.roundTimeout, func() {
+		h.mtx.Lock()
+		defer h.mtx.Unlock()
+		if h.done() || h.currentRound.Number() != number {
+			return
+		}
+		h.abort(fmt.Errorf("round %d: timed out after %s", number, h.roundTimeout), h.missing()...)
+	})
+}
+
+// missing returns the parties whose messages for the current round haven't been received yet.
+func (h *MultiHandler) missing() []party.ID {
+	r := h.currentRound
+	if h.contested != 0 {
+		// we are waiting for the broadcast messages received by the other parties
+		var missing []party.ID
+		for _, id := range r.OtherPartyIDs() {
+			if h.evidence[h.contested][id] == nil {
+				missing = append(missing, id)
+			}
+		}
+		return missing
+	}
+	number := r.Number()
+	_, isBroadcast := r.(round.BroadcastRound)
+	var missing []party.ID
+	for _, id := range r.OtherPartyIDs() {
+		if isBroadcast && h.broadcast[number] != nil && h.broadcast[number][id] == nil {
+			missing = append(missing, id)
+			continue
+		}
+		if expectsNormalMessage(r) && h.messages[number] != nil && h.messages[number][id] == nil {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
 func expectsNormalMessage(r round.Session) bool {
 	return r.MessageContent() != nil
 }
@@ -400,21 +798,22 @@ func (h *MultiHandler) receivedAll() bool {
 	return true
 }
 
-func (h *MultiHandler) duplicate(msg *Message) bool {
-	if msg.RoundNumber == 0 {
-		return false
-	}
+// duplicate returns RejectDuplicate if msg was already received, RejectUnexpected if its round
+// doesn't expect this kind of message, and RejectNone otherwise.
+func (h *MultiHandler) duplicate(msg *Message) RejectReason {
 	var q map[party.ID]*Message
 	if msg.Broadcast {
 		q = h.broadcast[msg.RoundNumber]
 	} else {
 		q = h.messages[msg.RoundNumber]
 	}
-	// technically, we already received the nil message since it is not expected :)
 	if q == nil {
-		return true
+		return RejectUnexpected
 	}
-	return q[msg.From] != nil
+	if q[msg.From] != nil {
+		return RejectDuplicate
+	}
+	return RejectNone
 }
 
 func (h *MultiHandler) store(msg *Message) {
@@ -430,9 +829,9 @@ func (h *MultiHandler) store(msg *Message) {
 	q[msg.From] = msg
 }
 
-// getRoundMessage attempts to unmarshal a raw Message for round `r` in a round.Message.
+// getRoundMessage attempts to decrypt and unmarshal a raw Message for round `r` in a round.Message.
 // If an error is returned, we should abort.
-func getRoundMessage(msg *Message, r round.Session) (round.Message, error) {
+func (h *MultiHandler) getRoundMessage(msg *Message, r round.Session) (round.Message, error) {
 	var content round.Content
 
 	// there are two possible content messages
@@ -446,8 +845,16 @@ func getRoundMessage(msg *Message, r round.Session) (round.Message, error) {
 		content = r.MessageContent()
 	}
 
+	data := msg.Data
+	if h.identities.encrypts(msg) {
+		var err error
+		if data, err = h.identities.decrypt(msg); err != nil {
+			return round.Message{}, err
+		}
+	}
+
 	// unmarshal message
-	if err := cbor.Unmarshal(msg.Data, content); err != nil {
+	if err := cbor.Unmarshal(data, content); err != nil {
 		return round.Message{}, fmt.Errorf("failed to unmarshal: %w", err)
 	}
 	roundMsg := round.Message{
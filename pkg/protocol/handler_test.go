@@ -0,0 +1,375 @@
+package protocol_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/pkg/taproot"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func drain(h protocol.Handler) {
+	for range h.Listen() {
+	}
+}
+
+func TestMultiHandlerStop(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, partyIDs[0], partyIDs, 1), nil)
+	require.NoError(t, err)
+
+	h.Stop()
+	drain(h)
+	_, err = h.Result()
+	require.Error(t, err)
+	assert.Equal(t, []party.ID{partyIDs[0]}, err.(protocol.Error).Culprits)
+
+	// Stopping again is a no-op.
+	h.Stop()
+}
+
+func TestMultiHandlerContext(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, partyIDs[0], partyIDs, 1), nil, protocol.WithContext(ctx))
+	require.NoError(t, err)
+
+	cancel()
+	drain(h)
+	_, err = h.Result()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, []party.ID{partyIDs[1], partyIDs[2]}, err.(protocol.Error).Culprits)
+}
+
+func TestMultiHandlerRoundTimeout(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	offline := partyIDs[2]
+	network := test.NewNetwork(partyIDs)
+	network.Quit(offline)
+
+	handlers := make(map[party.ID]*protocol.MultiHandler, 2)
+	for i, id := range partyIDs[:2] {
+		// Only the first party times out, the second one is then aborted by the first.
+		timeout := time.Duration(0)
+		if i == 0 {
+			timeout = 200 * time.Millisecond
+		}
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithRoundTimeout(timeout))
+		require.NoError(t, err)
+		handlers[id] = h
+	}
+
+	var wg sync.WaitGroup
+	for id, h := range handlers {
+		wg.Add(1)
+		go func(id party.ID, h *protocol.MultiHandler) {
+			defer wg.Done()
+			test.HandlerLoop(id, h, network)
+		}(id, h)
+	}
+	wg.Wait()
+
+	_, err := handlers[partyIDs[0]].Result()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.Equal(t, []party.ID{offline}, err.(protocol.Error).Culprits)
+
+	_, err = handlers[partyIDs[1]].Result()
+	require.Error(t, err)
+	assert.Equal(t, []party.ID{partyIDs[0]}, err.(protocol.Error).Culprits)
+}
+
+func identities(t *testing.T, partyIDs []party.ID) map[party.ID]*protocol.Identities {
+	keys := make(map[party.ID]protocol.IdentityKey, len(partyIDs))
+	public := make(map[party.ID]protocol.IdentityPublicKey, len(partyIDs))
+	for i, id := range partyIDs {
+		// both kinds of identities can be mixed in the same execution.
+		if i%2 == 0 {
+			_, sk, err := ed25519.GenerateKey(rand.Reader)
+			require.NoError(t, err)
+			keys[id] = protocol.Ed25519Identity(sk)
+		} else {
+			sk, _, err := taproot.GenKey(rand.Reader)
+			require.NoError(t, err)
+			keys[id] = protocol.Secp256k1Identity(sk)
+		}
+		public[id] = keys[id].Public()
+	}
+	result := make(map[party.ID]*protocol.Identities, len(partyIDs))
+	for _, id := range partyIDs {
+		result[id] = &protocol.Identities{Key: keys[id], Keys: public}
+	}
+	return result
+}
+
+func TestAuthenticatedMultiHandler(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	ids := identities(t, partyIDs)
+	network := test.NewNetwork(partyIDs)
+
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithIdentities(ids[id]))
+		require.NoError(t, err)
+		wg.Add(1)
+		go func(id party.ID, h *protocol.MultiHandler) {
+			defer wg.Done()
+			test.HandlerLoop(id, h, network)
+			_, err := h.Result()
+			assert.NoError(t, err)
+		}(id, h)
+	}
+	wg.Wait()
+}
+
+func TestAuthenticatedMultiHandlerCanAccept(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	ids := identities(t, partyIDs)
+	start := func(id party.ID) protocol.StartFunc {
+		return frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1)
+	}
+
+	receiver, err := protocol.NewMultiHandler(start(partyIDs[0]), nil, protocol.WithIdentities(ids[partyIDs[0]]))
+	require.NoError(t, err)
+	sender, err := protocol.NewMultiHandler(start(partyIDs[1]), nil, protocol.WithIdentities(ids[partyIDs[1]]))
+	require.NoError(t, err)
+	msg := <-sender.Listen()
+	assert.True(t, receiver.CanAccept(msg))
+
+	forged := *msg
+	forged.Data = append([]byte{}, msg.Data...)
+	forged.Data[0] ^= 1
+	assert.False(t, receiver.CanAccept(&forged), "tampered content")
+
+	forged = *msg
+	forged.From = partyIDs[2]
+	forged.Signature, err = ids[partyIDs[1]].Key.Sign(forged.Hash())
+	require.NoError(t, err)
+	assert.False(t, receiver.CanAccept(&forged), "forged sender")
+
+	forged = *msg
+	forged.Signature = nil
+	assert.False(t, receiver.CanAccept(&forged), "missing signature")
+
+	// every participant needs an identity, and ours must match our key.
+	missing := &protocol.Identities{Key: ids[partyIDs[0]].Key, Keys: map[party.ID]protocol.IdentityPublicKey{
+		partyIDs[0]: ids[partyIDs[0]].Keys[partyIDs[0]],
+	}}
+	_, err = protocol.NewMultiHandler(start(partyIDs[0]), nil, protocol.WithIdentities(missing))
+	assert.Error(t, err)
+	_, err = protocol.NewMultiHandler(start(partyIDs[0]), nil, protocol.WithIdentities(ids[partyIDs[1]]))
+	assert.Error(t, err)
+}
+
+func TestAcceptWithError(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b := partyIDs[0], partyIDs[1]
+	ids := identities(t, partyIDs)
+	start := func(id party.ID) protocol.StartFunc {
+		return frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1)
+	}
+	receiver, err := protocol.NewMultiHandler(start(a), nil, protocol.WithIdentities(ids[a]))
+	require.NoError(t, err)
+	sender, err := protocol.NewMultiHandler(start(b), nil, protocol.WithIdentities(ids[b]))
+	require.NoError(t, err)
+	msg := <-sender.Listen()
+
+	// modified returns a copy of msg, signed again by b after applying f.
+	modified := func(f func(m *protocol.Message)) *protocol.Message {
+		m := *msg
+		f(&m)
+		m.Signature, err = ids[b].Key.Sign(m.Hash())
+		require.NoError(t, err)
+		return &m
+	}
+	for _, tc := range []struct {
+		msg    *protocol.Message
+		reason protocol.RejectReason
+	}{
+		{nil, protocol.RejectNilMessage},
+		{modified(func(m *protocol.Message) { m.Broadcast, m.To = false, b }), protocol.RejectWrongRecipient},
+		{modified(func(m *protocol.Message) { m.Protocol = "other" }), protocol.RejectWrongProtocol},
+		{modified(func(m *protocol.Message) { m.SSID = []byte("other") }), protocol.RejectWrongSSID},
+		{modified(func(m *protocol.Message) { m.From = "unknown" }), protocol.RejectUnknownSender},
+		{modified(func(m *protocol.Message) { m.Data = nil }), protocol.RejectNilData},
+		{modified(func(m *protocol.Message) { m.RoundNumber = 100 }), protocol.RejectInvalidRound},
+		{modified(func(m *protocol.Message) { m.Type = 100 }), protocol.RejectInvalidType},
+		{modified(func(m *protocol.Message) { m.RoundNumber = 1 }), protocol.RejectStaleRound},
+		{&protocol.Message{SSID: msg.SSID, From: b, Protocol: msg.Protocol, RoundNumber: 2, Data: msg.Data, Broadcast: true}, protocol.RejectInvalidSignature},
+		{msg, protocol.RejectNone},
+		{msg, protocol.RejectDuplicate},
+	} {
+		err := receiver.AcceptWithError(tc.msg)
+		if tc.reason == protocol.RejectNone {
+			assert.NoError(t, err)
+			continue
+		}
+		var rejected *protocol.RejectError
+		require.ErrorAs(t, err, &rejected)
+		assert.Equal(t, tc.reason, rejected.Reason, "got %s", rejected.Reason)
+	}
+	dropped := receiver.Dropped()
+	assert.EqualValues(t, 1, dropped[protocol.RejectDuplicate])
+	assert.EqualValues(t, 1, dropped[protocol.RejectWrongSSID])
+	assert.Len(t, dropped, 11)
+
+	receiver.Stop()
+	err = receiver.AcceptWithError(msg)
+	assert.ErrorContains(t, err, protocol.RejectFinished.String())
+}
+
+func TestEncryptedMultiHandler(t *testing.T) {
+	group := curve.Edwards25519{}
+	partyIDs := test.PartyIDs(3)
+	ids := identities(t, partyIDs)
+	encryptionKeys := make(map[party.ID]curve.Point, len(partyIDs))
+	for _, id := range partyIDs {
+		ids[id].EncryptionKey = sample.Scalar(rand.Reader, group)
+		encryptionKeys[id] = ids[id].EncryptionKey.ActOnBase()
+		ids[id].EncryptionKeys = encryptionKeys
+	}
+	network := test.NewNetwork(partyIDs)
+
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		h, err := protocol.NewMultiHandler(frost.Keygen(group, id, partyIDs, 1), nil, protocol.WithIdentities(ids[id]))
+		require.NoError(t, err)
+		wg.Add(1)
+		go func(id party.ID, h *protocol.MultiHandler) {
+			defer wg.Done()
+			test.HandlerLoop(id, h, network)
+			_, err := h.Result()
+			assert.NoError(t, err)
+		}(id, h)
+	}
+	wg.Wait()
+}
+
+// node is a handler which only sends messages to some parties.
+type node struct {
+	id party.ID
+	h  *protocol.MultiHandler
+	to []party.ID
+}
+
+// route delivers all messages between nodes until none are left, after passing them through tamper.
+func route(nodes []*node, tamper func(*protocol.Message)) {
+	var queue []*protocol.Message
+	var senders []*node
+	drainAll := func() {
+		for _, n := range nodes {
+			for done := false; !done; {
+				select {
+				case msg, ok := <-n.h.Listen():
+					if !ok {
+						done = true
+						continue
+					}
+					tamper(msg)
+					queue = append(queue, msg)
+					senders = append(senders, n)
+				default:
+					done = true
+				}
+			}
+		}
+	}
+	drainAll()
+	for len(queue) > 0 {
+		msg, sender := queue[0], senders[0]
+		queue, senders = queue[1:], senders[1:]
+		for _, n := range nodes {
+			if party.NewIDSlice(sender.to).Contains(n.id) && msg.IsFor(n.id) {
+				n.h.Accept(msg)
+			}
+		}
+		drainAll()
+	}
+}
+
+func TestBroadcastEquivocation(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	ids := identities(t, partyIDs)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+	start := func(id party.ID) *protocol.MultiHandler {
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithIdentities(ids[id]))
+		require.NoError(t, err)
+		return h
+	}
+
+	// c runs two executions, and sends the messages of the first one to a, and the second one to b.
+	nodes := []*node{
+		{a, start(a), []party.ID{b, c}},
+		{b, start(b), []party.ID{a, c}},
+		{c, start(c), []party.ID{a}},
+		{c, start(c), []party.ID{b}},
+	}
+	route(nodes, func(*protocol.Message) {})
+	for _, n := range nodes[:2] {
+		_, err := n.h.Result()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broadcast different messages")
+		assert.Equal(t, []party.ID{c}, err.(protocol.Error).Culprits)
+	}
+
+	// b claims to have received different broadcast messages than everybody else.
+	nodes = []*node{
+		{a, start(a), []party.ID{b, c}},
+		{b, start(b), []party.ID{a, c}},
+		{c, start(c), []party.ID{a, b}},
+	}
+	route(nodes, func(msg *protocol.Message) {
+		if msg.From == b && msg.BroadcastVerification != nil {
+			msg.BroadcastVerification = append([]byte{}, msg.BroadcastVerification...)
+			msg.BroadcastVerification[0] ^= 1
+			msg.Signature, _ = ids[b].Key.Sign(msg.Hash())
+		}
+	})
+	for _, n := range []*node{nodes[0], nodes[2]} {
+		_, err := n.h.Result()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broadcast verification failed")
+		assert.Equal(t, []party.ID{b}, err.(protocol.Error).Culprits)
+	}
+}
+
+func TestMessageHashType(t *testing.T) {
+	msg := &protocol.Message{
+		SSID:        []byte("ssid"),
+		From:        "a",
+		Protocol:    "test",
+		RoundNumber: 2,
+		Data:        []byte("data"),
+		Broadcast:   true,
+	}
+	// round messages hash as they did before Type was added.
+	h := hash.New(
+		hash.BytesWithDomain{TheDomain: "SSID", Bytes: msg.SSID},
+		msg.From,
+		msg.To,
+		hash.BytesWithDomain{TheDomain: "Protocol", Bytes: []byte(msg.Protocol)},
+		msg.RoundNumber,
+		hash.BytesWithDomain{TheDomain: "Content", Bytes: msg.Data},
+		hash.BytesWithDomain{TheDomain: "Broadcast", Bytes: []byte{1}},
+		hash.BytesWithDomain{TheDomain: "BroadcastVerification", Bytes: msg.BroadcastVerification},
+	)
+	assert.Equal(t, h.Sum(), msg.Hash())
+
+	evidence := *msg
+	evidence.Type = protocol.MessageBroadcastEvidence
+	assert.NotEqual(t, msg.Hash(), evidence.Hash())
+}
@@ -0,0 +1,145 @@
+package protocol
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/taproot"
+)
+
+// IdentityKey is the static private key of a party, used to sign the messages it sends.
+type IdentityKey interface {
+	// Sign returns a signature of the 64 byte digest returned by Message.Hash.
+	Sign(digest []byte) ([]byte, error)
+	// Public returns the key verifying the signatures.
+	Public() IdentityPublicKey
+}
+
+// IdentityPublicKey verifies the signatures of the messages sent by a party.
+type IdentityPublicKey interface {
+	// Verify returns true if signature is a valid signature of digest.
+	Verify(digest, signature []byte) bool
+}
+
+// Identities authenticates the messages of a protocol execution.
+//
+// Every outgoing Message is signed with Key, and incoming messages are rejected by CanAccept
+// unless they are signed by the key of their sender in Keys.
+//
+// If EncryptionKey is set, the content of point-to-point messages is also encrypted to their
+// recipient, with a key derived from the Diffie-Hellman secret between the static encryption keys
+// of the sender and the recipient. The ciphertext is bound to the SSID, the round, and both parties,
+// so that these messages can go through an untrusted relay.
+type Identities struct {
+	// Key is the identity key of this party.
+	Key IdentityKey
+	// Keys maps every participant to its identity public key.
+	Keys map[party.ID]IdentityPublicKey
+
+	// EncryptionKey is the static decryption key of this party, or nil if messages are not encrypted.
+	EncryptionKey curve.Scalar
+	// EncryptionKeys maps every participant to EncryptionKey•G, over the same curve.
+	EncryptionKeys map[party.ID]curve.Point
+}
+
+// validate checks that every participant has an identity, and that ours matches Key.
+func (i *Identities) validate(self party.ID, partyIDs []party.ID) error {
+	if i.Key == nil {
+		return errors.New("protocol: missing identity key")
+	}
+	for _, id := range partyIDs {
+		if i.Keys[id] == nil {
+			return fmt.Errorf("protocol: missing identity of party %s", id)
+		}
+	}
+	digest := make([]byte, 64)
+	signature, err := i.Key.Sign(digest)
+	if err != nil {
+		return fmt.Errorf("protocol: identity key: %w", err)
+	}
+	if !i.Keys[self].Verify(digest, signature) {
+		return fmt.Errorf("protocol: identity key doesn't match the identity of party %s", self)
+	}
+	if i.EncryptionKey != nil || i.EncryptionKeys != nil {
+		return i.validateEncryption(self, partyIDs)
+	}
+	return nil
+}
+
+// sign sets the signature of msg.
+func (i *Identities) sign(msg *Message) error {
+	signature, err := i.Key.Sign(msg.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to sign message: %w", err)
+	}
+	msg.Signature = signature
+	return nil
+}
+
+// verify returns true if msg is signed by its sender.
+func (i *Identities) verify(msg *Message) bool {
+	key := i.Keys[msg.From]
+	return key != nil && key.Verify(msg.Hash(), msg.Signature)
+}
+
+// Ed25519Identity is an IdentityKey producing Ed25519 signatures.
+type Ed25519Identity ed25519.PrivateKey
+
+// Sign implements IdentityKey.
+func (k Ed25519Identity) Sign(digest []byte) ([]byte, error) {
+	if len(k) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid Ed25519 private key")
+	}
+	return ed25519.Sign(ed25519.PrivateKey(k), digest), nil
+}
+
+// Public implements IdentityKey.
+func (k Ed25519Identity) Public() IdentityPublicKey {
+	if len(k) != ed25519.PrivateKeySize {
+		return Ed25519PublicKey(nil)
+	}
+	return Ed25519PublicKey(ed25519.PrivateKey(k).Public().(ed25519.PublicKey))
+}
+
+// Ed25519PublicKey is an IdentityPublicKey verifying Ed25519 signatures.
+type Ed25519PublicKey ed25519.PublicKey
+
+// Verify implements IdentityPublicKey.
+func (pk Ed25519PublicKey) Verify(digest, signature []byte) bool {
+	if len(pk) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(pk), digest, signature)
+}
+
+// Secp256k1Identity is an IdentityKey producing BIP-340 signatures over secp256k1.
+type Secp256k1Identity taproot.SecretKey
+
+// Sign implements IdentityKey.
+func (k Secp256k1Identity) Sign(digest []byte) ([]byte, error) {
+	return taproot.SecretKey(k).Sign(rand.Reader, digest)
+}
+
+// Public implements IdentityKey.
+func (k Secp256k1Identity) Public() IdentityPublicKey {
+	public, err := taproot.SecretKey(k).Public()
+	if err != nil {
+		return Secp256k1PublicKey(nil)
+	}
+	return Secp256k1PublicKey(public)
+}
+
+// Secp256k1PublicKey is an IdentityPublicKey verifying BIP-340 signatures over secp256k1.
+type Secp256k1PublicKey taproot.PublicKey
+
+// Verify implements IdentityPublicKey.
+func (pk Secp256k1PublicKey) Verify(digest, signature []byte) bool {
+	if len(pk) != 32 {
+		return false
+	}
+	return taproot.PublicKey(pk).Verify(signature, digest)
+}
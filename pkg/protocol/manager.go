@@ -0,0 +1,312 @@
+package protocol
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSessionTTL is the time after which buffered messages for a session which hasn't started are dropped.
+	DefaultSessionTTL = time.Minute
+	// DefaultMaxPendingMessages bounds the number of messages buffered for a session which hasn't started.
+	DefaultMaxPendingMessages = 256
+	// DefaultMaxPendingSessions bounds the number of sessions which haven't started, and have buffered messages.
+	DefaultMaxPendingSessions = 1024
+)
+
+// sessionKey identifies a protocol execution, as (Message.Protocol, Message.SSID).
+type sessionKey struct {
+	protocol string
+	ssid     string
+}
+
+func messageKey(msg *Message) sessionKey {
+	return sessionKey{protocol: msg.Protocol, ssid: string(msg.SSID)}
+}
+
+// managedSession is a handler started by a SessionManager.
+type managedSession struct {
+	h *MultiHandler
+	// keys contains every (Protocol, SSID) the handler has used, since a protocol can be chained to another one.
+	keys []sessionKey
+	done chan struct{}
+}
+
+// pendingSession holds the messages received for a session before it was started.
+type pendingSession struct {
+	messages []*Message
+	expires  time.Time
+}
+
+// SessionManager runs many protocol executions at once, over the same connections.
+//
+// It routes incoming messages to the handler of their session, identified by (Message.Protocol, Message.SSID),
+// and merges the outgoing messages of all handlers into a single channel. Messages for sessions which haven't
+// started yet are buffered, until either the session starts or the TTL has elapsed. Once a handler is done,
+// its session is removed, and late messages for it are dropped.
+type SessionManager struct {
+	// TTL is the time after which buffered messages for a session which hasn't started are dropped,
+	// and also the time during which a finished session is remembered.
+	TTL time.Duration
+	// MaxPendingMessages bounds the number of messages buffered per session.
+	MaxPendingMessages int
+	// MaxPendingSessions bounds the number of sessions with buffered messages.
+	MaxPendingSessions int
+
+	mtx      sync.Mutex
+	sessions map[sessionKey]*managedSession
+	pending  map[sessionKey]*pendingSession
+	// finished contains the expiry of the sessions which are done.
+	finished  map[sessionKey]time.Time
+	lastSweep time.Time
+	out       chan *Message
+	closed    bool
+	wg        sync.WaitGroup
+	// dropped counts the messages which were not accepted, by reason.
+	dropped map[RejectReason]uint64
+}
+
+// NewSessionManager returns a SessionManager which drops buffered messages after ttl,
+// or after DefaultSessionTTL if ttl is 0.
+func NewSessionManager(ttl time.Duration) *SessionManager {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &SessionManager{
+		TTL:                ttl,
+		MaxPendingMessages: DefaultMaxPendingMessages,
+		MaxPendingSessions: DefaultMaxPendingSessions,
+		sessions:           map[sessionKey]*managedSession{},
+		pending:            map[sessionKey]*pendingSession{},
+		finished:           map[sessionKey]time.Time{},
+		lastSweep:          time.Now(),
+		out:                make(chan *Message),
+		dropped:            map[RejectReason]uint64{},
+	}
+}
+
+// Start adds a handler to the manager, and delivers the messages which were buffered for its session.
+// These are delivered in the background, while the messages of the handler are forwarded to Listen.
+//
+// The returned channel is closed once the handler is done, after which its result is given by Handler.Result().
+// An error is returned if a handler for the same session was already started.
+func (m *SessionManager) Start(h *MultiHandler) (<-chan struct{}, error) {
+	key := sessionKey{protocol: h.ProtocolID(), ssid: string(h.SSID())}
+	m.mtx.Lock()
+	if m.closed {
+		m.mtx.Unlock()
+		return nil, errors.New("protocol: session manager is closed")
+	}
+	if _, ok := m.sessions[key]; ok {
+		m.mtx.Unlock()
+		return nil, errors.New("protocol: session already started")
+	}
+	if _, ok := m.finished[key]; ok {
+		m.mtx.Unlock()
+		return nil, errors.New("protocol: session already finished")
+	}
+	s := &managedSession{h: h, done: make(chan struct{})}
+	pending := m.addKey(s, key)
+	m.wg.Add(1)
+	m.mtx.Unlock()
+
+	go m.forward(s)
+	replay(h, pending)
+	return s.done, nil
+}
+
+// replay delivers the messages buffered for a session to its handler, in the background.
+//
+// Accepting them may complete several rounds, whose messages only fit in the channel of the handler
+// once forward reads them, so this can't be done by forward itself, nor block the caller of Start.
+func replay(h *MultiHandler, messages []*Message) {
+	if len(messages) == 0 {
+		return
+	}
+	go func() {
+		for _, msg := range messages {
+			h.Accept(msg)
+		}
+	}()
+}
+
+// addKey routes the messages for key to s, and returns the ones which were buffered.
+// It must be called with the lock held.
+func (m *SessionManager) addKey(s *managedSession, key sessionKey) []*Message {
+	s.keys = append(s.keys, key)
+	m.sessions[key] = s
+	var messages []*Message
+	if p := m.pending[key]; p != nil {
+		if time.Now().Before(p.expires) {
+			messages = p.messages
+		}
+		delete(m.pending, key)
+	}
+	return messages
+}
+
+// forward sends the outgoing messages of a handler to the merged channel, until it is done.
+func (m *SessionManager) forward(s *managedSession) {
+	defer m.wg.Done()
+	for msg := range s.h.Listen() {
+		// the handler may have continued with a chained protocol.
+		key := messageKey(msg)
+		m.mtx.Lock()
+		var pending []*Message
+		if m.sessions[key] == nil {
+			pending = m.addKey(s, key)
+		}
+		m.mtx.Unlock()
+		replay(s.h, pending)
+		m.out <- msg
+	}
+
+	m.mtx.Lock()
+	expires := time.Now().Add(m.TTL)
+	for _, key := range s.keys {
+		delete(m.sessions, key)
+		m.finished[key] = expires
+	}
+	m.mtx.Unlock()
+	close(s.done)
+}
+
+// Listen returns a channel with the outgoing messages of all handlers, which must be sent to other parties.
+// The channel is closed by Close, once all handlers are done.
+func (m *SessionManager) Listen() <-chan *Message {
+	return m.out
+}
+
+// Accept delivers msg to the handler of its session, or buffers it if the session hasn't started yet.
+//
+// Messages for finished sessions, and messages which exceed the bounds of the manager, are dropped.
+func (m *SessionManager) Accept(msg *Message) {
+	_ = m.AcceptWithError(msg)
+}
+
+// AcceptWithError is like Accept, but returns a *RejectError if the message was dropped,
+// either by the manager or by the handler of its session.
+func (m *SessionManager) AcceptWithError(msg *Message) error {
+	if msg == nil {
+		return m.reject(RejectNilMessage, msg)
+	}
+	key := messageKey(msg)
+	m.mtx.Lock()
+	m.sweep()
+	if s := m.sessions[key]; s != nil {
+		m.mtx.Unlock()
+		err := s.h.AcceptWithError(msg)
+		var rejected *RejectError
+		if errors.As(err, &rejected) {
+			m.mtx.Lock()
+			m.dropped[rejected.Reason]++
+			m.mtx.Unlock()
+		}
+		return err
+	}
+	m.mtx.Unlock()
+	if reason := m.buffer(key, msg); reason != RejectNone {
+		return m.reject(reason, msg)
+	}
+	return nil
+}
+
+// buffer keeps msg until its session starts, and returns the reason why it was dropped, or RejectNone.
+func (m *SessionManager) buffer(key sessionKey, msg *Message) RejectReason {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if _, ok := m.finished[key]; ok || m.closed {
+		return RejectFinished
+	}
+	p := m.pending[key]
+	if p == nil {
+		if len(m.pending) >= m.MaxPendingSessions {
+			return RejectBufferFull
+		}
+		p = &pendingSession{expires: time.Now().Add(m.TTL)}
+		m.pending[key] = p
+	}
+	if len(p.messages) >= m.MaxPendingMessages {
+		return RejectBufferFull
+	}
+	p.messages = append(p.messages, msg)
+	return RejectNone
+}
+
+func (m *SessionManager) reject(reason RejectReason, msg *Message) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.dropped[reason]++
+	return newRejectError(reason, msg)
+}
+
+// Dropped returns the number of messages dropped so far, by reason, either by the manager or by the handlers.
+func (m *SessionManager) Dropped() map[RejectReason]uint64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	dropped := make(map[RejectReason]uint64, len(m.dropped))
+	for reason, count := range m.dropped {
+		dropped[reason] = count
+	}
+	return dropped
+}
+
+// sweep drops the expired buffers and finished sessions. It must be called with the lock held.
+func (m *SessionManager) sweep() {
+	now := time.Now()
+	if now.Sub(m.lastSweep) < m.TTL/2 {
+		return
+	}
+	for key, p := range m.pending {
+		if now.After(p.expires) {
+			delete(m.pending, key)
+		}
+	}
+	for key, expires := range m.finished {
+		if now.After(expires) {
+			delete(m.finished, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+// Sessions returns the number of handlers which are not done yet.
+func (m *SessionManager) Sessions() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	count := 0
+	seen := make(map[*managedSession]bool, len(m.sessions))
+	for _, s := range m.sessions {
+		if !seen[s] {
+			seen[s] = true
+			count++
+		}
+	}
+	return count
+}
+
+// Close stops all handlers, drops the buffered messages, and closes the channel returned by Listen
+// once the last messages of the handlers have been read from it.
+func (m *SessionManager) Close() {
+	m.mtx.Lock()
+	if m.closed {
+		m.mtx.Unlock()
+		return
+	}
+	m.closed = true
+	m.pending = map[sessionKey]*pendingSession{}
+	handlers := make([]*MultiHandler, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		handlers = append(handlers, s.h)
+	}
+	m.mtx.Unlock()
+
+	for _, h := range handlers {
+		h.Stop()
+	}
+	go func() {
+		m.wg.Wait()
+		close(m.out)
+	}()
+}
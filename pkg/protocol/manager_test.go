@@ -0,0 +1,228 @@
+package protocol_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/MixinNetwork/multi-party-sig/protocols/ssid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+	late := partyIDs[2]
+	sessions := 5
+
+	managers := make(map[party.ID]*protocol.SessionManager, len(partyIDs))
+	for _, id := range partyIDs {
+		managers[id] = protocol.NewSessionManager(time.Minute)
+	}
+	// a single connection per pair of parties, shared by all sessions.
+	for _, id := range partyIDs {
+		go func(id party.ID) {
+			for msg := range managers[id].Listen() {
+				for _, other := range partyIDs {
+					if other != id && msg.IsFor(other) {
+						managers[other].Accept(msg)
+					}
+				}
+			}
+		}(id)
+	}
+
+	start := func(id party.ID, i int) protocol.StartFunc {
+		// the last session is chained behind an SSID agreement, and changes its session on the way.
+		if i == sessions-1 {
+			return ssid.Chain(id, partyIDs, frost.Keygen(group, id, partyIDs, 1))
+		}
+		return frost.Keygen(group, id, partyIDs, 1)
+	}
+	handlers := make(map[party.ID][]*protocol.MultiHandler, len(partyIDs))
+	var done []<-chan struct{}
+	for _, id := range partyIDs {
+		if id == late {
+			continue
+		}
+		for i := 0; i < sessions; i++ {
+			h, err := protocol.NewMultiHandler(start(id, i), []byte(fmt.Sprint(i)))
+			require.NoError(t, err)
+			d, err := managers[id].Start(h)
+			require.NoError(t, err)
+			handlers[id] = append(handlers[id], h)
+			done = append(done, d)
+		}
+	}
+
+	// the messages for the last party are buffered until it starts its sessions.
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < sessions; i++ {
+		h, err := protocol.NewMultiHandler(start(late, i), []byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+		d, err := managers[late].Start(h)
+		require.NoError(t, err)
+		handlers[late] = append(handlers[late], h)
+		done = append(done, d)
+	}
+	for _, d := range done {
+		select {
+		case <-d:
+		case <-time.After(30 * time.Second):
+			t.Fatal("session didn't finish")
+		}
+	}
+
+	for i := 0; i < sessions; i++ {
+		var publicKey curve.Point
+		for _, id := range partyIDs {
+			result, err := handlers[id][i].Result()
+			require.NoError(t, err)
+			if publicKey == nil {
+				publicKey = result.(*frost.Config).PublicKey
+			}
+			assert.True(t, publicKey.Equal(result.(*frost.Config).PublicKey))
+		}
+	}
+	for _, id := range partyIDs {
+		assert.Zero(t, managers[id].Sessions(), "finished sessions should be removed")
+		// a finished session can't be started again.
+		_, err := managers[id].Start(handlers[id][0])
+		assert.Error(t, err)
+		managers[id].Close()
+	}
+}
+
+// TestSessionManagerStartBuffered checks that a session can be started with a full round of messages
+// buffered for it, before anybody reads the messages of the manager.
+func TestSessionManagerStartBuffered(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(5)
+	late := partyIDs[0]
+
+	managers := make(map[party.ID]*protocol.SessionManager, len(partyIDs))
+	for _, id := range partyIDs {
+		managers[id] = protocol.NewSessionManager(time.Minute)
+	}
+	forward := func(id party.ID) {
+		for msg := range managers[id].Listen() {
+			for _, other := range partyIDs {
+				if other != id && msg.IsFor(other) {
+					managers[other].Accept(msg)
+				}
+			}
+		}
+	}
+
+	handlers := make(map[party.ID]*protocol.MultiHandler, len(partyIDs))
+	done := make(map[party.ID]<-chan struct{}, len(partyIDs))
+	for _, id := range partyIDs[1:] {
+		go forward(id)
+		h, err := protocol.NewMultiHandler(frost.Keygen(group, id, partyIDs, 2), nil)
+		require.NoError(t, err)
+		handlers[id] = h
+		done[id], err = managers[id].Start(h)
+		require.NoError(t, err)
+	}
+	// the round 2 broadcasts of the others are buffered by the last party.
+	time.Sleep(50 * time.Millisecond)
+
+	h, err := protocol.NewMultiHandler(frost.Keygen(group, late, partyIDs, 2), nil)
+	require.NoError(t, err)
+	handlers[late] = h
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		done[late], err = managers[late].Start(h)
+	}()
+	select {
+	case <-started:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start blocked while delivering the buffered messages")
+	}
+	go forward(late)
+
+	var publicKey curve.Point
+	for _, id := range partyIDs {
+		select {
+		case <-done[id]:
+		case <-time.After(30 * time.Second):
+			t.Fatal("session didn't finish")
+		}
+		result, err := handlers[id].Result()
+		require.NoError(t, err)
+		if publicKey == nil {
+			publicKey = result.(*frost.Config).PublicKey
+		}
+		assert.True(t, publicKey.Equal(result.(*frost.Config).PublicKey))
+		managers[id].Close()
+	}
+}
+
+// delivered starts b's session, and returns true if b received a's round 2 broadcast,
+// which makes it send its round 3 messages.
+func delivered(t *testing.T, m *protocol.SessionManager, partyIDs party.IDSlice) bool {
+	defer func() {
+		m.Close()
+		for range m.Listen() {
+		}
+	}()
+	h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, partyIDs[1], partyIDs, 1), nil)
+	require.NoError(t, err)
+	_, err = m.Start(h)
+	require.NoError(t, err)
+	_, err = m.Start(h)
+	assert.Error(t, err, "a session can only be started once")
+	for {
+		select {
+		case msg := <-m.Listen():
+			if msg.RoundNumber == 3 {
+				return true
+			}
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+}
+
+func TestSessionManagerBounds(t *testing.T) {
+	partyIDs := test.PartyIDs(2)
+	h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, partyIDs[0], partyIDs, 1), nil)
+	require.NoError(t, err)
+	// the round 2 broadcast of a.
+	msg := <-h.Listen()
+
+	m := protocol.NewSessionManager(time.Minute)
+	m.Accept(msg)
+	assert.True(t, delivered(t, m, partyIDs), "buffered messages should be delivered")
+
+	m = protocol.NewSessionManager(10 * time.Millisecond)
+	m.Accept(msg)
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, delivered(t, m, partyIDs), "expired messages should be dropped")
+
+	m = protocol.NewSessionManager(time.Minute)
+	m.MaxPendingSessions = 1
+	m.Accept(&protocol.Message{Protocol: "other"})
+	m.Accept(msg)
+	assert.False(t, delivered(t, m, partyIDs), "messages for too many sessions should be dropped")
+
+	m = protocol.NewSessionManager(time.Minute)
+	m.MaxPendingMessages = 1
+	m.Accept(&protocol.Message{Protocol: msg.Protocol, SSID: msg.SSID, RoundNumber: 3})
+	var rejected *protocol.RejectError
+	require.ErrorAs(t, m.AcceptWithError(msg), &rejected)
+	assert.Equal(t, protocol.RejectBufferFull, rejected.Reason)
+	assert.False(t, delivered(t, m, partyIDs), "messages beyond the bound should be dropped")
+	// the session is done once the manager is closed, and its messages are then dropped.
+	require.ErrorAs(t, m.AcceptWithError(msg), &rejected)
+	assert.Equal(t, protocol.RejectFinished, rejected.Reason)
+	assert.Equal(t, map[protocol.RejectReason]uint64{protocol.RejectBufferFull: 1, protocol.RejectFinished: 1}, m.Dropped())
+}
@@ -9,6 +9,23 @@ import (
 	"github.com/fxamacker/cbor/v2"
 )
 
+// MessageType distinguishes the messages delivered to rounds from the ones handled by the MultiHandler itself.
+type MessageType uint8
+
+const (
+	// MessageRound contains the content of a round.
+	MessageRound MessageType = iota
+	// MessageBroadcastEvidence contains the signed broadcast messages received by the sender for RoundNumber.
+	// It is sent when parties disagree on these messages, in order to identify who sent different ones.
+	MessageBroadcastEvidence
+	// MessageResendRequest asks the recipient to send its messages for RoundNumber again.
+	MessageResendRequest
+	// MessageAbort tells the other parties that the sender aborted the protocol during RoundNumber.
+	// It gives the reason, the blamed parties, and the hash of the offending message if there is one,
+	// so that the other parties can refute it.
+	MessageAbort
+)
+
 type Message struct {
 	// SSID is a byte string which uniquely identifies the session this message belongs to.
 	SSID []byte
@@ -27,6 +44,11 @@ type Message struct {
 	// BroadcastVerification is the hash of all messages broadcast by the parties,
 	// and is included in all messages in the round following a broadcast round.
 	BroadcastVerification []byte
+	// Type is MessageRound, unless the message is handled by the MultiHandler itself.
+	Type MessageType
+	// Signature is the signature of Hash() by the identity key of the sender,
+	// if the protocol execution is authenticated with Identities.
+	Signature []byte
 }
 
 // String implements fmt.Stringer.
@@ -43,7 +65,10 @@ func (m Message) IsFor(id party.ID) bool {
 }
 
 // Hash returns a 64 byte hash of the message content, including the headers.
-// Can be used to produce a signature for the message.
+// This is what Signature signs, and so doesn't include it.
+//
+// The Type is only included for messages handled by the MultiHandler itself,
+// so that the hash of round messages is the same as before Type was added.
 func (m *Message) Hash() []byte {
 	var broadcast byte
 	if m.Broadcast {
@@ -59,6 +84,9 @@ func (m *Message) Hash() []byte {
 		hash.BytesWithDomain{TheDomain: "Broadcast", Bytes: []byte{broadcast}},
 		hash.BytesWithDomain{TheDomain: "BroadcastVerification", Bytes: m.BroadcastVerification},
 	)
+	if m.Type != MessageRound {
+		_ = h.WriteAny(hash.BytesWithDomain{TheDomain: "Type", Bytes: []byte{byte(m.Type)}})
+	}
 	return h.Sum()
 }
 
@@ -75,6 +103,8 @@ type marshallableMessage struct {
 	Data                  []byte
 	Broadcast             bool
 	BroadcastVerification []byte
+	Type                  MessageType
+	Signature             []byte
 }
 
 func (m *Message) toMarshallable() *marshallableMessage {
@@ -87,6 +117,8 @@ func (m *Message) toMarshallable() *marshallableMessage {
 		Data:                  m.Data,
 		Broadcast:             m.Broadcast,
 		BroadcastVerification: m.BroadcastVerification,
+		Type:                  m.Type,
+		Signature:             m.Signature,
 	}
 }
 
@@ -98,7 +130,7 @@ func (m *Message) MarshalBinary() ([]byte, error) {
 func (m *Message) UnmarshalBinary(data []byte) error {
 	deserialized := m.toMarshallable()
 	if err := cbor.Unmarshal(data, deserialized); err != nil {
-		return nil
+		return err
 	}
 	m.SSID = deserialized.SSID
 	m.From = deserialized.From
@@ -108,5 +140,7 @@ func (m *Message) UnmarshalBinary(data []byte) error {
 	m.Data = deserialized.Data
 	m.Broadcast = deserialized.Broadcast
 	m.BroadcastVerification = deserialized.BroadcastVerification
+	m.Type = deserialized.Type
+	m.Signature = deserialized.Signature
 	return nil
 }
@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+)
+
+// SessionInfo identifies the protocol execution an Observer is notified about.
+type SessionInfo struct {
+	// ProtocolID is the identifier of the protocol, which changes when a protocol is chained to another one.
+	ProtocolID string
+	// SSID is the session identifier of the protocol.
+	SSID []byte
+	// SelfID is the party running the handler.
+	SelfID party.ID
+}
+
+// Observer receives events about the execution of a protocol by a MultiHandler,
+// for instance to export metrics or traces. It is given to a handler with
+// the WithObserver option.
+//
+// Its methods are called synchronously, while the handler's lock is held,
+// so they should return quickly, and must not call the handler.
+type Observer interface {
+	// RoundStarted is called when the handler enters a round, and starts waiting for its messages.
+	RoundStarted(s SessionInfo, number round.Number)
+	// RoundFinished is called once the round has received all its messages, and was finalized.
+	// finalize is the time spent by the round computing the messages of the next one.
+	RoundFinished(s SessionInfo, number round.Number, finalize time.Duration)
+	// MessageReceived is called for every message accepted by the handler, before it is verified.
+	// Its size is len(msg.Data).
+	MessageReceived(s SessionInfo, msg *Message)
+	// MessageRejected is called for every message dropped by the handler.
+	MessageRejected(s SessionInfo, msg *Message, reason RejectReason)
+	// MessageSent is called for every message returned by Listen().
+	MessageSent(s SessionInfo, msg *Message)
+	// MessageVerified is called once a message was verified and stored by its round, with the time it took,
+	// and the error which aborts the protocol if the message was invalid.
+	MessageVerified(s SessionInfo, msg *Message, d time.Duration, err error)
+	// Aborted is called when the protocol fails.
+	Aborted(s SessionInfo, err Error)
+	// Finished is called when the protocol outputs its result.
+	Finished(s SessionInfo, result interface{})
+}
+
+// info returns the SessionInfo of the current round.
+func (h *MultiHandler) info() SessionInfo {
+	r := h.currentRound
+	return SessionInfo{ProtocolID: r.ProtocolID(), SSID: r.SSID(), SelfID: r.SelfID()}
+}
+
+// nopObserver is used by handlers without an Observer.
+type nopObserver struct{}
+
+func (nopObserver) RoundStarted(SessionInfo, round.Number)                      {}
+func (nopObserver) RoundFinished(SessionInfo, round.Number, time.Duration)      {}
+func (nopObserver) MessageReceived(SessionInfo, *Message)                       {}
+func (nopObserver) MessageRejected(SessionInfo, *Message, RejectReason)         {}
+func (nopObserver) MessageSent(SessionInfo, *Message)                           {}
+func (nopObserver) MessageVerified(SessionInfo, *Message, time.Duration, error) {}
+func (nopObserver) Aborted(SessionInfo, Error)                                  {}
+func (nopObserver) Finished(SessionInfo, interface{})                           {}
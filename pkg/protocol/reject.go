@@ -0,0 +1,97 @@
+package protocol
+
+import "fmt"
+
+// RejectReason explains why a handler dropped a message.
+type RejectReason uint8
+
+const (
+	// RejectNone means that the message was accepted.
+	RejectNone RejectReason = iota
+	// RejectNilMessage is given for a nil *Message.
+	RejectNilMessage
+	// RejectWrongRecipient is given for point-to-point messages addressed to another party.
+	RejectWrongRecipient
+	// RejectUnknownSender is given for messages from a party which doesn't participate in the protocol.
+	RejectUnknownSender
+	// RejectNilData is given for messages without content.
+	RejectNilData
+	// RejectWrongProtocol is given for messages of another protocol.
+	RejectWrongProtocol
+	// RejectWrongSSID is given for messages of another execution of the same protocol.
+	RejectWrongSSID
+	// RejectInvalidRound is given for messages for round 0, or for a round after the final one.
+	RejectInvalidRound
+	// RejectStaleRound is given for messages for a round which is already finished.
+	RejectStaleRound
+	// RejectInvalidType is given for messages with an unknown MessageType, or which can't be handled
+	// with the current configuration.
+	RejectInvalidType
+	// RejectInvalidSignature is given for messages whose signature doesn't match the identity of their sender.
+	RejectInvalidSignature
+	// RejectUnexpected is given for broadcast or point-to-point messages for a round which doesn't expect any.
+	RejectUnexpected
+	// RejectDuplicate is given for messages which were already received.
+	RejectDuplicate
+	// RejectFinished is given for messages which arrive once the protocol has output its result, or aborted.
+	RejectFinished
+	// RejectBufferFull is given for messages for a later session which exceed the bounds of the buffer,
+	// or which are evicted from it by later messages of the same sender.
+	RejectBufferFull
+	// RejectResendLimit is given for requests to resend a round which was already sent again too many times.
+	RejectResendLimit
+	// RejectGroundlessAbort is given for aborts which we could refute, see MessageAbort.
+	RejectGroundlessAbort
+)
+
+var rejectReasons = [...]string{
+	RejectNone:             "none",
+	RejectNilMessage:       "nil message",
+	RejectWrongRecipient:   "wrong recipient",
+	RejectUnknownSender:    "unknown sender",
+	RejectNilData:          "nil data",
+	RejectWrongProtocol:    "wrong protocol",
+	RejectWrongSSID:        "wrong SSID",
+	RejectInvalidRound:     "invalid round",
+	RejectStaleRound:       "stale round",
+	RejectInvalidType:      "invalid type",
+	RejectInvalidSignature: "invalid signature",
+	RejectUnexpected:       "unexpected message",
+	RejectDuplicate:        "duplicate",
+	RejectFinished:         "finished",
+	RejectBufferFull:       "buffer full",
+	RejectResendLimit:      "resend limit",
+	RejectGroundlessAbort:  "groundless abort",
+}
+
+// String implements fmt.Stringer.
+func (r RejectReason) String() string {
+	if int(r) < len(rejectReasons) {
+		return rejectReasons[r]
+	}
+	return fmt.Sprintf("RejectReason(%d)", r)
+}
+
+// RejectError is returned by AcceptWithError when a message is dropped.
+type RejectError struct {
+	Reason RejectReason
+	// From, Protocol and RoundNumber are copied from the message, if any.
+	From        string
+	Protocol    string
+	RoundNumber int
+}
+
+func newRejectError(reason RejectReason, msg *Message) *RejectError {
+	e := &RejectError{Reason: reason}
+	if msg != nil {
+		e.From = string(msg.From)
+		e.Protocol = msg.Protocol
+		e.RoundNumber = int(msg.RoundNumber)
+	}
+	return e
+}
+
+// Error implements error.
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("protocol: message from %q for %s round %d rejected: %s", e.From, e.Protocol, e.RoundNumber, e.Reason)
+}
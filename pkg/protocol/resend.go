@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+)
+
+// maxResends bounds the number of times a party can ask for the messages of the same round,
+// so that it can't make us flood the network.
+const maxResends = 3
+
+// send records msg for retransmission, and returns it through Listen().
+func (h *MultiHandler) send(msg *Message) {
+	h.record(msg)
+	h.observer.MessageSent(h.info(), msg)
+	h.out <- msg
+}
+
+// record keeps msg, so that it can be sent again by Resend.
+func (h *MultiHandler) record(msg *Message) {
+	h.sent[msg.RoundNumber] = append(h.sent[msg.RoundNumber], msg)
+}
+
+// Resend returns the messages for the given round which were sent to party id, or broadcast,
+// so that they can be sent again, for instance after a transport reconnects to id.
+//
+// The messages are exactly the ones which were returned by Listen(), so receiving one twice is harmless.
+// If we aborted during that round, our MessageAbort is included. Resend can still be called once the protocol is done,
+// in order to help the parties which haven't received our last messages: a resend request
+// is then rejected with RejectFinished, and the transport should answer it with Resend(msg.From, msg.RoundNumber).
+func (h *MultiHandler) Resend(id party.ID, number round.Number) []*Message {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.resend(id, number)
+}
+
+func (h *MultiHandler) resend(id party.ID, number round.Number) []*Message {
+	var messages []*Message
+	for _, msg := range h.sent[number] {
+		if msg.IsFor(id) {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// RequestMissing returns requests asking the parties whose messages for the current round haven't arrived
+// to send them again. The requests are signed when the handler authenticates messages.
+//
+// They can be sent periodically while a round is stalled, or after a transport reconnects.
+func (h *MultiHandler) RequestMissing() []*Message {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.done() {
+		return nil
+	}
+	r := h.currentRound
+	number := r.Number()
+	if h.contested != 0 {
+		number = h.contested
+	}
+	var requests []*Message
+	for _, id := range h.missing() {
+		msg := &Message{
+			SSID:        r.SSID(),
+			From:        r.SelfID(),
+			To:          id,
+			Protocol:    r.ProtocolID(),
+			RoundNumber: number,
+			Data:        []byte{},
+			Type:        MessageResendRequest,
+		}
+		if h.identities != nil {
+			if err := h.identities.sign(msg); err != nil {
+				continue
+			}
+		}
+		requests = append(requests, msg)
+	}
+	return requests
+}
+
+// acceptResendRequest sends the messages asked for by msg again, unless the sender has asked too many times.
+//
+// The messages are dropped if the channel returned by Listen() is full, since the sender can ask again,
+// and Accept must not block.
+func (h *MultiHandler) acceptResendRequest(msg *Message) RejectReason {
+	if h.resent[msg.From] == nil {
+		h.resent[msg.From] = map[round.Number]int{}
+	}
+	if h.resent[msg.From][msg.RoundNumber] >= maxResends {
+		return RejectResendLimit
+	}
+	h.resent[msg.From][msg.RoundNumber]++
+	h.observer.MessageReceived(h.info(), msg)
+	for _, m := range h.resend(msg.From, msg.RoundNumber) {
+		select {
+		case h.out <- m:
+			h.observer.MessageSent(h.info(), m)
+		default:
+			return RejectNone
+		}
+	}
+	return RejectNone
+}
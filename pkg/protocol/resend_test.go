@@ -0,0 +1,166 @@
+package protocol_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResend(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b := partyIDs[0], partyIDs[1]
+	ids := identities(t, partyIDs)
+	handlers := make(map[party.ID]*protocol.MultiHandler, len(partyIDs))
+	for _, id := range partyIDs {
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithIdentities(ids[id]))
+		require.NoError(t, err)
+		handlers[id] = h
+	}
+
+	// the first copy of every message from a to b is lost.
+	lost := map[*protocol.Message]bool{}
+	var queue []*protocol.Message
+	drain := func() {
+		for _, id := range partyIDs {
+			for done := false; !done; {
+				select {
+				case msg, ok := <-handlers[id].Listen():
+					done = !ok
+					if ok {
+						queue = append(queue, msg)
+					}
+				default:
+					done = true
+				}
+			}
+		}
+	}
+	requests := 0
+	for i := 0; i < 20; i++ {
+		for drain(); len(queue) > 0; drain() {
+			msg := queue[0]
+			queue = queue[1:]
+			for _, id := range partyIDs {
+				if id == msg.From || !msg.IsFor(id) {
+					continue
+				}
+				if msg.From == a && id == b && !lost[msg] {
+					lost[msg] = true
+					continue
+				}
+				// a finished handler doesn't answer requests anymore, so the transport does.
+				var rejected *protocol.RejectError
+				err := handlers[id].AcceptWithError(msg)
+				if errors.As(err, &rejected) && rejected.Reason == protocol.RejectFinished && msg.Type == protocol.MessageResendRequest {
+					queue = append(queue, handlers[id].Resend(msg.From, msg.RoundNumber)...)
+				}
+			}
+		}
+		if _, err := handlers[b].Result(); err == nil {
+			break
+		}
+		// the protocol stalls until b asks a for the messages it is missing.
+		for _, id := range partyIDs {
+			for _, req := range handlers[id].RequestMissing() {
+				requests++
+				queue = append(queue, req)
+			}
+		}
+	}
+	assert.NotZero(t, requests)
+	for _, id := range partyIDs {
+		_, err := handlers[id].Result()
+		require.NoError(t, err)
+	}
+
+	// the messages can still be sent again once the protocol is done.
+	resent := handlers[a].Resend(b, 2)
+	require.Len(t, resent, 1)
+	assert.True(t, resent[0].Broadcast)
+	assert.Empty(t, handlers[a].Resend(b, 1))
+}
+
+func TestResendRequest(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b := partyIDs[0], partyIDs[1]
+	ids := identities(t, partyIDs)
+	ha, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, a, partyIDs, 1), nil, protocol.WithIdentities(ids[a]))
+	require.NoError(t, err)
+	hb, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, b, partyIDs, 1), nil, protocol.WithIdentities(ids[b]))
+	require.NoError(t, err)
+	broadcast := <-ha.Listen()
+
+	var request *protocol.Message
+	for _, req := range hb.RequestMissing() {
+		if req.To == a {
+			request = req
+		}
+	}
+	require.NotNil(t, request)
+	assert.Equal(t, protocol.MessageResendRequest, request.Type)
+
+	// requests survive serialization.
+	data, err := request.MarshalBinary()
+	require.NoError(t, err)
+	request = &protocol.Message{}
+	require.NoError(t, request.UnmarshalBinary(data))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ha.AcceptWithError(request))
+		assert.Equal(t, broadcast, <-ha.Listen())
+	}
+	var rejected *protocol.RejectError
+	require.ErrorAs(t, ha.AcceptWithError(request), &rejected)
+	assert.Equal(t, protocol.RejectResendLimit, rejected.Reason)
+
+	// requests must be signed by the party asking for the messages.
+	forged := *request
+	forged.RoundNumber = 3
+	require.ErrorAs(t, ha.AcceptWithError(&forged), &rejected)
+	assert.Equal(t, protocol.RejectInvalidSignature, rejected.Reason)
+}
+
+func TestResendAfterRestore(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b := partyIDs[0], partyIDs[1]
+	ha, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, a, partyIDs, 1), nil)
+	require.NoError(t, err)
+	hb, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, b, partyIDs, 1), nil)
+	require.NoError(t, err)
+	broadcast := <-ha.Listen()
+
+	var request *protocol.Message
+	for _, req := range hb.RequestMissing() {
+		if req.To == a {
+			request = req
+		}
+	}
+	require.NotNil(t, request)
+	require.NoError(t, ha.AcceptWithError(request))
+	<-ha.Listen()
+
+	key := make([]byte, protocol.SnapshotKeySize)
+	data, err := ha.Snapshot(key)
+	require.NoError(t, err)
+	ha, err = protocol.RestoreMultiHandler(frost.RestoreKeygen, data, key)
+	require.NoError(t, err)
+
+	// the restored handler still knows what it sent, and how many times it was asked for it.
+	resent := ha.Resend(b, broadcast.RoundNumber)
+	require.Len(t, resent, 1)
+	assert.Equal(t, broadcast.Hash(), resent[0].Hash())
+	for i := 0; i < 2; i++ {
+		require.NoError(t, ha.AcceptWithError(request))
+		assert.Equal(t, broadcast.Hash(), (<-ha.Listen()).Hash())
+	}
+	var rejected *protocol.RejectError
+	require.ErrorAs(t, ha.AcceptWithError(request), &rejected)
+	assert.Equal(t, protocol.RejectResendLimit, rejected.Reason)
+}
@@ -0,0 +1,162 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// SnapshotKeySize is the size of the keys used to encrypt snapshots.
+const SnapshotKeySize = 32
+
+// snapshotDomain is used as additional data when encrypting snapshots.
+const snapshotDomain = "multi-party-sig/snapshot"
+
+// RestoreFunc recreates a round from the output of round.SessionMarshaler.MarshalSession.
+type RestoreFunc func(data []byte) (round.Session, error)
+
+type snapshot struct {
+	Session         []byte
+	Messages        []*Message
+	BroadcastHashes map[round.Number][]byte
+	// Sent and Resent are the messages we sent, and the number of resend requests answered,
+	// so that the restored handler can still answer resend requests, within the same bounds.
+	Sent   map[round.Number][]*Message
+	Resent map[party.ID]map[round.Number]int
+}
+
+// Snapshot saves the state of the protocol execution, encrypted with a key of SnapshotKeySize bytes.
+//
+// The snapshot contains the secret state of the current round, as well as the messages
+// received for this round and the following ones, so that RestoreMultiHandler can continue
+// the protocol with the same SSID, even from a different process. The messages sent so far
+// are saved as well, so that the restored handler can send them again with Resend.
+//
+// The messages returned by Listen() must all have been sent before calling this function,
+// since they are not part of the snapshot. It fails if the current round can't be saved,
+// as is the case for all the rounds of the signing protocols.
+func (h *MultiHandler) Snapshot(key []byte) ([]byte, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.done() {
+		return nil, errors.New("protocol: snapshot: protocol is already finished")
+	}
+	if len(h.out) > 0 {
+		return nil, errors.New("protocol: snapshot: outgoing messages haven't been sent")
+	}
+	r, ok := h.currentRound.(round.SessionMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("protocol: snapshot: round %d of %s can't be marshalled", h.currentRound.Number(), h.currentRound.ProtocolID())
+	}
+	data, err := r.MarshalSession()
+	if err != nil {
+		return nil, fmt.Errorf("protocol: snapshot: %w", err)
+	}
+
+	s := &snapshot{
+		Session:         data,
+		BroadcastHashes: h.broadcastHashes,
+		Sent:            h.sent,
+		Resent:          h.resent,
+	}
+	for number := round.Number(2); number <= r.FinalRoundNumber(); number++ {
+		for _, id := range r.PartyIDs() {
+			if msg := h.broadcast[number][id]; msg != nil {
+				s.Messages = append(s.Messages, msg)
+			}
+			if msg := h.messages[number][id]; msg != nil {
+				s.Messages = append(s.Messages, msg)
+			}
+		}
+	}
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	plaintext, err := enc.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("protocol: snapshot: %w", err)
+	}
+
+	aead, err := snapshotCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("protocol: snapshot: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(snapshotDomain)), nil
+}
+
+// RestoreMultiHandler decrypts a snapshot created by MultiHandler.Snapshot, and continues the
+// protocol from the round it was saved in.
+//
+// restore must be the RestoreFunc of the protocol which was running, and key the one used
+// to encrypt the snapshot. Messages for this round which were received after the snapshot
+// was taken need to be delivered again. opts are the same as for NewMultiHandler, and are
+// not part of the snapshot.
+func RestoreMultiHandler(restore RestoreFunc, data, key []byte, opts ...Option) (*MultiHandler, error) {
+	o := newOptions(opts)
+	aead, err := snapshotCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("protocol: restore: snapshot is too short")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(snapshotDomain))
+	if err != nil {
+		return nil, fmt.Errorf("protocol: restore: %w", err)
+	}
+	s := &snapshot{}
+	if err = cbor.Unmarshal(plaintext, s); err != nil {
+		return nil, fmt.Errorf("protocol: restore: %w", err)
+	}
+
+	r, err := restore(s.Session)
+	if err != nil {
+		return nil, fmt.Errorf("protocol: restore: %w", err)
+	}
+	h, err := newMultiHandler(r, o)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range s.Messages {
+		if msg == nil || msg.Protocol != r.ProtocolID() || !bytes.Equal(msg.SSID, r.SSID()) || !r.PartyIDs().Contains(msg.From) {
+			return nil, errors.New("protocol: restore: snapshot contains a message from another session")
+		}
+		h.store(msg)
+	}
+	for number, hash := range s.BroadcastHashes {
+		h.broadcastHashes[number] = hash
+	}
+	for number, messages := range s.Sent {
+		for _, msg := range messages {
+			if msg == nil || msg.From != r.SelfID() || msg.Protocol != r.ProtocolID() || !bytes.Equal(msg.SSID, r.SSID()) {
+				return nil, errors.New("protocol: restore: snapshot contains a message we didn't send")
+			}
+		}
+		h.sent[number] = messages
+	}
+	for id, counts := range s.Resent {
+		h.resent[id] = counts
+	}
+	h.start(o.ctx)
+	return h, nil
+}
+
+func snapshotCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != SnapshotKeySize {
+		return nil, fmt.Errorf("protocol: snapshot key must be %d bytes", SnapshotKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,236 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// TranscriptEntry is a message sent or received by a handler, as recorded by a Transcript.
+type TranscriptEntry struct {
+	// Sent is true if the message was returned by Listen(), and false if it was accepted by the handler.
+	Sent bool
+	// Message is the message, including its signature.
+	Message *Message
+	// Hash commits to the entry, to all the previous entries of the same round,
+	// and to the entries of the previous round recorded before this round started.
+	Hash []byte
+}
+
+// transcriptRound identifies the chain of entries of a round.
+type transcriptRound struct {
+	protocol string
+	ssid     string
+	number   round.Number
+}
+
+// Transcript is an append-only record of the messages sent and received by a handler,
+// in order to keep a verifiable trace of a ceremony.
+//
+// The entries of every round form a hash chain: the hash of an entry commits to the message and
+// to the hash of the previous entry of its round, so that an entry can't be removed, reordered or
+// modified without changing the hash of the last one, returned by Head. The chain of a round starts
+// from the head of the previous round, so that the head of the last round commits to the whole session.
+//
+// A Transcript is an Observer, and records the messages of the handlers it is given to with WithObserver,
+// possibly along with other observers with observe.Multi. Messages are recorded as they were transmitted:
+// point-to-point messages hold the secret shares of the protocols unless Identities.EncryptionKey is set,
+// so the transcript must then be stored as securely as the shares themselves.
+type Transcript struct {
+	mtx     sync.Mutex
+	w       io.Writer
+	err     error
+	entries []TranscriptEntry
+	heads   map[transcriptRound][]byte
+}
+
+// NewTranscript returns an empty Transcript, which also writes every entry to w as soon as it is recorded,
+// unless w is nil. The entries can be read back with ReadTranscript.
+func NewTranscript(w io.Writer) *Transcript {
+	return &Transcript{w: w, heads: map[transcriptRound][]byte{}}
+}
+
+// ReadTranscript reads the entries written by a Transcript, and checks their hash chains.
+//
+// The returned Transcript doesn't write its entries anywhere.
+func ReadTranscript(r io.Reader) (*Transcript, error) {
+	t := NewTranscript(nil)
+	dec := cbor.NewDecoder(r)
+	for i := 0; ; i++ {
+		var entry TranscriptEntry
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return t, nil
+			}
+			return nil, fmt.Errorf("protocol: transcript: entry %d: %w", i, err)
+		}
+		if entry.Message == nil {
+			return nil, fmt.Errorf("protocol: transcript: entry %d: missing message", i)
+		}
+		if recorded := t.record(entry.Sent, entry.Message); !bytes.Equal(recorded.Hash, entry.Hash) {
+			return nil, fmt.Errorf("protocol: transcript: entry %d: hash doesn't match the previous entries", i)
+		}
+	}
+}
+
+// record appends msg to the transcript, and returns its entry.
+func (t *Transcript) record(sent bool, msg *Message) TranscriptEntry {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	key := transcriptRound{protocol: msg.Protocol, ssid: string(msg.SSID), number: msg.RoundNumber}
+	previous, ok := t.heads[key]
+	if !ok {
+		// messages for a round may arrive before the previous one is over, so the chain of a round
+		// starts from the head of the previous round at that point.
+		previousRound := key
+		previousRound.number--
+		previous = hash.New(
+			hash.BytesWithDomain{TheDomain: "SSID", Bytes: msg.SSID},
+			hash.BytesWithDomain{TheDomain: "Protocol", Bytes: []byte(msg.Protocol)},
+			msg.RoundNumber,
+			hash.BytesWithDomain{TheDomain: "Previous Round", Bytes: t.heads[previousRound]},
+		).Sum()
+	}
+	var direction byte
+	if sent {
+		direction = 1
+	}
+	entry := TranscriptEntry{
+		Sent:    sent,
+		Message: msg,
+		Hash: hash.New(
+			hash.BytesWithDomain{TheDomain: "Previous", Bytes: previous},
+			hash.BytesWithDomain{TheDomain: "Sent", Bytes: []byte{direction}},
+			hash.BytesWithDomain{TheDomain: "Message", Bytes: msg.Hash()},
+			hash.BytesWithDomain{TheDomain: "Signature", Bytes: msg.Signature},
+		).Sum(),
+	}
+	t.heads[key] = entry.Hash
+	t.entries = append(t.entries, entry)
+
+	if t.w != nil && t.err == nil {
+		t.err = cbor.NewEncoder(t.w).Encode(entry)
+	}
+	return entry
+}
+
+// Entries returns the entries recorded so far, in order.
+func (t *Transcript) Entries() []TranscriptEntry {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return append([]TranscriptEntry(nil), t.entries...)
+}
+
+// Messages returns the messages recorded so far, in order, for instance to audit the protocol
+// with the functions provided by its package.
+func (t *Transcript) Messages() []*Message {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	messages := make([]*Message, 0, len(t.entries))
+	for _, entry := range t.entries {
+		messages = append(messages, entry.Message)
+	}
+	return messages
+}
+
+// Head returns the hash of the last entry of a round, which commits to all the messages of the round
+// and of the previous ones, or nil if none was recorded.
+func (t *Transcript) Head(protocolID string, ssid []byte, number round.Number) []byte {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.heads[transcriptRound{protocol: protocolID, ssid: string(ssid), number: number}]
+}
+
+// Err returns the first error which happened while writing the entries, after which they are only kept in memory.
+func (t *Transcript) Err() error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.err
+}
+
+// MessageReceived implements Observer.
+func (t *Transcript) MessageReceived(_ SessionInfo, msg *Message) { t.record(false, msg) }
+
+// MessageSent implements Observer.
+func (t *Transcript) MessageSent(_ SessionInfo, msg *Message) { t.record(true, msg) }
+
+// RoundStarted implements Observer.
+func (*Transcript) RoundStarted(SessionInfo, round.Number) {}
+
+// RoundFinished implements Observer.
+func (*Transcript) RoundFinished(SessionInfo, round.Number, time.Duration) {}
+
+// MessageRejected implements Observer.
+func (*Transcript) MessageRejected(SessionInfo, *Message, RejectReason) {}
+
+// MessageVerified implements Observer.
+func (*Transcript) MessageVerified(SessionInfo, *Message, time.Duration, error) {}
+
+// Aborted implements Observer.
+func (*Transcript) Aborted(SessionInfo, Error) {}
+
+// Finished implements Observer.
+func (*Transcript) Finished(SessionInfo, interface{}) {}
+
+// ReplayBroadcasts gives the broadcast messages of round r found in messages to r.StoreBroadcastMessage,
+// as a handler would, in order to audit a transcript without taking part in the protocol.
+// r must be a round.BroadcastRound.
+//
+// keys must contain the identity public key of every party of r. As with WithIdentities, messages which
+// aren't signed by the key of their sender are ignored, so that the transcript can't put words in the mouth
+// of a party. Every party of r must have sent exactly one broadcast message for the round: the returned Error
+// blames the parties whose message is missing, conflicting or invalid. Messages for other rounds or sessions are ignored.
+func ReplayBroadcasts(r round.Session, keys map[party.ID]IdentityPublicKey, messages []*Message) error {
+	b, ok := r.(round.BroadcastRound)
+	if !ok {
+		return fmt.Errorf("protocol: round %d of %s doesn't expect broadcast messages", r.Number(), r.ProtocolID())
+	}
+	for _, id := range r.PartyIDs() {
+		if keys[id] == nil {
+			return fmt.Errorf("protocol: missing identity of party %s", id)
+		}
+	}
+	byParty := make(map[party.ID]*Message, r.N())
+	for _, msg := range messages {
+		if msg == nil || !msg.Broadcast || msg.Type != MessageRound || msg.RoundNumber != r.Number() ||
+			msg.Protocol != r.ProtocolID() || !bytes.Equal(msg.SSID, r.SSID()) || !r.PartyIDs().Contains(msg.From) {
+			continue
+		}
+		if !keys[msg.From].Verify(msg.Hash(), msg.Signature) {
+			continue
+		}
+		if prev := byParty[msg.From]; prev != nil && !bytes.Equal(prev.Hash(), msg.Hash()) {
+			return Error{Culprits: []party.ID{msg.From}, Err: fmt.Errorf("round %d: conflicting broadcast messages", r.Number())}
+		}
+		byParty[msg.From] = msg
+	}
+
+	var missing []party.ID
+	for _, id := range r.PartyIDs() {
+		if byParty[id] == nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return Error{Culprits: missing, Err: fmt.Errorf("round %d: missing broadcast messages", r.Number())}
+	}
+	for _, id := range r.PartyIDs() {
+		content := b.BroadcastContent()
+		if err := cbor.Unmarshal(byParty[id].Data, content); err != nil {
+			return Error{Culprits: []party.ID{id}, Err: fmt.Errorf("round %d: failed to unmarshal: %w", r.Number(), err)}
+		}
+		if err := b.StoreBroadcastMessage(round.Message{From: id, Content: content, Broadcast: true}); err != nil {
+			return Error{Culprits: []party.ID{id}, Err: fmt.Errorf("round %d: %w", r.Number(), err)}
+		}
+	}
+	return nil
+}
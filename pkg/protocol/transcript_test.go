@@ -0,0 +1,102 @@
+package protocol_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/MixinNetwork/multi-party-sig/transport/simnet"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscript(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	self := partyIDs[0]
+	var record bytes.Buffer
+	transcript := protocol.NewTranscript(&record)
+
+	n := simnet.New(0)
+	var h0 *protocol.MultiHandler
+	for _, id := range partyIDs {
+		var observer protocol.Observer
+		if id == self {
+			observer = transcript
+		}
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil, protocol.WithObserver(observer))
+		require.NoError(t, err)
+		if id == self {
+			h0 = h
+		}
+		n.Add(id, h)
+	}
+	require.NoError(t, n.Run())
+	require.NoError(t, transcript.Err())
+
+	entries := transcript.Entries()
+	require.NotEmpty(t, entries)
+	senders := map[party.ID]bool{}
+	for _, entry := range entries {
+		assert.Equal(t, entry.Sent, entry.Message.From == self)
+		senders[entry.Message.From] = true
+	}
+	assert.Len(t, senders, len(partyIDs))
+	head := transcript.Head(h0.ProtocolID(), h0.SSID(), 2)
+	require.NotNil(t, head)
+	assert.Nil(t, transcript.Head(h0.ProtocolID(), []byte("other"), 2))
+
+	data := record.Bytes()
+	read, err := protocol.ReadTranscript(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, entries, read.Entries())
+	assert.Equal(t, head, read.Head(h0.ProtocolID(), h0.SSID(), 2))
+
+	// removing or modifying an entry breaks the chain of its round.
+	var rewritten bytes.Buffer
+	for i, entry := range entries {
+		if i == 1 {
+			continue
+		}
+		require.NoError(t, cbor.NewEncoder(&rewritten).Encode(entry))
+	}
+	_, err = protocol.ReadTranscript(&rewritten)
+	assert.Error(t, err)
+
+	rewritten.Reset()
+	for i, entry := range entries {
+		if i == len(entries)-1 {
+			msg := *entry.Message
+			msg.Data = append([]byte{}, msg.Data...)
+			msg.Data[0] ^= 1
+			entry.Message = &msg
+		}
+		require.NoError(t, cbor.NewEncoder(&rewritten).Encode(entry))
+	}
+	_, err = protocol.ReadTranscript(&rewritten)
+	assert.Error(t, err)
+
+	_, err = protocol.ReadTranscript(bytes.NewReader(data[:len(data)-1]))
+	assert.Error(t, err)
+
+	// the head of the last round also commits to the messages of the previous rounds,
+	// so a round can't be rewritten even with consistent hashes.
+	last := entries[len(entries)-1].Message.RoundNumber
+	recorded := protocol.NewTranscript(nil)
+	for i, entry := range entries {
+		if i == 1 {
+			continue
+		}
+		if entry.Sent {
+			recorded.MessageSent(protocol.SessionInfo{}, entry.Message)
+		} else {
+			recorded.MessageReceived(protocol.SessionInfo{}, entry.Message)
+		}
+	}
+	require.NotEqual(t, last, entries[1].Message.RoundNumber)
+	assert.NotEqual(t, transcript.Head(h0.ProtocolID(), h0.SSID(), last), recorded.Head(h0.ProtocolID(), h0.SSID(), last))
+}
@@ -0,0 +1,352 @@
+// Package pvss implements publicly verifiable encryption of scalars.
+//
+// A scalar s is encrypted to a public key D = d•G bit by bit, with exponential ElGamal:
+//
+//	Eₖ = rₖ•G
+//	Bₖ = bₖ•G + rₖ•D
+//
+// where bₖ is the k-th bit of s. Each ciphertext comes with a proof that it encrypts
+// either 0 or 1, and an aggregate proof that the bits add up to the discrete logarithm
+// of a public point S = s•G. Anybody knowing D and S can check that the ciphertext is a
+// correct encryption of s, without learning anything else about s.
+//
+// Since every bit is either 0 or 1, decryption only requires comparing bₖ•G with
+// the identity and the generator.
+package pvss
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/cronokirby/saferith"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// BitProof is a disjunctive Chaum-Pedersen proof that (E, B) encrypts either 0 or 1, i.e.
+// that (E, B - j•G) = r•(G, D) for j = 0 or j = 1.
+type BitProof struct {
+	C0, C1 curve.Scalar
+	Z0, Z1 curve.Scalar
+}
+
+// Ciphertext is the encryption of a scalar, together with a proof of correct encryption.
+//
+// When unmarshalling, EmptyCiphertext must be called first, to provide a group.
+type Ciphertext struct {
+	group curve.Curve
+	// E[k] = rₖ•G.
+	E []curve.Point
+	// B[k] = bₖ•G + rₖ•D.
+	B []curve.Point
+	// Bits[k] shows that (E[k], B[k]) encrypts either 0 or 1.
+	Bits []BitProof
+	// Challenge and Response show that ∑ₖ 2ᵏ•B[k] - S = R•D, with R•G = ∑ₖ 2ᵏ•E[k].
+	Challenge curve.Scalar
+	Response  curve.Scalar
+}
+
+type rawCiphertext struct {
+	E         []curve.Point
+	B         []curve.Point
+	Bits      []BitProof
+	Challenge curve.Scalar
+	Response  curve.Scalar
+}
+
+// bits returns the number of bits needed to encrypt a scalar of this group.
+func bits(group curve.Curve) int {
+	return group.Order().BitLen()
+}
+
+// EmptyCiphertext returns a new ciphertext with a given curve, ready to be unmarshalled.
+func EmptyCiphertext(group curve.Curve) *Ciphertext {
+	return &Ciphertext{group: group}
+}
+
+// Encrypt encrypts s to the public key D, and proves that it is the discrete logarithm of s•G.
+//
+// The proof is bound to the current state of h, which should identify the sender, the
+// recipient, and the session.
+func Encrypt(h *hash.Hash, D curve.Point, s curve.Scalar) (*Ciphertext, error) {
+	group := D.Curve()
+	if D.IsIdentity() {
+		return nil, errors.New("pvss: invalid public key")
+	}
+	n := bits(group)
+	b, err := decompose(s, n)
+	if err != nil {
+		return nil, err
+	}
+
+	G := group.NewBasePoint()
+	c := &Ciphertext{
+		group: group,
+		E:     make([]curve.Point, n),
+		B:     make([]curve.Point, n),
+		Bits:  make([]BitProof, n),
+	}
+	// R = ∑ₖ 2ᵏ•rₖ, computed from the most significant bit down.
+	R := group.NewScalar()
+	for k := n - 1; k >= 0; k-- {
+		r := sample.Scalar(rand.Reader, group)
+		c.E[k] = r.ActOnBase()
+		c.B[k] = r.Act(D)
+		if b[k] == 1 {
+			c.B[k] = c.B[k].Add(G)
+		}
+		c.Bits[k] = proveBit(h, k, D, c.E[k], c.B[k], b[k], r)
+		R.Add(group.NewScalar().Set(R)).Add(r)
+	}
+
+	E, B := c.sum()
+	a := sample.Scalar(rand.Reader, group)
+	c.Challenge = sumChallenge(h, n, D, E, B.Sub(s.ActOnBase()), a.ActOnBase(), a.Act(D))
+	c.Response = group.NewScalar().Set(c.Challenge).Mul(R).Add(a)
+	return c, nil
+}
+
+// Verify checks that c is an encryption of log_G(S) under the public key D.
+func (c *Ciphertext) Verify(h *hash.Hash, D, S curve.Point) bool {
+	if c == nil || c.Challenge == nil || c.Response == nil || D.IsIdentity() {
+		return false
+	}
+	group := D.Curve()
+	n := bits(group)
+	if len(c.E) != n || len(c.B) != n || len(c.Bits) != n {
+		return false
+	}
+	for k := 0; k < n; k++ {
+		if c.E[k] == nil || c.B[k] == nil || c.E[k].IsIdentity() {
+			return false
+		}
+		if !c.Bits[k].verify(h, k, D, c.E[k], c.B[k]) {
+			return false
+		}
+	}
+
+	// A₁ = z•G - e•E, A₂ = z•D - e•(B - S)
+	E, B := c.sum()
+	BS := B.Sub(S)
+	A1 := c.Response.ActOnBase().Sub(c.Challenge.Act(E))
+	A2 := c.Response.Act(D).Sub(c.Challenge.Act(BS))
+	return sumChallenge(h, n, D, E, BS, A1, A2).Equal(c.Challenge)
+}
+
+// Decrypt recovers the encrypted scalar with the private key d.
+//
+// The ciphertext should have been verified beforehand, otherwise decryption may fail.
+func (c *Ciphertext) Decrypt(d curve.Scalar) (curve.Scalar, error) {
+	group := d.Curve()
+	n := bits(group)
+	if len(c.E) != n || len(c.B) != n {
+		return nil, errors.New("pvss: invalid ciphertext length")
+	}
+	G := group.NewBasePoint()
+	b := make([]uint8, n)
+	for k := 0; k < n; k++ {
+		// bₖ•G = Bₖ - d•Eₖ
+		bG := c.B[k].Sub(d.Act(c.E[k]))
+		switch {
+		case bG.IsIdentity():
+		case bG.Equal(G):
+			b[k] = 1
+		default:
+			return nil, fmt.Errorf("pvss: failed to decrypt bit %d", k)
+		}
+	}
+	return compose(group, b)
+}
+
+// decompose returns the n bits of s, least significant first.
+//
+// Curves don't agree on the byte order of their scalar encoding, so both orders are tried,
+// and checked against s•G.
+func decompose(s curve.Scalar, n int) ([]uint8, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("pvss: %w", err)
+	}
+	S := s.ActOnBase()
+	for _, be := range [][]byte{data, reversed(data)} {
+		b := make([]uint8, 8*len(be))
+		for k := range b {
+			b[k] = (be[len(be)-1-k/8] >> (k % 8)) & 1
+		}
+		if len(b) < n || bytes.IndexByte(b[n:], 1) >= 0 {
+			continue
+		}
+		if bitsOnBase(s.Curve(), b[:n]).Equal(S) {
+			return b[:n], nil
+		}
+	}
+	return nil, errors.New("pvss: failed to decompose scalar")
+}
+
+// compose returns the scalar ∑ₖ 2ᵏ•b[k].
+func compose(group curve.Curve, b []uint8) (curve.Scalar, error) {
+	be := make([]byte, (len(b)+7)/8)
+	for k, bit := range b {
+		be[len(be)-1-k/8] |= bit << (k % 8)
+	}
+	// The sum may exceed the order of the group, so it is reduced first.
+	size := len(group.NewScalar().Bytes())
+	n := new(saferith.Nat).SetBytes(be)
+	be = n.Mod(n, group.Order()).FillBytes(make([]byte, size))
+
+	P := bitsOnBase(group, b)
+	for _, data := range [][]byte{be, reversed(be)} {
+		s := group.NewScalar()
+		if err := s.UnmarshalBinary(data); err != nil {
+			continue
+		}
+		if s.ActOnBase().Equal(P) {
+			return s, nil
+		}
+	}
+	return nil, errors.New("pvss: failed to compose scalar")
+}
+
+// bitsOnBase returns (∑ₖ 2ᵏ•b[k])•G.
+func bitsOnBase(group curve.Curve, b []uint8) curve.Point {
+	G := group.NewBasePoint()
+	P := group.NewPoint()
+	for k := len(b) - 1; k >= 0; k-- {
+		P = P.Add(P)
+		if b[k] == 1 {
+			P = P.Add(G)
+		}
+	}
+	return P
+}
+
+func reversed(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, v := range data {
+		out[len(data)-1-i] = v
+	}
+	return out
+}
+
+// sum returns (∑ₖ 2ᵏ•E[k], ∑ₖ 2ᵏ•B[k]).
+func (c *Ciphertext) sum() (curve.Point, curve.Point) {
+	E, B := c.group.NewPoint(), c.group.NewPoint()
+	for k := len(c.E) - 1; k >= 0; k-- {
+		E = E.Add(E).Add(c.E[k])
+		B = B.Add(B).Add(c.B[k])
+	}
+	return E, B
+}
+
+func proveBit(h *hash.Hash, k int, D, E, B curve.Point, b uint8, r curve.Scalar) BitProof {
+	group := D.Curve()
+	G := group.NewBasePoint()
+
+	// Simulate the branch for the other bit value.
+	cFake := sample.Scalar(rand.Reader, group)
+	zFake := sample.Scalar(rand.Reader, group)
+	BFake := B
+	if b == 0 {
+		BFake = B.Sub(G)
+	}
+	AFake := zFake.ActOnBase().Sub(cFake.Act(E))
+	AFakeD := zFake.Act(D).Sub(cFake.Act(BFake))
+
+	a := sample.Scalar(rand.Reader, group)
+	A, AD := a.ActOnBase(), a.Act(D)
+
+	var e curve.Scalar
+	if b == 0 {
+		e = bitChallenge(h, k, D, E, B, A, AD, AFake, AFakeD)
+	} else {
+		e = bitChallenge(h, k, D, E, B, AFake, AFakeD, A, AD)
+	}
+	cReal := e.Sub(cFake)
+	zReal := group.NewScalar().Set(cReal).Mul(r).Add(a)
+	if b == 0 {
+		return BitProof{C0: cReal, C1: cFake, Z0: zReal, Z1: zFake}
+	}
+	return BitProof{C0: cFake, C1: cReal, Z0: zFake, Z1: zReal}
+}
+
+func (p *BitProof) verify(h *hash.Hash, k int, D, E, B curve.Point) bool {
+	if p.C0 == nil || p.C1 == nil || p.Z0 == nil || p.Z1 == nil {
+		return false
+	}
+	B1 := B.Sub(D.Curve().NewBasePoint())
+	A0 := p.Z0.ActOnBase().Sub(p.C0.Act(E))
+	A0D := p.Z0.Act(D).Sub(p.C0.Act(B))
+	A1 := p.Z1.ActOnBase().Sub(p.C1.Act(E))
+	A1D := p.Z1.Act(D).Sub(p.C1.Act(B1))
+	e := bitChallenge(h, k, D, E, B, A0, A0D, A1, A1D)
+	return e.Equal(D.Curve().NewScalar().Set(p.C0).Add(p.C1))
+}
+
+func bitChallenge(h *hash.Hash, k int, D, E, B, A0, A0D, A1, A1D curve.Point) curve.Scalar {
+	index := make([]byte, 4)
+	binary.BigEndian.PutUint32(index, uint32(k))
+	fork := h.Fork(&hash.BytesWithDomain{TheDomain: "PVSS Bit", Bytes: index}, D, E, B, A0, A0D, A1, A1D)
+	return sample.Scalar(fork.Digest(), D.Curve())
+}
+
+func sumChallenge(h *hash.Hash, n int, D, E, BS, A1, A2 curve.Point) curve.Scalar {
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(n))
+	fork := h.Fork(&hash.BytesWithDomain{TheDomain: "PVSS Sum", Bytes: size}, D, E, BS, A1, A2)
+	return sample.Scalar(fork.Digest(), D.Curve())
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *Ciphertext) MarshalBinary() ([]byte, error) {
+	enc, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+	return enc.Marshal(rawCiphertext{
+		E:         c.E,
+		B:         c.B,
+		Bits:      c.Bits,
+		Challenge: c.Challenge,
+		Response:  c.Response,
+	})
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *Ciphertext) UnmarshalBinary(data []byte) error {
+	if c == nil || c.group == nil {
+		return errors.New("pvss: can't unmarshal Ciphertext with no group")
+	}
+	group := c.group
+	n := bits(group)
+	raw := rawCiphertext{
+		E:         make([]curve.Point, n),
+		B:         make([]curve.Point, n),
+		Bits:      make([]BitProof, n),
+		Challenge: group.NewScalar(),
+		Response:  group.NewScalar(),
+	}
+	for k := 0; k < n; k++ {
+		raw.E[k] = group.NewPoint()
+		raw.B[k] = group.NewPoint()
+		raw.Bits[k] = BitProof{
+			C0: group.NewScalar(),
+			C1: group.NewScalar(),
+			Z0: group.NewScalar(),
+			Z1: group.NewScalar(),
+		}
+	}
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw.E) != n || len(raw.B) != n || len(raw.Bits) != n {
+		return errors.New("pvss: invalid ciphertext length")
+	}
+	c.E, c.B, c.Bits = raw.E, raw.B, raw.Bits
+	c.Challenge, c.Response = raw.Challenge, raw.Response
+	return nil
+}
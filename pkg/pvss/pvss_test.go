@@ -0,0 +1,42 @@
+package pvss
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCiphertext(t *testing.T) {
+	for _, group := range []curve.Curve{curve.Secp256k1{}, curve.Edwards25519{}} {
+		d := sample.Scalar(rand.Reader, group)
+		D := d.ActOnBase()
+		s := sample.Scalar(rand.Reader, group)
+		S := s.ActOnBase()
+
+		c, err := Encrypt(hash.New(), D, s)
+		require.NoError(t, err)
+		assert.True(t, c.Verify(hash.New(), D, S), "%s: proof failed to verify", group.Name())
+		assert.False(t, c.Verify(hash.New(), D, S.Add(group.NewBasePoint())), "%s: proof verified for wrong point", group.Name())
+		assert.False(t, c.Verify(hash.New(&hash.BytesWithDomain{TheDomain: "Other", Bytes: []byte{1}}), D, S), "%s: proof verified for wrong hash", group.Name())
+		assert.False(t, c.Verify(hash.New(), S, S), "%s: proof verified for wrong key", group.Name())
+
+		decrypted, err := c.Decrypt(d)
+		require.NoError(t, err)
+		assert.True(t, decrypted.Equal(s), "%s: wrong decryption", group.Name())
+
+		data, err := cbor.Marshal(c)
+		require.NoError(t, err)
+		c2 := EmptyCiphertext(group)
+		require.NoError(t, cbor.Unmarshal(data, c2))
+		assert.True(t, c2.Verify(hash.New(), D, S), "%s: unmarshalled proof failed to verify", group.Name())
+
+		c2.B[0], c2.B[1] = c2.B[1], c2.B[0]
+		assert.False(t, c2.Verify(hash.New(), D, S), "%s: proof verified with swapped bits", group.Name())
+	}
+}
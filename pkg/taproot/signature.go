@@ -200,3 +200,103 @@ func (pk PublicKey) Verify(sig Signature, m []byte) bool {
 	}
 	return bytes.Equal(check.XScalar().Bytes(), sig[:32])
 }
+
+// AdaptorSignatureLen is the number of bytes in an AdaptorSignature.
+const AdaptorSignatureLen = 65
+
+// AdaptorSignature represents a BIP-340 pre-signature, encrypted under an adaptor point T.
+//
+// This consists of the compressed adapted nonce R' = R + T, followed by the 32 byte
+// response s'. We keep the full point instead of only its x coordinate, because
+// the parity of R' decides whether the adaptor secret t needs to be added to, or
+// subtracted from s' in order to complete the signature.
+type AdaptorSignature []byte
+
+// adaptorParts parses the adapted nonce and the response out of an AdaptorSignature.
+func (sig AdaptorSignature) adaptorParts() (*curve.Secp256k1Point, *curve.Secp256k1Scalar, error) {
+	if len(sig) != AdaptorSignatureLen {
+		return nil, nil, fmt.Errorf("invalid adaptor signature length %d", len(sig))
+	}
+	R := new(curve.Secp256k1Point)
+	if err := R.UnmarshalBinary(sig[:33]); err != nil {
+		return nil, nil, err
+	}
+	s := new(curve.Secp256k1Scalar)
+	if err := s.UnmarshalBinary(sig[33:]); err != nil {
+		return nil, nil, err
+	}
+	return R, s, nil
+}
+
+// VerifyAdaptor checks the integrity of a pre-signature for the adaptor point T, using a public key.
+//
+// A successful verification guarantees that completing the pre-signature with the
+// discrete logarithm of T yields a valid Signature for m.
+//
+// Note that m is the hash of a message, and not the message itself.
+func (pk PublicKey) VerifyAdaptor(sig AdaptorSignature, T curve.Point, m []byte) bool {
+	P, err := curve.Secp256k1{}.LiftX(pk)
+	if err != nil {
+		return false
+	}
+	R, s, err := sig.adaptorParts()
+	if err != nil {
+		return false
+	}
+	eHash := TaggedHash("BIP0340/challenge", sig[1:33], pk, m)
+	e := new(curve.Secp256k1Scalar)
+	_ = e.UnmarshalBinary(eHash)
+
+	// The signers negated their nonces if R' had an odd y coordinate.
+	expected := R.Sub(T)
+	if !R.HasEvenY() {
+		expected = expected.Negate()
+	}
+	actual := s.ActOnBase().Sub(e.Act(P))
+	return actual.Equal(expected)
+}
+
+// Complete uses the adaptor secret t to turn the pre-signature into a valid Signature.
+func (sig AdaptorSignature) Complete(t curve.Scalar) (Signature, error) {
+	R, s, err := sig.adaptorParts()
+	if err != nil {
+		return nil, err
+	}
+	if R.HasEvenY() {
+		s.Add(t)
+	} else {
+		s.Sub(t)
+	}
+	sBytes, _ := s.MarshalBinary()
+
+	out := make([]byte, 0, SignatureLen)
+	out = append(out, sig[1:33]...)
+	out = append(out, sBytes...)
+	return Signature(out), nil
+}
+
+// Extract recovers the adaptor secret t from a Signature completed from this pre-signature.
+//
+// An error is returned if the signature doesn't match the pre-signature, or if
+// the recovered secret doesn't correspond to the adaptor point T.
+func (sig AdaptorSignature) Extract(completed Signature, T curve.Point) (curve.Scalar, error) {
+	R, s, err := sig.adaptorParts()
+	if err != nil {
+		return nil, err
+	}
+	if len(completed) != SignatureLen || !bytes.Equal(completed[:32], sig[1:33]) {
+		return nil, fmt.Errorf("signature doesn't match adaptor signature")
+	}
+	t := new(curve.Secp256k1Scalar)
+	if err := t.UnmarshalBinary(completed[32:]); err != nil {
+		return nil, err
+	}
+	t.Sub(s)
+	if !R.HasEvenY() {
+		t.Negate()
+	}
+	if !t.ActOnBase().Equal(T) {
+		return nil, fmt.Errorf("extracted secret doesn't match adaptor point")
+	}
+	return t, nil
+}
@@ -2,11 +2,13 @@ package zksch
 
 import (
 	"crypto/rand"
+	"errors"
 	"io"
 
 	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/fxamacker/cbor/v2"
 )
 
 // Randomness = a ← ℤₚ.
@@ -43,6 +45,39 @@ func NewProof(hash *hash.Hash, public curve.Point, private curve.Scalar, gen cur
 	}
 }
 
+// EmptyRandomness creates an empty Randomness with a fixed group, ready to be unmarshalled.
+func EmptyRandomness(group curve.Curve) *Randomness {
+	return &Randomness{
+		a:          group.NewScalar(),
+		commitment: Commitment{C: group.NewPoint()},
+	}
+}
+
+type rawRandomness struct {
+	A curve.Scalar
+	C curve.Point
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// Since the randomness is secret, this should only be used to save the state of a protocol.
+func (r *Randomness) MarshalBinary() ([]byte, error) {
+	return cbor.Marshal(&rawRandomness{A: r.a, C: r.commitment.C})
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (r *Randomness) UnmarshalBinary(data []byte) error {
+	if r.a == nil || r.commitment.C == nil {
+		return errors.New("zksch.Randomness: must be initialized using EmptyRandomness")
+	}
+	raw := &rawRandomness{A: r.a, C: r.commitment.C}
+	if err := cbor.Unmarshal(data, raw); err != nil {
+		return err
+	}
+	r.a, r.commitment.C = raw.A, raw.C
+	return nil
+}
+
 // NewRandomness creates a new a ∈ ℤₚ and the corresponding commitment C = a•G.
 // This can be used to run the proof in a non-interactive way.
 func NewRandomness(rand io.Reader, group curve.Curve, gen curve.Point) *Randomness {
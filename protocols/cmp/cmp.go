@@ -31,7 +31,11 @@ func EmptyConfig(group curve.Curve) *Config {
 // For better performance, a `pool.Pool` can be provided in order to parallelize certain steps of the protocol.
 // Returns *cmp.Config if successful.
 func Keygen(group curve.Curve, selfID party.ID, participants []party.ID, threshold int, pl *pool.Pool) protocol.StartFunc {
-	info := round.Info{
+	return keygen.Start(keygenInfo(group, selfID, participants, threshold), pl)
+}
+
+func keygenInfo(group curve.Curve, selfID party.ID, participants []party.ID, threshold int) round.Info {
+	return round.Info{
 		ProtocolID:       "cmp/keygen-threshold",
 		FinalRoundNumber: keygen.Rounds,
 		SelfID:           selfID,
@@ -39,7 +43,26 @@ func Keygen(group curve.Curve, selfID party.ID, participants []party.ID, thresho
 		Threshold:        threshold,
 		Group:            group,
 	}
-	return keygen.Start(info, pl)
+}
+
+// Refresh creates new shares of the key generated by `Keygen`, as well as new auxiliary parameters,
+// among the same participants. The public key, the chain key and the threshold stay the same,
+// but the previous shares can't be combined with the new ones, and should be deleted once the protocol succeeds.
+//
+// Returns *cmp.Config if successful.
+func Refresh(config *Config, pl *pool.Pool) protocol.StartFunc {
+	info := keygenInfo(config.Group, config.ID, config.PartyIDs(), config.Threshold)
+	info.ProtocolID = "cmp/refresh-threshold"
+	return keygen.StartRefresh(info, config, pl)
+}
+
+// AuditKeygen checks the transcript of a `Keygen` execution with the given sessionID, without taking part in it.
+//
+// messages should contain the broadcast messages of all participants, such as the messages of a
+// protocol.Transcript recorded by any of them, and keys the identity public keys which signed them.
+// Returns the public part of the *cmp.Config, without any secret, if successful.
+func AuditKeygen(group curve.Curve, participants []party.ID, threshold int, sessionID []byte, keys map[party.ID]protocol.IdentityPublicKey, messages []*protocol.Message, pl *pool.Pool) (*Config, error) {
+	return keygen.Audit(keygenInfo(group, "", participants, threshold), sessionID, keys, messages, pl)
 }
 
 // Sign generates an ECDSA signature for `messageHash` among the given `signers`.
@@ -47,3 +70,29 @@ func Keygen(group curve.Curve, selfID party.ID, participants []party.ID, thresho
 func Sign(config *Config, signers []party.ID, messageHash []byte, pl *pool.Pool) protocol.StartFunc {
 	return sign.StartSign(config, signers, messageHash, pl)
 }
+
+// SignAdaptor generates an ECDSA pre-signature for `messageHash` among the given `signers`,
+// encrypted under the adaptor point Y.
+//
+// The pre-signature can be decrypted into a valid signature with the discrete logarithm y of Y,
+// which can in turn be recovered from the pre-signature and the decrypted signature.
+// Returns *ecdsa.AdaptorSignature if successful.
+func SignAdaptor(config *Config, signers []party.ID, messageHash []byte, Y curve.Point, pl *pool.Pool) protocol.StartFunc {
+	return sign.StartSignAdaptor(config, signers, messageHash, Y, pl)
+}
+
+// AuditSign checks the transcript of a `Sign` execution with the given sessionID, or of a `SignAdaptor`
+// execution if Y isn't nil, without taking part in it.
+//
+// config only needs to contain the public data of the parties, as returned by AuditKeygen,
+// and keys the identity public keys of the signers.
+// Returns *ecdsa.Signature or *ecdsa.AdaptorSignature if successful.
+func AuditSign(config *Config, signers []party.ID, messageHash []byte, Y curve.Point, sessionID []byte, keys map[party.ID]protocol.IdentityPublicKey, messages []*protocol.Message, pl *pool.Pool) (interface{}, error) {
+	return sign.Audit(config, signers, messageHash, Y, sessionID, keys, messages, pl)
+}
+
+// RestoreKeygen returns a protocol.RestoreFunc for protocol.RestoreMultiHandler,
+// resuming a `Keygen` execution from a snapshot.
+func RestoreKeygen(pl *pool.Pool) protocol.RestoreFunc {
+	return keygen.Restore(pl)
+}
@@ -1,6 +1,8 @@
 package cmp
 
 import (
+	"bytes"
+	"crypto/ed25519"
 	"crypto/rand"
 	"math"
 	"sync"
@@ -14,6 +16,7 @@ import (
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
 	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
 	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/transport/simnet"
 )
 
 func do(t *testing.T, id party.ID, ids []party.ID, threshold int, message []byte, pl *pool.Pool, n *test.Network, wg *sync.WaitGroup) {
@@ -56,6 +59,46 @@ func TestCMP(t *testing.T) {
 	wg.Wait()
 }
 
+func TestRestore(t *testing.T) {
+	N := 3
+	T := N - 1
+	message := []byte("hello")
+
+	partyIDs := test.PartyIDs(N)
+
+	n := test.NewNetwork(partyIDs)
+
+	var wg sync.WaitGroup
+	wg.Add(N)
+	for _, id := range partyIDs {
+		pl := pool.NewPool(3)
+		defer pl.TearDown()
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(Keygen(curve.Secp256k1{}, id, partyIDs, T, pl), nil)
+			require.NoError(t, err)
+			h, err = test.RestoringHandlerLoop(id, h, n, RestoreKeygen(pl))
+			require.NoError(t, err)
+			r, err := h.Result()
+			require.NoError(t, err)
+			require.IsType(t, &Config{}, r)
+			c := r.(*Config)
+
+			// once the first round is done, the signing round holds nonces, and can't be saved.
+			h, err = protocol.NewMultiHandler(Sign(c, partyIDs, message, pl), nil)
+			require.NoError(t, err)
+			_, err = h.Snapshot(make([]byte, protocol.SnapshotKeySize))
+			require.Error(t, err)
+			test.HandlerLoop(id, h, n)
+			signResult, err := h.Result()
+			require.NoError(t, err)
+			require.IsType(t, &ecdsa.Signature{}, signResult)
+			assert.True(t, signResult.(*ecdsa.Signature).Verify(c.PublicPoint(), message))
+		}(id)
+	}
+	wg.Wait()
+}
+
 func TestStart(t *testing.T) {
 	group := curve.Secp256k1{}
 	N := 6
@@ -127,3 +170,133 @@ func TestStart(t *testing.T) {
 		})
 	}
 }
+
+// run executes a protocol over a simulated network, and records the messages of the first party in transcript.
+// If ids isn't nil, the handlers authenticate their messages with the identities it contains.
+func run(t *testing.T, partyIDs party.IDSlice, start func(id party.ID) protocol.StartFunc, transcript *protocol.Transcript, ids map[party.ID]*protocol.Identities) map[party.ID]interface{} {
+	n := simnet.New(0)
+	handlers := make(map[party.ID]*protocol.MultiHandler, len(partyIDs))
+	for i, id := range partyIDs {
+		var observer protocol.Observer
+		if i == 0 {
+			observer = transcript
+		}
+		opts := []protocol.Option{protocol.WithObserver(observer)}
+		if ids != nil {
+			opts = append(opts, protocol.WithIdentities(ids[id]))
+		}
+		h, err := protocol.NewMultiHandler(start(id), nil, opts...)
+		require.NoError(t, err)
+		handlers[id] = h
+		n.Add(id, h)
+	}
+	require.NoError(t, n.Run())
+	results := make(map[party.ID]interface{}, len(partyIDs))
+	for id, h := range handlers {
+		result, err := h.Result()
+		require.NoError(t, err)
+		results[id] = result
+	}
+	return results
+}
+
+// identities generates an identity key for each party.
+func identities(t *testing.T, partyIDs []party.ID) map[party.ID]*protocol.Identities {
+	keys := make(map[party.ID]protocol.IdentityKey, len(partyIDs))
+	public := make(map[party.ID]protocol.IdentityPublicKey, len(partyIDs))
+	for _, id := range partyIDs {
+		_, sk, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[id] = protocol.Ed25519Identity(sk)
+		public[id] = keys[id].Public()
+	}
+	ids := make(map[party.ID]*protocol.Identities, len(partyIDs))
+	for _, id := range partyIDs {
+		ids[id] = &protocol.Identities{Key: keys[id], Keys: public}
+	}
+	return ids
+}
+
+func TestAudit(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+	threshold := 1
+	signers := partyIDs[1:]
+	message := []byte("hello")
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	ids := identities(t, partyIDs)
+	keys := ids[partyIDs[0]].Keys
+
+	var record bytes.Buffer
+	configs := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return Keygen(group, id, partyIDs, threshold, pl)
+	}, protocol.NewTranscript(&record), ids)
+	transcript, err := protocol.ReadTranscript(&record)
+	require.NoError(t, err)
+	keygenMessages := transcript.Messages()
+	public, err := AuditKeygen(group, partyIDs, threshold, nil, keys, keygenMessages, pl)
+	require.NoError(t, err)
+	assert.Nil(t, public.ECDSA)
+	for id, c := range configs {
+		config := c.(*Config)
+		assert.True(t, public.PublicPoint().Equal(config.PublicPoint()))
+		assert.Equal(t, public.RID, config.RID)
+		assert.True(t, public.Public[id].ECDSA.Equal(config.ECDSA.ActOnBase()))
+	}
+
+	transcript = protocol.NewTranscript(nil)
+	signatures := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return Sign(configs[id].(*Config), signers, message, pl)
+	}, transcript, ids)
+	result, err := AuditSign(public, signers, message, nil, nil, keys, transcript.Messages(), pl)
+	require.NoError(t, err)
+	require.IsType(t, &ecdsa.Signature{}, result)
+	signature := result.(*ecdsa.Signature)
+	assert.True(t, signature.Verify(public.PublicPoint(), message))
+	assert.True(t, signature.R.Equal(signatures[signers[0]].(*ecdsa.Signature).R))
+
+	// a signer whose broadcast is missing is blamed.
+	var messages []*protocol.Message
+	for _, msg := range transcript.Messages() {
+		if !(msg.Broadcast && msg.RoundNumber == 5 && msg.From == signers[1]) {
+			messages = append(messages, msg)
+		}
+	}
+	_, err = AuditSign(public, signers, message, nil, nil, keys, messages, pl)
+	require.Error(t, err)
+	require.IsType(t, protocol.Error{}, err)
+	assert.Equal(t, []party.ID{signers[1]}, err.(protocol.Error).Culprits)
+
+	// the messages of another session are ignored.
+	_, err = AuditKeygen(group, partyIDs, threshold, []byte("other"), keys, keygenMessages, pl)
+	assert.Error(t, err)
+}
+
+func TestRefresh(t *testing.T) {
+	group := curve.Secp256k1{}
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	configs, partyIDs := test.GenerateConfig(group, 3, 1, rand.Reader, pl)
+	signers := partyIDs[1:]
+	message := []byte("hello")
+
+	refreshed := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return Refresh(configs[id], pl)
+	}, protocol.NewTranscript(nil), nil)
+	for id, r := range refreshed {
+		require.IsType(t, &Config{}, r)
+		previous, config := configs[id], r.(*Config)
+		assert.True(t, previous.PublicPoint().Equal(config.PublicPoint()))
+		assert.Equal(t, previous.ChainKey, config.ChainKey)
+		assert.False(t, previous.ECDSA.Equal(config.ECDSA))
+		assert.True(t, config.ECDSA.ActOnBase().Equal(config.Public[id].ECDSA))
+	}
+
+	signatures := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return Sign(refreshed[id].(*Config), signers, message, pl)
+	}, protocol.NewTranscript(nil), nil)
+	for _, s := range signatures {
+		assert.True(t, s.(*ecdsa.Signature).Verify(configs[signers[0]].PublicPoint(), message))
+	}
+}
@@ -0,0 +1,101 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/paillier"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	zksch "github.com/MixinNetwork/multi-party-sig/pkg/zk/sch"
+	"github.com/MixinNetwork/multi-party-sig/protocols/cmp/config"
+	"github.com/cronokirby/saferith"
+)
+
+// Audit checks the transcript of a key generation started with Start, and returns the public part of the Config it produced.
+//
+// info is the info given to Start, whose SelfID is ignored, and messages should contain the broadcast messages of all
+// participants, such as the messages of a protocol.Transcript recorded by any of them. The commitments, the VSS polynomials,
+// the Paillier and Pedersen parameters with their zkmod, zkprm and zkfac proofs, and the final Schnorr proofs of the new
+// shares are checked as the participants did. The encrypted shares can only be checked by their recipients.
+//
+// The returned Config has no ID nor secrets, and can't sign, but contains the public key and the public data of every
+// party, as needed by sign.Audit.
+//
+// keys contains the identity public key of every one of the participants, as given to protocol.WithIdentities:
+// only the messages they signed are taken into account.
+func Audit(info round.Info, sessionID []byte, keys map[party.ID]protocol.IdentityPublicKey, messages []*protocol.Message, pl *pool.Pool) (*config.Config, error) {
+	if len(info.PartyIDs) == 0 {
+		return nil, errors.New("keygen.Audit: no participants")
+	}
+	// The auditor isn't a participant, so we impersonate one, without using its secrets.
+	info.SelfID = info.PartyIDs[0]
+	session, err := Start(info, pl)(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("keygen.Audit: %w", err)
+	}
+	r1 := session.(*round1)
+	n := r1.N()
+
+	r2 := &round2{
+		round1:         r1,
+		VSSPolynomials: make(map[party.ID]*polynomial.Exponent, n),
+		Commitments:    make(map[party.ID]hash.Commitment, n),
+		RIDs:           make(map[party.ID]types.RID, n),
+		ChainKeys:      make(map[party.ID]types.RID, n),
+		ShareReceived:  make(map[party.ID]curve.Scalar, n),
+		ElGamalPublic:  make(map[party.ID]curve.Point, n),
+		PaillierPublic: make(map[party.ID]*paillier.PublicKey, n),
+		NModulus:       make(map[party.ID]*saferith.Modulus, n),
+		S:              make(map[party.ID]*saferith.Nat, n),
+		T:              make(map[party.ID]*saferith.Nat, n),
+	}
+	if err = protocol.ReplayBroadcasts(r2, keys, messages); err != nil {
+		return nil, err
+	}
+	r3 := &round3{
+		round2:             r2,
+		SchnorrCommitments: make(map[party.ID]*zksch.Commitment, n),
+	}
+	if err = protocol.ReplayBroadcasts(r3, keys, messages); err != nil {
+		return nil, err
+	}
+
+	rid, chainKey := r3.agreedRandomness()
+	r3.UpdateHashState(rid)
+	r4 := &round4{
+		round3:   r3,
+		RID:      rid,
+		ChainKey: chainKey,
+	}
+	if err = protocol.ReplayBroadcasts(r4, keys, messages); err != nil {
+		return nil, err
+	}
+
+	public, err := r4.publicData()
+	if err != nil {
+		return nil, fmt.Errorf("keygen.Audit: %w", err)
+	}
+	c := &config.Config{
+		Group:     r4.Group(),
+		Threshold: r4.Threshold(),
+		RID:       rid.Copy(),
+		ChainKey:  chainKey.Copy(),
+		Public:    public,
+	}
+	r4.UpdateHashState(c)
+	r5 := &round5{
+		round4:        r4,
+		UpdatedConfig: c,
+	}
+	if err = protocol.ReplayBroadcasts(r5, keys, messages); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -2,13 +2,17 @@ package keygen
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
 	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
 	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/cmp/config"
 )
 
 const Rounds round.Number = 5
@@ -32,3 +36,41 @@ func Start(info round.Info, pl *pool.Pool) protocol.StartFunc {
 
 	}
 }
+
+// StartRefresh creates new shares of the key in c, as well as new Paillier and Pedersen parameters,
+// among the parties of c and with the same threshold, which must match info.
+//
+// The public key and the chain key don't change, while the previous shares can't be combined with the new ones.
+func StartRefresh(info round.Info, c *config.Config, pl *pool.Pool) protocol.StartFunc {
+	return func(sessionID []byte) (_ round.Session, err error) {
+		if c == nil || c.ECDSA == nil || c.Public == nil {
+			return nil, errors.New("keygen: invalid config")
+		}
+		if info.SelfID != c.ID || info.Threshold != c.Threshold || len(info.PartyIDs) != len(c.Public) || !c.PartyIDs().Contains(info.PartyIDs...) {
+			return nil, errors.New("keygen: session doesn't match config")
+		}
+		if public, ok := c.Public[c.ID]; !ok || !c.ECDSA.ActOnBase().Equal(public.ECDSA) {
+			return nil, errors.New("keygen: secret share doesn't match its public share")
+		}
+		helper, err := round.NewSession(info, sessionID, pl, c)
+		if err != nil {
+			return nil, fmt.Errorf("keygen: %w", err)
+		}
+
+		group := helper.Group()
+
+		PublicSharesECDSA := make(map[party.ID]curve.Point, len(c.Public))
+		for id, public := range c.Public {
+			PublicSharesECDSA[id] = public.ECDSA
+		}
+		// sample fᵢ(X) deg(fᵢ) = t, fᵢ(0) = 0
+		VSSSecret := polynomial.NewPolynomial(group, helper.Threshold(), group.NewScalar())
+		return &round1{
+			Helper:                    helper,
+			PreviousSecretECDSA:       c.ECDSA,
+			PreviousPublicSharesECDSA: PublicSharesECDSA,
+			PreviousChainKey:          c.ChainKey,
+			VSSSecret:                 VSSSecret,
+		}, nil
+	}
+}
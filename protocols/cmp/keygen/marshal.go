@@ -0,0 +1,305 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/paillier"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	zksch "github.com/MixinNetwork/multi-party-sig/pkg/zk/sch"
+	"github.com/MixinNetwork/multi-party-sig/protocols/cmp/config"
+	"github.com/cronokirby/saferith"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// These assert that our rounds can be saved and restored.
+var (
+	_ round.SessionMarshaler = (*round1)(nil)
+	_ round.SessionMarshaler = (*round2)(nil)
+	_ round.SessionMarshaler = (*round3)(nil)
+	_ round.SessionMarshaler = (*round4)(nil)
+	_ round.SessionMarshaler = (*round5)(nil)
+)
+
+// sessionMarshal contains the state of every round up to Number.
+type sessionMarshal struct {
+	Helper *round.Helper
+	Number round.Number
+
+	// round1
+	PreviousSecretECDSA       curve.Scalar
+	PreviousPublicSharesECDSA *party.PointMap
+	PreviousChainKey          types.RID
+	VSSSecret                 *polynomial.Polynomial
+
+	// round2
+	VSSPolynomials map[party.ID][]byte
+	Commitments    map[party.ID]hash.Commitment
+	RIDs           map[party.ID]types.RID
+	ChainKeys      map[party.ID]types.RID
+	ShareReceived  *party.ScalarMap
+	ElGamalPublic  *party.PointMap
+	NModulus       map[party.ID]*saferith.Modulus
+	S, T           map[party.ID]*saferith.Nat
+	ElGamalSecret  curve.Scalar
+	P, Q           *saferith.Nat
+	PedersenSecret *saferith.Nat
+	SchnorrRand    *zksch.Randomness
+	Decommitment   hash.Decommitment
+
+	// round3
+	SchnorrCommitments *party.PointMap
+
+	// round4
+	RID      types.RID
+	ChainKey types.RID
+
+	// round5
+	UpdatedConfig *config.Config
+}
+
+func (r *round1) save(s *sessionMarshal) error {
+	s.Helper = r.Helper
+	s.PreviousSecretECDSA = r.PreviousSecretECDSA
+	if r.PreviousPublicSharesECDSA != nil {
+		s.PreviousPublicSharesECDSA = party.NewPointMap(r.PreviousPublicSharesECDSA)
+	}
+	s.PreviousChainKey = r.PreviousChainKey
+	s.VSSSecret = r.VSSSecret
+	return nil
+}
+
+func (r *round2) save(s *sessionMarshal) error {
+	if err := r.round1.save(s); err != nil {
+		return err
+	}
+	s.VSSPolynomials = make(map[party.ID][]byte, len(r.VSSPolynomials))
+	for id, F := range r.VSSPolynomials {
+		data, err := F.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		s.VSSPolynomials[id] = data
+	}
+	s.Commitments = r.Commitments
+	s.RIDs = r.RIDs
+	s.ChainKeys = r.ChainKeys
+	s.ShareReceived = party.NewScalarMap(r.ShareReceived)
+	s.ElGamalPublic = party.NewPointMap(r.ElGamalPublic)
+	s.NModulus = r.NModulus
+	s.S, s.T = r.S, r.T
+	s.ElGamalSecret = r.ElGamalSecret
+	s.P, s.Q = r.PaillierSecret.P(), r.PaillierSecret.Q()
+	s.PedersenSecret = r.PedersenSecret
+	s.SchnorrRand = r.SchnorrRand
+	s.Decommitment = r.Decommitment
+	return nil
+}
+
+func (r *round3) save(s *sessionMarshal) error {
+	if err := r.round2.save(s); err != nil {
+		return err
+	}
+	commitments := make(map[party.ID]curve.Point, len(r.SchnorrCommitments))
+	for id, A := range r.SchnorrCommitments {
+		commitments[id] = A.C
+	}
+	s.SchnorrCommitments = party.NewPointMap(commitments)
+	return nil
+}
+
+func (r *round4) save(s *sessionMarshal) error {
+	if err := r.round3.save(s); err != nil {
+		return err
+	}
+	s.RID = r.RID
+	s.ChainKey = r.ChainKey
+	return nil
+}
+
+func (r *round5) save(s *sessionMarshal) error {
+	if err := r.round4.save(s); err != nil {
+		return err
+	}
+	s.UpdatedConfig = r.UpdatedConfig
+	return nil
+}
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round1) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round2) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round3) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round4) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round5) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+func marshalSession(r interface {
+	round.Session
+	save(*sessionMarshal) error
+}) ([]byte, error) {
+	s := &sessionMarshal{Number: r.Number()}
+	if err := r.save(s); err != nil {
+		return nil, fmt.Errorf("keygen: %w", err)
+	}
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	return enc.Marshal(s)
+}
+
+// Restore returns a protocol.RestoreFunc recreating a round saved with MarshalSession.
+//
+// As in Start, a pool.Pool can be provided in order to parallelize certain steps of the protocol.
+func Restore(pl *pool.Pool) protocol.RestoreFunc {
+	return func(data []byte) (round.Session, error) {
+		session, err := restore(data, pl)
+		if err != nil {
+			return nil, fmt.Errorf("keygen.Restore: %w", err)
+		}
+		return session, nil
+	}
+}
+
+func restore(data []byte, pl *pool.Pool) (round.Session, error) {
+	// The group is only known once the Helper has been unmarshalled.
+	raw := &struct{ Helper *round.Helper }{}
+	if err := cbor.Unmarshal(data, raw); err != nil {
+		return nil, err
+	}
+	if raw.Helper == nil || raw.Helper.Group() == nil {
+		return nil, errors.New("missing session")
+	}
+	group := raw.Helper.Group()
+	s := &sessionMarshal{
+		PreviousSecretECDSA:       group.NewScalar(),
+		PreviousPublicSharesECDSA: party.EmptyPointMap(group),
+		VSSSecret:                 polynomial.EmptyPolynomial(group),
+		ShareReceived:             party.EmptyScalarMap(group),
+		ElGamalPublic:             party.EmptyPointMap(group),
+		ElGamalSecret:             group.NewScalar(),
+		SchnorrRand:               zksch.EmptyRandomness(group),
+		SchnorrCommitments:        party.EmptyPointMap(group),
+		UpdatedConfig:             config.EmptyConfig(group),
+	}
+	if err := cbor.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Helper.FinalRoundNumber() != Rounds {
+		return nil, fmt.Errorf("unexpected protocol %s", s.Helper.ProtocolID())
+	}
+	if s.Number < 1 || s.Number > Rounds {
+		return nil, fmt.Errorf("invalid round %d", s.Number)
+	}
+	if s.VSSSecret == nil {
+		return nil, round.ErrNilFields
+	}
+	s.Helper.Pool = pl
+
+	r1 := &round1{
+		Helper:    s.Helper,
+		VSSSecret: s.VSSSecret,
+	}
+	// Missing optional fields keep their initial value when unmarshalling, so we only keep them when refreshing.
+	if s.PreviousChainKey != nil {
+		if s.PreviousPublicSharesECDSA == nil || s.PreviousSecretECDSA.IsZero() {
+			return nil, round.ErrNilFields
+		}
+		r1.PreviousSecretECDSA = s.PreviousSecretECDSA
+		r1.PreviousPublicSharesECDSA = s.PreviousPublicSharesECDSA.Points
+		r1.PreviousChainKey = s.PreviousChainKey
+	}
+	if s.Number == 1 {
+		return r1, nil
+	}
+
+	if s.ShareReceived == nil || s.ElGamalPublic == nil || s.SchnorrRand == nil || s.PedersenSecret == nil {
+		return nil, round.ErrNilFields
+	}
+	if err := paillier.ValidatePrime(s.P); err != nil {
+		return nil, fmt.Errorf("prime P: %w", err)
+	}
+	if err := paillier.ValidatePrime(s.Q); err != nil {
+		return nil, fmt.Errorf("prime Q: %w", err)
+	}
+	r2 := &round2{
+		round1:         r1,
+		VSSPolynomials: make(map[party.ID]*polynomial.Exponent, len(s.VSSPolynomials)),
+		Commitments:    s.Commitments,
+		RIDs:           s.RIDs,
+		ChainKeys:      s.ChainKeys,
+		ShareReceived:  s.ShareReceived.Scalars,
+		ElGamalPublic:  s.ElGamalPublic.Points,
+		PaillierPublic: make(map[party.ID]*paillier.PublicKey, len(s.NModulus)),
+		NModulus:       s.NModulus,
+		S:              s.S,
+		T:              s.T,
+		ElGamalSecret:  s.ElGamalSecret,
+		PaillierSecret: paillier.NewSecretKeyFromPrimes(s.P, s.Q),
+		PedersenSecret: s.PedersenSecret,
+		SchnorrRand:    s.SchnorrRand,
+		Decommitment:   s.Decommitment,
+	}
+	for id, data := range s.VSSPolynomials {
+		F := polynomial.EmptyExponent(group)
+		if err := F.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		r2.VSSPolynomials[id] = F
+	}
+	for id, N := range s.NModulus {
+		if id == s.Helper.SelfID() {
+			r2.PaillierPublic[id] = r2.PaillierSecret.PublicKey
+			continue
+		}
+		r2.PaillierPublic[id] = paillier.NewPublicKey(N)
+	}
+	if r2.Commitments == nil || r2.RIDs == nil || r2.ChainKeys == nil || r2.S == nil || r2.T == nil {
+		return nil, round.ErrNilFields
+	}
+	if s.Number == 2 {
+		return r2, nil
+	}
+
+	if s.SchnorrCommitments == nil {
+		return nil, round.ErrNilFields
+	}
+	r3 := &round3{
+		round2:             r2,
+		SchnorrCommitments: make(map[party.ID]*zksch.Commitment, len(s.SchnorrCommitments.Points)),
+	}
+	for id, A := range s.SchnorrCommitments.Points {
+		r3.SchnorrCommitments[id] = &zksch.Commitment{C: A}
+	}
+	if s.Number == 3 {
+		return r3, nil
+	}
+
+	r4 := &round4{
+		round3:   r3,
+		RID:      s.RID,
+		ChainKey: s.ChainKey,
+	}
+	if s.Number == 4 {
+		return r4, nil
+	}
+
+	if s.UpdatedConfig == nil {
+		return nil, round.ErrNilFields
+	}
+	return &round5{
+		round4:        r4,
+		UpdatedConfig: s.UpdatedConfig,
+	}, nil
+}
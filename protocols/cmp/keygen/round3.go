@@ -138,16 +138,7 @@ func (round3) StoreMessage(round.Message) error { return nil }
 //
 // - send proofs and encryption of share for Pⱼ.
 func (r *round3) Finalize(out chan<- *round.Message) (round.Session, error) {
-	// c = ⊕ⱼ cⱼ
-	chainKey := types.EmptyRID()
-	for _, j := range r.PartyIDs() {
-		chainKey.XOR(r.ChainKeys[j])
-	}
-	// RID = ⊕ⱼ RIDⱼ
-	rid := types.EmptyRID()
-	for _, j := range r.PartyIDs() {
-		rid.XOR(r.RIDs[j])
-	}
+	rid, chainKey := r.agreedRandomness()
 
 	// temporary hash which does not modify the state
 	h := r.Hash()
@@ -205,6 +196,20 @@ func (r *round3) Finalize(out chan<- *round.Message) (round.Session, error) {
 	}, nil
 }
 
+// agreedRandomness returns RID = ⊕ⱼ RIDⱼ and the chain key c = ⊕ⱼ cⱼ, or the previous chain key if refreshing.
+func (r *round3) agreedRandomness() (types.RID, types.RID) {
+	rid := types.EmptyRID()
+	chainKey := types.EmptyRID()
+	for _, j := range r.PartyIDs() {
+		rid.XOR(r.RIDs[j])
+		chainKey.XOR(r.ChainKeys[j])
+	}
+	if r.PreviousChainKey != nil {
+		chainKey = r.PreviousChainKey.Copy()
+	}
+	return rid, chainKey
+}
+
 // MessageContent implements round.Round.
 func (round3) MessageContent() round.Content { return nil }
 
@@ -126,34 +126,18 @@ func (r *round4) StoreMessage(msg round.Message) error {
 func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
 	// add all shares to our secret
 	UpdatedSecretECDSA := r.Group().NewScalar()
+	if r.PreviousSecretECDSA != nil {
+		UpdatedSecretECDSA.Set(r.PreviousSecretECDSA)
+	}
 	for _, j := range r.PartyIDs() {
 		UpdatedSecretECDSA.Add(r.ShareReceived[j])
 	}
 
-	// [F₁(X), …, Fₙ(X)]
-	ShamirPublicPolynomials := make([]*polynomial.Exponent, 0, len(r.VSSPolynomials))
-	for _, VSSPolynomial := range r.VSSPolynomials {
-		ShamirPublicPolynomials = append(ShamirPublicPolynomials, VSSPolynomial)
-	}
-
-	// ShamirPublicPolynomial = F(X) = ∑Fⱼ(X)
-	ShamirPublicPolynomial, err := polynomial.Sum(ShamirPublicPolynomials)
+	PublicData, err := r.publicData()
 	if err != nil {
 		return r, err
 	}
 
-	// compute the new public key share Xⱼ = F(j) (+X'ⱼ if doing a refresh)
-	PublicData := make(map[party.ID]*config.Public, len(r.PartyIDs()))
-	for _, j := range r.PartyIDs() {
-		PublicECDSAShare := ShamirPublicPolynomial.Evaluate(j.Scalar(r.Group()))
-		PublicData[j] = &config.Public{
-			ECDSA:    PublicECDSAShare,
-			ElGamal:  r.ElGamalPublic[j],
-			Paillier: r.PaillierPublic[j],
-			Pedersen: pedersen.New(r.PaillierPublic[j].Modulus(), r.S[j], r.T[j]),
-		}
-	}
-
 	UpdatedConfig := &config.Config{
 		Group:     r.Group(),
 		ID:        r.SelfID(),
@@ -186,6 +170,37 @@ func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
 	}, nil
 }
 
+// publicData returns the public information of every party, as given by the new Config.
+func (r *round4) publicData() (map[party.ID]*config.Public, error) {
+	// [F₁(X), …, Fₙ(X)]
+	ShamirPublicPolynomials := make([]*polynomial.Exponent, 0, len(r.VSSPolynomials))
+	for _, VSSPolynomial := range r.VSSPolynomials {
+		ShamirPublicPolynomials = append(ShamirPublicPolynomials, VSSPolynomial)
+	}
+
+	// ShamirPublicPolynomial = F(X) = ∑Fⱼ(X)
+	ShamirPublicPolynomial, err := polynomial.Sum(ShamirPublicPolynomials)
+	if err != nil {
+		return nil, err
+	}
+
+	// compute the new public key share Xⱼ = F(j) (+X'ⱼ if doing a refresh)
+	PublicData := make(map[party.ID]*config.Public, len(r.PartyIDs()))
+	for _, j := range r.PartyIDs() {
+		PublicECDSAShare := ShamirPublicPolynomial.Evaluate(j.Scalar(r.Group()))
+		if r.PreviousPublicSharesECDSA != nil {
+			PublicECDSAShare = PublicECDSAShare.Add(r.PreviousPublicSharesECDSA[j])
+		}
+		PublicData[j] = &config.Public{
+			ECDSA:    PublicECDSAShare,
+			ElGamal:  r.ElGamalPublic[j],
+			Paillier: r.PaillierPublic[j],
+			Pedersen: pedersen.New(r.PaillierPublic[j].Modulus(), r.S[j], r.T[j]),
+		}
+	}
+	return PublicData, nil
+}
+
 // RoundNumber implements round.Content.
 func (message4) RoundNumber() round.Number { return 4 }
 
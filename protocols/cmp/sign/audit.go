@@ -0,0 +1,113 @@
+package sign
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/paillier"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/cmp/config"
+)
+
+// Audit checks the transcript of a signing session started with StartSign, or StartSignAdaptor if Y isn't nil,
+// and returns the signature it produced.
+//
+// c only needs the public data of the parties, as returned by keygen.Audit, and messages should contain the
+// broadcast messages of all signers, such as the messages of a protocol.Transcript recorded by any of them.
+// The ciphertexts Kⱼ and Gⱼ, the consistency of Δ = [δ]G, the DLEQ shares of an adaptor signature, and the
+// final signature are checked as the signers did. The zero-knowledge proofs are sent over private channels,
+// and can only be checked by their recipients.
+//
+// keys contains the identity public key of every one of the signers, as given to protocol.WithIdentities:
+// only the messages they signed are taken into account.
+func Audit(c *config.Config, signers []party.ID, message []byte, Y curve.Point, sessionID []byte, keys map[party.ID]protocol.IdentityPublicKey, messages []*protocol.Message, pl *pool.Pool) (interface{}, error) {
+	if c == nil || c.Group == nil || c.Public == nil {
+		return nil, errors.New("sign.Audit: missing public config")
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("sign.Audit: no signers")
+	}
+	if Y != nil && Y.IsIdentity() {
+		return nil, errors.New("sign.Audit: invalid adaptor point")
+	}
+
+	// The auditor isn't a signer, so we impersonate one with a zero share, and never use its secrets.
+	impersonated := &config.Config{
+		Group:     c.Group,
+		ID:        signers[0],
+		Threshold: c.Threshold,
+		ECDSA:     c.Group.NewScalar(),
+		RID:       c.RID,
+		ChainKey:  c.ChainKey,
+		Public:    c.Public,
+	}
+	session, err := startSign(impersonated, signers, message, Y, pl)(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("sign.Audit: %w", err)
+	}
+	r1 := session.(*round1)
+	n := r1.N()
+
+	r2 := &round2{
+		round1:        r1,
+		K:             make(map[party.ID]*paillier.Ciphertext, n),
+		G:             make(map[party.ID]*paillier.Ciphertext, n),
+		BigGammaShare: make(map[party.ID]curve.Point, n),
+	}
+	if err = protocol.ReplayBroadcasts(r2, keys, messages); err != nil {
+		return nil, err
+	}
+	r3 := &round3{round2: r2}
+	if Y != nil {
+		r3.AdaptorGammaShare = make(map[party.ID]curve.Point, n)
+		r3.DLEQCommitmentG = make(map[party.ID]curve.Point, n)
+		r3.DLEQCommitmentY = make(map[party.ID]curve.Point, n)
+	}
+	if err = protocol.ReplayBroadcasts(r3, keys, messages); err != nil {
+		return nil, err
+	}
+
+	// Γ = ∑ⱼ Γⱼ
+	Gamma := r3.Group().NewPoint()
+	for _, j := range r3.PartyIDs() {
+		Gamma = Gamma.Add(r3.BigGammaShare[j])
+	}
+	r4 := &round4{
+		round3:         r3,
+		DeltaShares:    make(map[party.ID]curve.Scalar, n),
+		BigDeltaShares: make(map[party.ID]curve.Point, n),
+		Gamma:          Gamma,
+	}
+	if err = protocol.ReplayBroadcasts(r4, keys, messages); err != nil {
+		return nil, err
+	}
+
+	r5, err := r4.presignature()
+	if err != nil {
+		return nil, protocol.Error{Err: err}
+	}
+	r5.SigmaShares = make(map[party.ID]curve.Scalar, n)
+	if Y != nil {
+		r5.DLEQShares = make(map[party.ID]curve.Scalar, n)
+	}
+	if err = protocol.ReplayBroadcasts(r5, keys, messages); err != nil {
+		return nil, err
+	}
+
+	final, err := r5.Finalize(nil)
+	if err != nil {
+		return nil, fmt.Errorf("sign.Audit: %w", err)
+	}
+	switch final := final.(type) {
+	case *round.Output:
+		return final.Result, nil
+	case *round.Abort:
+		return nil, protocol.Error{Culprits: final.Culprits, Err: final.Err}
+	default:
+		return nil, fmt.Errorf("sign.Audit: unexpected round %T", final)
+	}
+}
@@ -26,6 +26,9 @@ type round1 struct {
 	ECDSA          map[party.ID]curve.Point
 
 	Message []byte
+
+	// Adaptor = Y is the adaptor point, only set when producing an adaptor signature.
+	Adaptor curve.Point
 }
 
 // VerifyMessage implements round.Round.
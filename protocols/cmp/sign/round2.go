@@ -1,10 +1,12 @@
 package sign
 
 import (
+	"crypto/rand"
 	"errors"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
 	"github.com/MixinNetwork/multi-party-sig/pkg/mta"
 	"github.com/MixinNetwork/multi-party-sig/pkg/paillier"
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
@@ -104,9 +106,21 @@ func (round2) StoreMessage(round.Message) error { return nil }
 //
 // - compute Hash(ssid, K₁, G₁, …, Kₙ, Gₙ).
 func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
-	if err := r.BroadcastMessage(out, &broadcast3{
+	broadcastMsg := broadcast3{
 		BigGammaShare: r.BigGammaShare[r.SelfID()],
-	}); err != nil {
+	}
+	// aᵢ <- 𝔽, the nonce for our share of the DLEQ proof
+	var DLEQNonce curve.Scalar
+	if r.Adaptor != nil {
+		// Γ'ᵢ = [γᵢ]Y
+		GammaShare := r.Group().NewScalar().SetNat(r.GammaShare.Mod(r.Group().Order()))
+		broadcastMsg.AdaptorGammaShare = GammaShare.Act(r.Adaptor)
+		// Aᵢ = [aᵢ]G, Bᵢ = [aᵢ]Y
+		DLEQNonce = sample.Scalar(rand.Reader, r.Group())
+		broadcastMsg.DLEQCommitmentG = DLEQNonce.ActOnBase()
+		broadcastMsg.DLEQCommitmentY = DLEQNonce.Act(r.Adaptor)
+	}
+	if err := r.BroadcastMessage(out, &broadcastMsg); err != nil {
 		return r, err
 	}
 
@@ -164,13 +178,20 @@ func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
 		ChiShareBetas[j] = m.ChiBeta
 	}
 
-	return &round3{
+	nextRound := &round3{
 		round2:          r,
 		DeltaShareBeta:  DeltaShareBetas,
 		ChiShareBeta:    ChiShareBetas,
 		DeltaShareAlpha: map[party.ID]*saferith.Int{},
 		ChiShareAlpha:   map[party.ID]*saferith.Int{},
-	}, nil
+	}
+	if r.Adaptor != nil {
+		nextRound.AdaptorGammaShare = map[party.ID]curve.Point{r.SelfID(): broadcastMsg.AdaptorGammaShare}
+		nextRound.DLEQCommitmentG = map[party.ID]curve.Point{r.SelfID(): broadcastMsg.DLEQCommitmentG}
+		nextRound.DLEQCommitmentY = map[party.ID]curve.Point{r.SelfID(): broadcastMsg.DLEQCommitmentY}
+		nextRound.DLEQNonce = DLEQNonce
+	}
+	return nextRound, nil
 }
 
 // RoundNumber implements round.Content.
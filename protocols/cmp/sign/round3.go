@@ -26,6 +26,17 @@ type round3 struct {
 	ChiShareAlpha map[party.ID]*saferith.Int
 	// ChiShareBeta[j] = β̂ᵢⱼ
 	ChiShareBeta map[party.ID]*saferith.Int
+
+	// The following are only set when producing an adaptor signature.
+
+	// AdaptorGammaShare[j] = Γ'ⱼ = [γⱼ]Y
+	AdaptorGammaShare map[party.ID]curve.Point
+	// DLEQCommitmentG[j] = Aⱼ = [aⱼ]G
+	DLEQCommitmentG map[party.ID]curve.Point
+	// DLEQCommitmentY[j] = Bⱼ = [aⱼ]Y
+	DLEQCommitmentY map[party.ID]curve.Point
+	// DLEQNonce = aᵢ <- 𝔽
+	DLEQNonce curve.Scalar
 }
 
 type message3 struct {
@@ -41,11 +52,16 @@ type message3 struct {
 type broadcast3 struct {
 	round.NormalBroadcastContent
 	BigGammaShare curve.Point // BigGammaShare = Γⱼ
+
+	AdaptorGammaShare curve.Point `cbor:",omitempty"` // AdaptorGammaShare = Γ'ⱼ = [γⱼ]Y
+	DLEQCommitmentG   curve.Point `cbor:",omitempty"` // DLEQCommitmentG = Aⱼ
+	DLEQCommitmentY   curve.Point `cbor:",omitempty"` // DLEQCommitmentY = Bⱼ
 }
 
 // StoreBroadcastMessage implements round.BroadcastRound.
 //
 // - store Γⱼ
+// - store Γ'ⱼ, Aⱼ, Bⱼ for adaptor signatures
 func (r *round3) StoreBroadcastMessage(msg round.Message) error {
 	body, ok := msg.Content.(*broadcast3)
 	if !ok || body == nil {
@@ -55,6 +71,18 @@ func (r *round3) StoreBroadcastMessage(msg round.Message) error {
 		return round.ErrNilFields
 	}
 	r.BigGammaShare[msg.From] = body.BigGammaShare
+
+	if r.Adaptor != nil {
+		if body.AdaptorGammaShare == nil || body.DLEQCommitmentG == nil || body.DLEQCommitmentY == nil {
+			return round.ErrNilFields
+		}
+		if body.AdaptorGammaShare.IsIdentity() || body.DLEQCommitmentG.IsIdentity() || body.DLEQCommitmentY.IsIdentity() {
+			return round.ErrNilFields
+		}
+		r.AdaptorGammaShare[msg.From] = body.AdaptorGammaShare
+		r.DLEQCommitmentG[msg.From] = body.DLEQCommitmentG
+		r.DLEQCommitmentY[msg.From] = body.DLEQCommitmentY
+	}
 	return nil
 }
 
@@ -226,9 +254,15 @@ func (broadcast3) RoundNumber() round.Number { return 3 }
 
 // BroadcastContent implements round.BroadcastRound.
 func (r *round3) BroadcastContent() round.BroadcastContent {
-	return &broadcast3{
+	b := &broadcast3{
 		BigGammaShare: r.Group().NewPoint(),
 	}
+	if r.Adaptor != nil {
+		b.AdaptorGammaShare = r.Group().NewPoint()
+		b.DLEQCommitmentG = r.Group().NewPoint()
+		b.DLEQCommitmentY = r.Group().NewPoint()
+	}
+	return b
 }
 
 // Number implements round.Round.
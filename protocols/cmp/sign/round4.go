@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/ecdsa"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
 	zklogstar "github.com/MixinNetwork/multi-party-sig/pkg/zk/logstar"
@@ -89,7 +90,43 @@ func (round4) StoreMessage(round.Message) error {
 // - set Δ = ∑ⱼ Δⱼ
 // - verify Δ = [δ]G
 // - compute σᵢ = rχᵢ + kᵢm.
+// - for adaptor signatures, use r = R'|ₓ with R' = [δ⁻¹]Γ', and compute zᵢ.
 func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
+	nextRound, err := r.presignature()
+	if err != nil {
+		return r.AbortRound(err), nil
+	}
+	R := nextRound.R
+
+	broadcastMsg := broadcast5{}
+	if r.Adaptor != nil {
+		// zᵢ = aᵢ + e⋅δ⁻¹⋅γᵢ
+		deltaInv := r.Group().NewScalar().Set(nextRound.Delta).Invert()
+		GammaShare := r.Group().NewScalar().SetNat(r.GammaShare.Mod(r.Group().Order()))
+		DLEQShare := r.Group().NewScalar().Set(nextRound.DLEQChallenge).Mul(deltaInv).Mul(GammaShare).Add(r.DLEQNonce)
+		broadcastMsg.DLEQShare = DLEQShare
+		nextRound.DLEQShares = map[party.ID]curve.Scalar{r.SelfID(): DLEQShare}
+	}
+
+	// km = Hash(m)⋅kᵢ
+	km := curve.FromHash(r.Group(), r.Message)
+	km.Mul(r.KShare)
+
+	// σᵢ = rχᵢ + kᵢm
+	SigmaShare := r.Group().NewScalar().Set(R).Mul(r.ChiShare).Add(km)
+
+	// Send to all
+	broadcastMsg.SigmaShare = SigmaShare
+	if err = r.BroadcastMessage(out, &broadcastMsg); err != nil {
+		return r, err
+	}
+	nextRound.SigmaShares = map[party.ID]curve.Scalar{r.SelfID(): SigmaShare}
+	return nextRound, nil
+}
+
+// presignature computes the public values of the next round from the broadcast shares:
+// δ, Δ, R = [δ⁻¹]Γ and r = R|ₓ, and for adaptor signatures R', A, B and e.
+func (r *round4) presignature() (*round5, error) {
 	// δ = ∑ⱼ δⱼ
 	// Δ = ∑ⱼ Δⱼ
 	Delta := r.Group().NewScalar()
@@ -102,33 +139,44 @@ func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
 	// Δ == [δ]G
 	deltaComputed := Delta.ActOnBase()
 	if !deltaComputed.Equal(BigDelta) {
-		return r.AbortRound(errors.New("computed Δ is inconsistent with [δ]G")), nil
+		return nil, errors.New("computed Δ is inconsistent with [δ]G")
 	}
 
 	deltaInv := r.Group().NewScalar().Set(Delta).Invert() // δ⁻¹
 	BigR := deltaInv.Act(r.Gamma)                         // R = [δ⁻¹] Γ
 	R := BigR.XScalar()                                   // r = R|ₓ
 
-	// km = Hash(m)⋅kᵢ
-	km := curve.FromHash(r.Group(), r.Message)
-	km.Mul(r.KShare)
-
-	// σᵢ = rχᵢ + kᵢm
-	SigmaShare := r.Group().NewScalar().Set(R).Mul(r.ChiShare).Add(km)
-
-	// Send to all
-	err := r.BroadcastMessage(out, &broadcast5{SigmaShare: SigmaShare})
-	if err != nil {
-		return r, err
+	nextRound := &round5{
+		round4:   r,
+		Delta:    Delta,
+		BigDelta: BigDelta,
+		BigR:     BigR,
+	}
+	if r.Adaptor != nil {
+		// R' = [δ⁻¹] ∑ⱼ Γ'ⱼ = [k⁻¹]Y
+		// A = ∑ⱼ Aⱼ, B = ∑ⱼ Bⱼ
+		AdaptorGamma := r.Group().NewPoint()
+		A := r.Group().NewPoint()
+		B := r.Group().NewPoint()
+		for _, j := range r.PartyIDs() {
+			AdaptorGamma = AdaptorGamma.Add(r.AdaptorGammaShare[j])
+			A = A.Add(r.DLEQCommitmentG[j])
+			B = B.Add(r.DLEQCommitmentY[j])
+		}
+		AdaptorR := deltaInv.Act(AdaptorGamma)
+		if AdaptorR.IsIdentity() {
+			return nil, errors.New("adapted nonce point is the identity")
+		}
+		// the signature commits to r = R'|ₓ instead
+		R = AdaptorR.XScalar()
+
+		// e = H(Y, R', R, A, B)
+		nextRound.AdaptorR = AdaptorR
+		nextRound.DLEQChallenge = ecdsa.DLEQChallenge(r.Adaptor, AdaptorR, BigR, A, B)
+		nextRound.DLEQCommitment = &ecdsa.DLEQProof{A: A, B: B}
 	}
-	return &round5{
-		round4:      r,
-		SigmaShares: map[party.ID]curve.Scalar{r.SelfID(): SigmaShare},
-		Delta:       Delta,
-		BigDelta:    BigDelta,
-		BigR:        BigR,
-		R:           R,
-	}, nil
+	nextRound.R = R
+	return nextRound, nil
 }
 
 // RoundNumber implements round.Content.
@@ -29,16 +29,29 @@ type round5 struct {
 
 	// R = R|ₓ
 	R curve.Scalar
+
+	// The following are only set when producing an adaptor signature.
+
+	// AdaptorR = R' = [δ⁻¹] Γ'
+	AdaptorR curve.Point
+	// DLEQChallenge = e = H(Y, R', R, A, B)
+	DLEQChallenge curve.Scalar
+	// DLEQCommitment holds A = ∑ⱼ Aⱼ and B = ∑ⱼ Bⱼ
+	DLEQCommitment *ecdsa.DLEQProof
+	// DLEQShares[j] = zⱼ = aⱼ + e⋅δ⁻¹⋅γⱼ
+	DLEQShares map[party.ID]curve.Scalar
 }
 
 type broadcast5 struct {
 	round.NormalBroadcastContent
 	SigmaShare curve.Scalar
+	DLEQShare  curve.Scalar `cbor:",omitempty"`
 }
 
 // StoreBroadcastMessage implements round.BroadcastRound.
 //
 // - save σⱼ
+// - for adaptor signatures, verify and save zⱼ
 func (r *round5) StoreBroadcastMessage(msg round.Message) error {
 	body, ok := msg.Content.(*broadcast5)
 	if !ok || body == nil {
@@ -49,6 +62,21 @@ func (r *round5) StoreBroadcastMessage(msg round.Message) error {
 		return round.ErrNilFields
 	}
 
+	if r.Adaptor != nil {
+		if body.DLEQShare == nil {
+			return round.ErrNilFields
+		}
+		// zⱼ•G = Aⱼ + [e⋅δ⁻¹]Γⱼ
+		// zⱼ•Y = Bⱼ + [e⋅δ⁻¹]Γ'ⱼ
+		e := r.Group().NewScalar().Set(r.Delta).Invert().Mul(r.DLEQChallenge)
+		expectedG := e.Act(r.BigGammaShare[msg.From]).Add(r.DLEQCommitmentG[msg.From])
+		expectedY := e.Act(r.AdaptorGammaShare[msg.From]).Add(r.DLEQCommitmentY[msg.From])
+		if !body.DLEQShare.ActOnBase().Equal(expectedG) || !body.DLEQShare.Act(r.Adaptor).Equal(expectedY) {
+			return errors.New("failed to validate DLEQ share")
+		}
+		r.DLEQShares[msg.From] = body.DLEQShare
+	}
+
 	r.SigmaShares[msg.From] = body.SigmaShare
 	return nil
 }
@@ -63,6 +91,7 @@ func (round5) StoreMessage(round.Message) error { return nil }
 //
 // - compute σ = ∑ⱼ σⱼ
 // - verify signature.
+// - for adaptor signatures, compute z = ∑ⱼ zⱼ and verify the pre-signature instead.
 func (r *round5) Finalize(chan<- *round.Message) (round.Session, error) {
 	// compute σ = ∑ⱼ σⱼ
 	Sigma := r.Group().NewScalar()
@@ -70,6 +99,30 @@ func (r *round5) Finalize(chan<- *round.Message) (round.Session, error) {
 		Sigma.Add(r.SigmaShares[j])
 	}
 
+	if r.Adaptor != nil {
+		// z = ∑ⱼ zⱼ
+		Z := r.Group().NewScalar()
+		for _, j := range r.PartyIDs() {
+			Z.Add(r.DLEQShares[j])
+		}
+		signature := &ecdsa.AdaptorSignature{
+			R:  r.AdaptorR,
+			RG: r.BigR,
+			S:  Sigma,
+			Proof: &ecdsa.DLEQProof{
+				A: r.DLEQCommitment.A,
+				B: r.DLEQCommitment.B,
+				Z: Z,
+			},
+		}
+
+		if !signature.Verify(r.PublicKey, r.Adaptor, r.Message) {
+			return r.AbortRound(errors.New("failed to validate adaptor signature")), nil
+		}
+
+		return r.ResultRound(signature), nil
+	}
+
 	signature := &ecdsa.Signature{
 		R: r.BigR,
 		S: Sigma,
@@ -90,9 +143,13 @@ func (broadcast5) RoundNumber() round.Number { return 5 }
 
 // BroadcastContent implements round.BroadcastRound.
 func (r *round5) BroadcastContent() round.BroadcastContent {
-	return &broadcast5{
+	b := &broadcast5{
 		SigmaShare: r.Group().NewScalar(),
 	}
+	if r.Adaptor != nil {
+		b.DLEQShare = r.Group().NewScalar()
+	}
+	return b
 }
 
 // Number implements round.Round.
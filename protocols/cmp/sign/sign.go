@@ -6,6 +6,7 @@ import (
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
 	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
 	"github.com/MixinNetwork/multi-party-sig/pkg/paillier"
@@ -18,11 +19,30 @@ import (
 
 // protocolSignID for the "3 round" variant using echo broadcast.
 const (
-	protocolSignID                  = "cmp/sign"
-	protocolSignRounds round.Number = 5
+	protocolSignID                     = "cmp/sign"
+	protocolSignAdaptorID              = "cmp/sign-adaptor"
+	protocolSignRounds    round.Number = 5
 )
 
 func StartSign(config *config.Config, signers []party.ID, message []byte, pl *pool.Pool) protocol.StartFunc {
+	return startSign(config, signers, message, nil, pl)
+}
+
+// StartSignAdaptor is like StartSign, but produces an ecdsa.AdaptorSignature encrypted
+// under the adaptor point Y, instead of a complete signature.
+//
+// The nonce point of the final signature is shifted to R = [k⁻¹]Y, and the parties jointly
+// prove that it shares its discrete logarithm with the pre-signature nonce point [k⁻¹]G.
+func StartSignAdaptor(config *config.Config, signers []party.ID, message []byte, Y curve.Point, pl *pool.Pool) protocol.StartFunc {
+	if Y == nil || Y.IsIdentity() {
+		return func([]byte) (round.Session, error) {
+			return nil, errors.New("sign.Create: invalid adaptor point")
+		}
+	}
+	return startSign(config, signers, message, Y, pl)
+}
+
+func startSign(config *config.Config, signers []party.ID, message []byte, adaptor curve.Point, pl *pool.Pool) protocol.StartFunc {
 	return func(sessionID []byte) (round.Session, error) {
 		group := config.Group
 
@@ -40,7 +60,20 @@ func StartSign(config *config.Config, signers []party.ID, message []byte, pl *po
 			Group:            config.Group,
 		}
 
-		helper, err := round.NewSession(info, sessionID, pl, config, types.SigningMessage(message))
+		var adaptorInfo hash.WriterToWithDomain
+		if adaptor != nil {
+			info.ProtocolID = protocolSignAdaptorID
+			adaptorBytes, err := adaptor.MarshalBinary()
+			if err != nil {
+				return nil, fmt.Errorf("sign.Create: %w", err)
+			}
+			adaptorInfo = &hash.BytesWithDomain{
+				TheDomain: "Adaptor Point",
+				Bytes:     adaptorBytes,
+			}
+		}
+
+		helper, err := round.NewSession(info, sessionID, pl, config, types.SigningMessage(message), adaptorInfo)
 		if err != nil {
 			return nil, fmt.Errorf("sign.Create: %w", err)
 		}
@@ -77,6 +110,7 @@ func StartSign(config *config.Config, signers []party.ID, message []byte, pl *po
 			Pedersen:       Pedersen,
 			ECDSA:          ECDSA,
 			Message:        message,
+			Adaptor:        adaptor,
 		}, nil
 	}
 }
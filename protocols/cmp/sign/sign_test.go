@@ -1,6 +1,7 @@
 package sign
 
 import (
+	"crypto/rand"
 	mrand "math/rand"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/MixinNetwork/multi-party-sig/internal/test"
 	"github.com/MixinNetwork/multi-party-sig/pkg/ecdsa"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
 	"github.com/MixinNetwork/multi-party-sig/pkg/pool"
 	"golang.org/x/crypto/sha3"
 )
@@ -57,3 +59,54 @@ func TestRound(t *testing.T) {
 		assert.True(t, signature.Verify(publicPoint, messageHash), "expected valid signature")
 	}
 }
+
+func TestRoundAdaptor(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 4
+	T := N - 1
+
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+
+	publicPoint := configs[partyIDs[0]].PublicPoint()
+
+	messageToSign := []byte("hello")
+	messageHash := make([]byte, 64)
+	sha3.ShakeSum128(messageHash, messageToSign)
+
+	adaptorSecret := sample.Scalar(rand.Reader, group)
+	adaptorPoint := adaptorSecret.ActOnBase()
+
+	rounds := make([]round.Session, 0, N)
+	for _, partyID := range partyIDs {
+		c := configs[partyID]
+		r, err := StartSignAdaptor(c, partyIDs, messageHash, adaptorPoint, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+		resultRound := r.(*round.Output)
+		require.IsType(t, &ecdsa.AdaptorSignature{}, resultRound.Result, "expected adaptor signature result")
+		preSignature := resultRound.Result.(*ecdsa.AdaptorSignature)
+		assert.True(t, preSignature.Verify(publicPoint, adaptorPoint, messageHash), "expected valid adaptor signature")
+
+		signature := preSignature.Decrypt(adaptorSecret)
+		assert.True(t, signature.Verify(publicPoint, messageHash), "expected valid decrypted signature")
+
+		recovered, err := preSignature.RecoverDecryptionKey(signature, adaptorPoint)
+		require.NoError(t, err)
+		assert.True(t, recovered.Equal(adaptorSecret), "expected recovered adaptor secret")
+	}
+}
@@ -1,6 +1,7 @@
 package frost
 
 import (
+	"github.com/MixinNetwork/mixin/crypto"
 	"github.com/MixinNetwork/multi-party-sig/common/round"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
@@ -10,9 +11,10 @@ import (
 )
 
 type (
-	Config        = keygen.Config
-	TaprootConfig = keygen.TaprootConfig
-	Signature     = sign.Signature
+	Config           = keygen.Config
+	TaprootConfig    = keygen.TaprootConfig
+	Signature        = sign.Signature
+	AdaptorSignature = sign.AdaptorSignature
 )
 
 // EmptyConfig creates an empty Config with a specific group.
@@ -58,6 +60,44 @@ func KeygenTaproot(selfID party.ID, participants []party.ID, threshold int) prot
 	return keygen.StartKeygenCommon(true, curve.Secp256k1{}, participants, threshold, selfID)
 }
 
+// KeygenPVSS is like Keygen, but doesn't require private channels between participants.
+//
+// Each share is encrypted to the static public key of its recipient, and broadcast with a proof
+// of correct encryption. The protocol can then run over a public log, such as a blockchain, and
+// anybody can check that it completed correctly with keygen.AuditPVSS.
+//
+// encryptionKey is the static private key of selfID, and encryptionKeys contains the public
+// keys of all participants, on the same curve.
+func KeygenPVSS(group curve.Curve, selfID party.ID, participants []party.ID, threshold int, encryptionKey curve.Scalar, encryptionKeys map[party.ID]curve.Point) protocol.StartFunc {
+	return keygen.StartKeygenPVSS(false, group, participants, threshold, selfID, encryptionKey, encryptionKeys)
+}
+
+// KeygenTaprootPVSS is like KeygenPVSS, but will make Taproot / BIP-340 compatible keys.
+func KeygenTaprootPVSS(selfID party.ID, participants []party.ID, threshold int, encryptionKey curve.Scalar, encryptionKeys map[party.ID]curve.Point) protocol.StartFunc {
+	return keygen.StartKeygenPVSS(true, curve.Secp256k1{}, participants, threshold, selfID, encryptionKey, encryptionKeys)
+}
+
+// Refresh creates new shares of the key generated by Keygen, among the same participants.
+//
+// The public key, the chain key and the threshold stay the same, but the previous shares
+// can't be combined with the new ones, and should be deleted once the protocol succeeds.
+func Refresh(config *Config) protocol.StartFunc {
+	return keygen.StartRefresh(false, config)
+}
+
+// RefreshTaproot is like Refresh, but for the shares of a key generated by KeygenTaproot.
+//
+// This will also return TaprootResult instead of Result, at the end of the protocol.
+func RefreshTaproot(config *TaprootConfig) protocol.StartFunc {
+	normalResult, err := taprootConfig(config)
+	if err != nil {
+		return func([]byte) (round.Session, error) {
+			return nil, err
+		}
+	}
+	return keygen.StartRefresh(true, normalResult)
+}
+
 // Sign initiates the protocol for producing a threshold signature, with Frost.
 //
 // result is the result of the key generation phase, for this participant.
@@ -88,22 +128,78 @@ func Sign(config *Config, signers []party.ID, messageHash []byte, variant int) p
 //
 // See: https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki
 func SignTaproot(config *TaprootConfig, signers []party.ID, messageHash []byte) protocol.StartFunc {
-	publicKey, err := curve.Secp256k1{}.LiftX(config.PublicKey)
+	normalResult, err := taprootConfig(config)
 	if err != nil {
 		return func([]byte) (round.Session, error) {
 			return nil, err
 		}
 	}
+	return sign.StartSignCommon(normalResult, signers, messageHash, sign.ProtocolTaproot)
+}
+
+// SignMixinGhost is like Sign with sign.ProtocolMixinPublic, but takes the Mixin kernel output
+// being spent, instead of a message prefixed by the ghost key scalar.
+//
+// The output is identified by its mask R and its index in the transaction, and must be sent to
+// the deterministic Mixin address with config.PublicKey as public spend key. Its one-time public
+// key is given by MixinGhostKey.
+//
+// The resulting *Signature verifies with VerifyEd25519 against the one-time public key.
+func SignMixinGhost(config *Config, signers []party.ID, message []byte, R crypto.Key, outputIndex uint64) protocol.StartFunc {
+	return sign.StartSignMixinGhost(config, signers, message, R, outputIndex)
+}
+
+// MixinGhostKey returns the one-time public key of the output with mask R and index outputIndex,
+// sent to the deterministic Mixin address with config.PublicKey as public spend key.
+func MixinGhostKey(config *Config, R crypto.Key, outputIndex uint64) (crypto.Key, error) {
+	_, P, err := sign.MixinGhostKey(config.PublicKey, R, outputIndex)
+	return P, err
+}
+
+// SignAdaptor is like Sign, but will generate a pre-signature, encrypted under the adaptor point T.
+//
+// The result is an *AdaptorSignature, which can be verified against T, completed into
+// a Signature by anybody knowing the discrete logarithm t of T, and which reveals t
+// to anybody seeing both the pre-signature and the completed signature.
+func SignAdaptor(config *Config, signers []party.ID, messageHash []byte, T curve.Point) protocol.StartFunc {
+	return sign.StartSignAdaptor(config, signers, messageHash, T, sign.ProtocolDefault)
+}
+
+// SignTaprootAdaptor is like SignAdaptor, but will generate a Taproot / BIP-340 compatible pre-signature.
+//
+// The result is a taproot.AdaptorSignature.
+func SignTaprootAdaptor(config *TaprootConfig, signers []party.ID, messageHash []byte, T curve.Point) protocol.StartFunc {
+	normalResult, err := taprootConfig(config)
+	if err != nil {
+		return func([]byte) (round.Session, error) {
+			return nil, err
+		}
+	}
+	return sign.StartSignAdaptor(normalResult, signers, messageHash, T, sign.ProtocolTaproot)
+}
+
+// RestoreKeygen resumes any of the keygen variants from a snapshot taken with
+// protocol.MultiHandler.Snapshot, and can be passed to protocol.RestoreMultiHandler.
+func RestoreKeygen(data []byte) (round.Session, error) {
+	return keygen.Restore(data)
+}
+
+// taprootConfig converts a TaprootConfig into a generic Config, by lifting the public key.
+func taprootConfig(config *TaprootConfig) (*keygen.Config, error) {
+	publicKey, err := curve.Secp256k1{}.LiftX(config.PublicKey)
+	if err != nil {
+		return nil, err
+	}
 	genericVerificationShares := make(map[party.ID]curve.Point)
 	for k, v := range config.VerificationShares {
 		genericVerificationShares[k] = v
 	}
-	normalResult := &keygen.Config{
+	return &keygen.Config{
 		ID:                 config.ID,
 		Threshold:          config.Threshold,
 		PrivateShare:       config.PrivateShare,
 		PublicKey:          publicKey,
+		ChainKey:           config.ChainKey,
 		VerificationShares: party.NewPointMap(genericVerificationShares),
-	}
-	return sign.StartSignCommon(normalResult, signers, messageHash, sign.ProtocolTaproot)
+	}, nil
 }
@@ -1,6 +1,9 @@
 package frost
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
 	"sync"
 	"testing"
@@ -10,7 +13,9 @@ import (
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
 	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
 	"github.com/MixinNetwork/multi-party-sig/pkg/taproot"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost/keygen"
 	"github.com/MixinNetwork/multi-party-sig/protocols/frost/sign"
+	"github.com/MixinNetwork/multi-party-sig/transport/simnet"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -85,3 +90,201 @@ func TestFrost(t *testing.T) {
 	testFrost(t, curve.Edwards25519{}, sign.ProtocolDefault)
 	testFrost(t, curve.Secp256k1{}, sign.ProtocolDefault)
 }
+
+// run executes a protocol over a simulated network, and records the messages of the first party in transcript.
+// If ids isn't nil, the handlers authenticate their messages with the identities it contains.
+func run(t *testing.T, partyIDs party.IDSlice, start func(id party.ID) protocol.StartFunc, transcript *protocol.Transcript, ids map[party.ID]*protocol.Identities) map[party.ID]interface{} {
+	n := simnet.New(0)
+	handlers := make(map[party.ID]*protocol.MultiHandler, len(partyIDs))
+	for i, id := range partyIDs {
+		var observer protocol.Observer
+		if i == 0 {
+			observer = transcript
+		}
+		opts := []protocol.Option{protocol.WithObserver(observer)}
+		if ids != nil {
+			opts = append(opts, protocol.WithIdentities(ids[id]))
+		}
+		h, err := protocol.NewMultiHandler(start(id), nil, opts...)
+		require.NoError(t, err)
+		handlers[id] = h
+		n.Add(id, h)
+	}
+	require.NoError(t, n.Run())
+	results := make(map[party.ID]interface{}, len(partyIDs))
+	for id, h := range handlers {
+		result, err := h.Result()
+		require.NoError(t, err)
+		results[id] = result
+	}
+	return results
+}
+
+// identities generates an identity key for each party.
+func identities(t *testing.T, partyIDs []party.ID) map[party.ID]*protocol.Identities {
+	keys := make(map[party.ID]protocol.IdentityKey, len(partyIDs))
+	public := make(map[party.ID]protocol.IdentityPublicKey, len(partyIDs))
+	for _, id := range partyIDs {
+		_, sk, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[id] = protocol.Ed25519Identity(sk)
+		public[id] = keys[id].Public()
+	}
+	ids := make(map[party.ID]*protocol.Identities, len(partyIDs))
+	for _, id := range partyIDs {
+		ids[id] = &protocol.Identities{Key: keys[id], Keys: public}
+	}
+	return ids
+}
+
+func TestAudit(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(4)
+	threshold := 2
+	signers := partyIDs[1:]
+	message := []byte("hello")
+	ids := identities(t, partyIDs)
+	keys := ids[partyIDs[0]].Keys
+
+	var record bytes.Buffer
+	configs := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return Keygen(group, id, partyIDs, threshold)
+	}, protocol.NewTranscript(&record), ids)
+	transcript, err := protocol.ReadTranscript(&record)
+	require.NoError(t, err)
+	keygenMessages := transcript.Messages()
+	public, err := keygen.Audit(false, group, partyIDs, threshold, nil, keys, keygenMessages)
+	require.NoError(t, err)
+	for id, c := range configs {
+		config := c.(*Config)
+		assert.True(t, public.PublicKey.Equal(config.PublicKey))
+		assert.True(t, public.VerificationShares.Points[id].Equal(config.PrivateShare.ActOnBase()))
+		assert.Equal(t, public.ChainKey, config.ChainKey)
+	}
+
+	transcript = protocol.NewTranscript(nil)
+	signatures := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return Sign(configs[id].(*Config), signers, message, sign.ProtocolDefault)
+	}, transcript, ids)
+	result, err := sign.Audit(public, signers, message, sign.ProtocolDefault, nil, keys, transcript.Messages())
+	require.NoError(t, err)
+	require.IsType(t, &Signature{}, result)
+	signature := result.(*Signature)
+	assert.True(t, signature.Verify(public.PublicKey, message))
+	assert.True(t, signature.R.Equal(signatures[signers[0]].(*Signature).R))
+
+	// a response is checked against the verification share of its sender.
+	var messages []*protocol.Message
+	for _, msg := range transcript.Messages() {
+		if msg.Broadcast && msg.RoundNumber == 3 && msg.From == signers[1] {
+			tampered := *msg
+			for _, m := range transcript.Messages() {
+				if m.Broadcast && m.RoundNumber == 3 && m.From == signers[2] {
+					tampered.Data = m.Data
+				}
+			}
+			signature, err := ids[signers[1]].Key.Sign(tampered.Hash())
+			require.NoError(t, err)
+			tampered.Signature = signature
+			msg = &tampered
+		}
+		messages = append(messages, msg)
+	}
+	_, err = sign.Audit(public, signers, message, sign.ProtocolDefault, nil, keys, messages)
+	require.Error(t, err)
+	require.IsType(t, protocol.Error{}, err)
+	assert.Equal(t, []party.ID{signers[1]}, err.(protocol.Error).Culprits)
+
+	// a message which isn't signed by its sender is ignored.
+	var forged []*protocol.Message
+	for _, msg := range transcript.Messages() {
+		if msg.Broadcast && msg.RoundNumber == 3 && msg.From == signers[1] {
+			tampered := *msg
+			signature, err := ids[signers[2]].Key.Sign(tampered.Hash())
+			require.NoError(t, err)
+			tampered.Signature = signature
+			msg = &tampered
+		}
+		forged = append(forged, msg)
+	}
+	_, err = sign.Audit(public, signers, message, sign.ProtocolDefault, nil, keys, forged)
+	require.Error(t, err)
+	require.IsType(t, protocol.Error{}, err)
+	assert.Equal(t, []party.ID{signers[1]}, err.(protocol.Error).Culprits)
+	assert.Contains(t, err.Error(), "missing broadcast messages")
+
+	// the messages of another session are ignored.
+	_, err = keygen.Audit(false, group, partyIDs, threshold, []byte("other"), keys, keygenMessages)
+	assert.Error(t, err)
+}
+
+func TestRefresh(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(4)
+	threshold := 2
+	signers := partyIDs[1:]
+	message := []byte("hello")
+
+	configs := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return Keygen(group, id, partyIDs, threshold)
+	}, protocol.NewTranscript(nil), nil)
+	refreshed := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return Refresh(configs[id].(*Config))
+	}, protocol.NewTranscript(nil), nil)
+	for id, r := range refreshed {
+		require.IsType(t, &Config{}, r)
+		previous, config := configs[id].(*Config), r.(*Config)
+		assert.True(t, previous.PublicKey.Equal(config.PublicKey))
+		assert.Equal(t, previous.ChainKey, config.ChainKey)
+		assert.False(t, previous.PrivateShare.Equal(config.PrivateShare))
+		assert.True(t, config.PrivateShare.ActOnBase().Equal(config.VerificationShares.Points[id]))
+	}
+	signatures := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return Sign(refreshed[id].(*Config), signers, message, sign.ProtocolDefault)
+	}, protocol.NewTranscript(nil), nil)
+	for _, s := range signatures {
+		assert.True(t, s.(*Signature).Verify(configs[signers[0]].(*Config).PublicKey, message))
+	}
+
+	taprootConfigs := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return KeygenTaproot(id, partyIDs, threshold)
+	}, protocol.NewTranscript(nil), nil)
+	refreshed = run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return RefreshTaproot(taprootConfigs[id].(*TaprootConfig))
+	}, protocol.NewTranscript(nil), nil)
+	for id, r := range refreshed {
+		require.IsType(t, &TaprootConfig{}, r)
+		previous, config := taprootConfigs[id].(*TaprootConfig), r.(*TaprootConfig)
+		assert.Equal(t, previous.PublicKey, config.PublicKey)
+		assert.Equal(t, previous.ChainKey, config.ChainKey)
+	}
+	signatures = run(t, signers, func(id party.ID) protocol.StartFunc {
+		return SignTaproot(refreshed[id].(*TaprootConfig), signers, message)
+	}, protocol.NewTranscript(nil), nil)
+	for _, s := range signatures {
+		assert.True(t, taprootConfigs[signers[0]].(*TaprootConfig).PublicKey.Verify(s.(taproot.Signature), message))
+	}
+}
+
+func TestSnapshotSign(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+	threshold := 1
+	message := []byte("hello")
+
+	configs := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return Keygen(group, id, partyIDs, threshold)
+	}, protocol.NewTranscript(nil), nil)
+
+	// the first round is done as soon as the handler starts, and the next one holds the nonces
+	// committed to in its messages, so it can't be saved.
+	for _, id := range partyIDs {
+		h, err := protocol.NewMultiHandler(Sign(configs[id].(*Config), partyIDs, message, sign.ProtocolDefault), nil)
+		require.NoError(t, err)
+		for len(h.Listen()) > 0 {
+			<-h.Listen()
+		}
+		_, err = h.Snapshot(make([]byte, protocol.SnapshotKeySize))
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,75 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// Audit checks the transcript of a key generation started with StartKeygenCommon, and returns the public key it produced.
+//
+// messages should contain the broadcast messages of all participants, such as the messages of a protocol.Transcript
+// recorded by any of them. The polynomial commitments, the proofs of knowledge of their constant terms, the chain key
+// decommitments, and the shares revealed to answer complaints are checked as the participants did. The shares sent over
+// private channels can't be checked without the secrets of their recipients, but a party who received an invalid share
+// complained about it, so that a successful audit means that no complaint was left unanswered.
+//
+// keys contains the identity public key of every one of the participants, as given to protocol.WithIdentities:
+// only the messages they signed are taken into account.
+func Audit(taproot bool, group curve.Curve, participants []party.ID, threshold int, sessionID []byte, keys map[party.ID]protocol.IdentityPublicKey, messages []*protocol.Message) (*PublicConfig, error) {
+	if len(participants) == 0 {
+		return nil, errors.New("keygen.Audit: no participants")
+	}
+	// The auditor isn't a participant, so we impersonate one, without using its shares.
+	session, err := startKeygen(taproot, group, participants, threshold, participants[0], nil, nil, nil, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("keygen.Audit: %w", err)
+	}
+	r1 := session.(*round1)
+
+	r2 := &round2{
+		round1:              r1,
+		Phi:                 make(map[party.ID]*polynomial.Exponent, len(participants)),
+		ChainKeys:           make(map[party.ID]types.RID, len(participants)),
+		ChainKeyCommitments: make(map[party.ID]hash.Commitment, len(participants)),
+	}
+	if err = protocol.ReplayBroadcasts(r2, keys, messages); err != nil {
+		return nil, err
+	}
+	r3 := &round3{
+		round2:     r2,
+		shareFrom:  make(map[party.ID]curve.Scalar),
+		complaints: make(map[party.ID]bool),
+	}
+	if err = protocol.ReplayBroadcasts(r3, keys, messages); err != nil {
+		return nil, err
+	}
+	r4 := &round4{
+		round3:     r3,
+		complaints: make(map[party.ID][]party.ID, len(participants)),
+	}
+	if err = protocol.ReplayBroadcasts(r4, keys, messages); err != nil {
+		return nil, err
+	}
+	if complainers := r4.complainers(); len(complainers) > 0 {
+		r5 := &round5{
+			round4:      r4,
+			complainers: complainers,
+		}
+		if err = protocol.ReplayBroadcasts(r5, keys, messages); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := publicConfig(r3.finish())
+	if err != nil {
+		return nil, fmt.Errorf("keygen.Audit: %w", err)
+	}
+	return config, nil
+}
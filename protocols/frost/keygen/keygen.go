@@ -1,9 +1,12 @@
 package keygen
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
 	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
@@ -13,8 +16,15 @@ const (
 	// Frost KeyGen with Threshold.
 	protocolIDDefault = "frost/keygen-threshold-default"
 	protocolIDTaproot = "frost/keygen-threshold-taproot"
-	// This protocol has 3 concrete rounds.
-	protocolRounds round.Number = 3
+	// Frost KeyGen with Threshold, over a public broadcast channel.
+	protocolIDDefaultPVSS = "frost/keygen-threshold-default-pvss"
+	protocolIDTaprootPVSS = "frost/keygen-threshold-taproot-pvss"
+	// Frost Refresh with Threshold.
+	protocolIDRefreshDefault = "frost/refresh-threshold-default"
+	protocolIDRefreshTaproot = "frost/refresh-threshold-taproot"
+	// This protocol has 3 concrete rounds, and up to 2 more to resolve complaints
+	// about shares sent over private channels.
+	protocolRounds round.Number = 5
 )
 
 // These assert that our rounds implement the round.Round interface.
@@ -22,42 +32,129 @@ var (
 	_ round.Round = (*round1)(nil)
 	_ round.Round = (*round2)(nil)
 	_ round.Round = (*round3)(nil)
+	_ round.Round = (*round4)(nil)
+	_ round.Round = (*round5)(nil)
 )
 
 func StartKeygenCommon(taproot bool, group curve.Curve, participants []party.ID, threshold int, selfID party.ID) protocol.StartFunc {
 	return func(sessionID []byte) (round.Session, error) {
-		info := round.Info{
-			FinalRoundNumber: protocolRounds,
-			SelfID:           selfID,
-			PartyIDs:         participants,
-			Threshold:        threshold,
-			Group:            group,
+		return startKeygen(taproot, group, participants, threshold, selfID, nil, nil, nil, sessionID)
+	}
+}
+
+// StartRefresh creates new shares of the key in config, among the same participants and with the same threshold.
+//
+// Every participant shares 0 instead of a random secret, so that the public key and the chain key don't change,
+// while the previous shares can't be combined with the new ones. For Taproot keys, config must hold the public
+// key with an even y coordinate, and a TaprootConfig is returned.
+func StartRefresh(taproot bool, config *Config) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if config == nil || config.PublicKey == nil || config.PublicKey.IsIdentity() || config.VerificationShares == nil {
+			return nil, errors.New("keygen.StartRefresh: invalid config")
 		}
-		if taproot {
-			info.ProtocolID = protocolIDTaproot
-		} else {
-			info.ProtocolID = protocolIDDefault
+		participants := make([]party.ID, 0, len(config.VerificationShares.Points))
+		for id := range config.VerificationShares.Points {
+			participants = append(participants, id)
 		}
+		return startKeygen(taproot, config.Curve(), participants, config.Threshold, config.ID, nil, nil, config, sessionID)
+	}
+}
 
-		helper, err := round.NewSession(info, sessionID, nil)
+// StartKeygenPVSS is like StartKeygenCommon, but doesn't require private channels between participants.
+//
+// Every share is encrypted to the static public key of its recipient, and broadcast along with
+// a proof of correct encryption, so that the whole protocol only consists of broadcast messages.
+// These can be published on a public log, and audited by anybody with AuditPVSS.
+//
+// encryptionKey is the private key of this participant, and encryptionKeys contains the public
+// keys of all participants, including this one.
+func StartKeygenPVSS(taproot bool, group curve.Curve, participants []party.ID, threshold int, selfID party.ID, encryptionKey curve.Scalar, encryptionKeys map[party.ID]curve.Point) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if encryptionKey == nil || encryptionKey.IsZero() {
+			return nil, errors.New("keygen.StartKeygenPVSS: missing encryption key")
+		}
+		keys, err := newEncryptionKeys(group, participants, encryptionKeys)
+		if err != nil {
+			return nil, fmt.Errorf("keygen.StartKeygenPVSS: %w", err)
+		}
+		if D, ok := keys[selfID]; !ok || !encryptionKey.ActOnBase().Equal(D) {
+			return nil, errors.New("keygen.StartKeygenPVSS: encryption key mismatch")
+		}
+		return startKeygen(taproot, group, participants, threshold, selfID, encryptionKey, keys, nil, sessionID)
+	}
+}
+
+// startKeygen creates the first round of a key generation, or of a refresh of previous.
+func startKeygen(taproot bool, group curve.Curve, participants []party.ID, threshold int, selfID party.ID, encryptionKey curve.Scalar, keys encryptionKeys, previous *Config, sessionID []byte) (round.Session, error) {
+	info := round.Info{
+		FinalRoundNumber: protocolRounds,
+		SelfID:           selfID,
+		PartyIDs:         participants,
+		Threshold:        threshold,
+		Group:            group,
+	}
+	switch {
+	case taproot && previous != nil:
+		info.ProtocolID = protocolIDRefreshTaproot
+	case previous != nil:
+		info.ProtocolID = protocolIDRefreshDefault
+	case taproot && keys != nil:
+		info.ProtocolID = protocolIDTaprootPVSS
+	case taproot:
+		info.ProtocolID = protocolIDTaproot
+	case keys != nil:
+		info.ProtocolID = protocolIDDefaultPVSS
+	default:
+		info.ProtocolID = protocolIDDefault
+	}
+
+	var aux hash.WriterToWithDomain
+	switch {
+	case keys != nil:
+		aux = keys
+	case previous != nil:
+		// bind the session to the key being refreshed
+		publicKeyBytes, err := previous.PublicKey.MarshalBinary()
 		if err != nil {
 			return nil, fmt.Errorf("keygen.StartKeygen: %w", err)
 		}
+		aux = &hash.BytesWithDomain{TheDomain: "Previous Public Key", Bytes: publicKeyBytes}
+	}
+	helper, err := round.NewSession(info, sessionID, nil, aux)
+	if err != nil {
+		return nil, fmt.Errorf("keygen.StartKeygen: %w", err)
+	}
 
-		privateShare := group.NewScalar()
-		publicKey := group.NewPoint()
-		verificationShares := make(map[party.ID]curve.Point, len(participants))
+	privateShare := group.NewScalar()
+	publicKey := group.NewPoint()
+	verificationShares := make(map[party.ID]curve.Point, len(participants))
+	for _, k := range participants {
+		verificationShares[k] = group.NewPoint()
+	}
+	var chainKey types.RID
+	if previous != nil {
+		if previous.PrivateShare == nil || !previous.PrivateShare.ActOnBase().Equal(previous.VerificationShares.Points[selfID]) {
+			return nil, errors.New("keygen.StartKeygen: private share doesn't match its verification share")
+		}
+		if err = types.RID(previous.ChainKey).Validate(); err != nil {
+			return nil, fmt.Errorf("keygen.StartKeygen: chain key: %w", err)
+		}
+		privateShare.Set(previous.PrivateShare)
+		publicKey = previous.PublicKey
 		for _, k := range participants {
-			verificationShares[k] = group.NewPoint()
+			verificationShares[k] = previous.VerificationShares.Points[k]
 		}
-
-		return &round1{
-			Helper:             helper,
-			taproot:            taproot,
-			threshold:          threshold,
-			privateShare:       privateShare,
-			verificationShares: verificationShares,
-			publicKey:          publicKey,
-		}, nil
+		chainKey = types.RID(previous.ChainKey).Copy()
 	}
+	return &round1{
+		Helper:             helper,
+		taproot:            taproot,
+		threshold:          threshold,
+		privateShare:       privateShare,
+		verificationShares: verificationShares,
+		publicKey:          publicKey,
+		chainKey:           chainKey,
+		encryptionKey:      encryptionKey,
+		encryptionKeys:     keys,
+	}, nil
 }
@@ -1,13 +1,18 @@
 package keygen
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"testing"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
 	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/internal/test/byzantine"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/cronokirby/saferith"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -162,3 +167,125 @@ func TestKeygenTaproot(t *testing.T) {
 
 	checkOutputTaproot(t, rounds, partyIDs)
 }
+
+// complaintRule makes accused send an invalid share to complainer, or makes complainer lie about it.
+type complaintRule struct {
+	accused, complainer party.ID
+	// invalidShare makes the accused send an invalid share, instead of the complainer lying.
+	invalidShare bool
+}
+
+func (rule *complaintRule) ModifyBefore(r round.Session) {
+	if r3, ok := r.(*round3); ok && r3.SelfID() == rule.complainer && !rule.invalidShare {
+		r3.complaints[rule.accused] = true
+	}
+}
+
+func (rule *complaintRule) ModifyAfter(round.Session) {}
+
+func (rule *complaintRule) ModifyContent(rNext round.Session, to party.ID, content round.Content) {
+	if rNext.SelfID() != rule.accused {
+		return
+	}
+	if body, ok := content.(*message3); ok && to == rule.complainer && rule.invalidShare {
+		body.F_li.Add(rNext.Group().NewScalar().SetNat(new(saferith.Nat).SetUint64(1)))
+	}
+}
+
+func identities(t *testing.T, partyIDs []party.ID) map[party.ID]*protocol.Identities {
+	keys := make(map[party.ID]protocol.IdentityKey, len(partyIDs))
+	public := make(map[party.ID]protocol.IdentityPublicKey, len(partyIDs))
+	for _, id := range partyIDs {
+		_, sk, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[id] = protocol.Ed25519Identity(sk)
+		public[id] = keys[id].Public()
+	}
+	ids := make(map[party.ID]*protocol.Identities, len(partyIDs))
+	for _, id := range partyIDs {
+		ids[id] = &protocol.Identities{Key: keys[id], Keys: public}
+	}
+	return ids
+}
+
+func TestKeygenComplaint(t *testing.T) {
+	group := curve.Secp256k1{}
+	N := 4
+	partyIDs := test.PartyIDs(N)
+
+	run := func(rule *complaintRule) ([]round.Session, error) {
+		rounds := make([]round.Session, 0, N)
+		for _, partyID := range partyIDs {
+			r, err := StartKeygenCommon(false, group, partyIDs, N-2, partyID)(nil)
+			require.NoError(t, err, "round creation should not result in an error")
+			rounds = append(rounds, r)
+		}
+		for {
+			err, done := test.Rounds(rounds, rule)
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return rounds, nil
+			}
+		}
+	}
+
+	t.Run("false complaint", func(t *testing.T) {
+		rounds, err := run(&complaintRule{accused: partyIDs[0], complainer: partyIDs[1]})
+		require.NoError(t, err)
+		checkOutput(t, group, rounds, partyIDs)
+	})
+
+	t.Run("justified share", func(t *testing.T) {
+		rounds, err := run(&complaintRule{accused: partyIDs[0], complainer: partyIDs[1], invalidShare: true})
+		require.NoError(t, err)
+		checkOutput(t, group, rounds, partyIDs)
+	})
+
+	// the accused sends an invalid share, and reveals another invalid share when justifying it.
+	// This runs over protocol.MultiHandler, which blames the sender of a message failing to be stored.
+	t.Run("invalid justification", func(t *testing.T) {
+		accused, complainer := partyIDs[0], partyIDs[1]
+		one := group.NewScalar().SetNat(new(saferith.Nat).SetUint64(1))
+		invalidShare := byzantine.Attack{
+			Name:  "invalid share",
+			Round: 3,
+			Tamper: func(msg *round.Message, _ party.IDSlice) ([]*round.Message, error) {
+				if msg.To == complainer {
+					msg.Content.(*message3).F_li.Add(one)
+				}
+				return []*round.Message{msg}, nil
+			},
+		}
+		invalidJustification := byzantine.Attack{
+			Name:      "invalid justification",
+			Round:     5,
+			Broadcast: true,
+			Tamper: func(msg *round.Message, _ party.IDSlice) ([]*round.Message, error) {
+				body := msg.Content.(*broadcast5)
+				share := group.NewScalar()
+				if err := share.UnmarshalBinary(body.Shares[complainer]); err != nil {
+					return nil, err
+				}
+				body.Shares[complainer], _ = share.Add(one).MarshalBinary()
+				return []*round.Message{msg}, nil
+			},
+		}
+		e := &byzantine.Execution{
+			PartyIDs: partyIDs,
+			Start: func(id party.ID) protocol.StartFunc {
+				return StartKeygenCommon(false, group, partyIDs, N-2, id)
+			},
+			Attacks:    map[party.ID][]byzantine.Attack{accused: {invalidShare, invalidJustification}},
+			Identities: identities(t, partyIDs),
+		}
+		handlers, err := e.Run()
+		require.NoError(t, err)
+		byzantine.RequireCulprits(t, handlers, e.Honest(), accused)
+		for _, id := range e.Honest() {
+			_, err := handlers[id].Result()
+			assert.Contains(t, err.Error(), "VSS failed to validate justification")
+		}
+	})
+}
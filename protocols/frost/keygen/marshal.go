@@ -0,0 +1,280 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// These assert that our rounds can be saved and restored.
+var (
+	_ round.SessionMarshaler = (*round1)(nil)
+	_ round.SessionMarshaler = (*round2)(nil)
+	_ round.SessionMarshaler = (*round3)(nil)
+	_ round.SessionMarshaler = (*round4)(nil)
+	_ round.SessionMarshaler = (*round5)(nil)
+)
+
+// sessionMarshal contains the state of every round up to Number.
+type sessionMarshal struct {
+	Helper *round.Helper
+	Number round.Number
+
+	// round1
+	Taproot            bool
+	Threshold          int
+	PrivateShare       curve.Scalar
+	VerificationShares *party.PointMap
+	PublicKey          curve.Point
+	ChainKey           types.RID
+	EncryptionKey      curve.Scalar
+	EncryptionKeys     *party.PointMap
+
+	// round2
+	F_i                  *polynomial.Polynomial
+	Phi                  map[party.ID][]byte
+	ChainKeyDecommitment hash.Decommitment
+	ChainKeys            map[party.ID]types.RID
+	ChainKeyCommitments  map[party.ID]hash.Commitment
+
+	// round3
+	ShareFrom  *party.ScalarMap
+	Complaints map[party.ID]bool
+
+	// round4
+	ComplaintsFrom map[party.ID][]party.ID
+
+	// round5
+	Complainers map[party.ID][]party.ID
+}
+
+func (r *round1) save(s *sessionMarshal) error {
+	s.Helper = r.Helper
+	s.Taproot = r.taproot
+	s.Threshold = r.threshold
+	s.PrivateShare = r.privateShare
+	// Identity points can't be marshalled, and are left out instead.
+	verificationShares := make(map[party.ID]curve.Point, len(r.verificationShares))
+	for id, point := range r.verificationShares {
+		if !point.IsIdentity() {
+			verificationShares[id] = point
+		}
+	}
+	s.VerificationShares = party.NewPointMap(verificationShares)
+	if !r.publicKey.IsIdentity() {
+		s.PublicKey = r.publicKey
+	}
+	s.ChainKey = r.chainKey
+	s.EncryptionKey = r.encryptionKey
+	if r.encryptionKeys != nil {
+		s.EncryptionKeys = party.NewPointMap(r.encryptionKeys)
+	}
+	return nil
+}
+
+func (r *round2) save(s *sessionMarshal) error {
+	if err := r.round1.save(s); err != nil {
+		return err
+	}
+	s.F_i = r.f_i
+	s.Phi = make(map[party.ID][]byte, len(r.Phi))
+	for id, phi := range r.Phi {
+		data, err := phi.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		s.Phi[id] = data
+	}
+	s.ChainKeyDecommitment = r.ChainKeyDecommitment
+	s.ChainKeys = r.ChainKeys
+	s.ChainKeyCommitments = r.ChainKeyCommitments
+	return nil
+}
+
+func (r *round3) save(s *sessionMarshal) error {
+	if err := r.round2.save(s); err != nil {
+		return err
+	}
+	s.ShareFrom = party.NewScalarMap(r.shareFrom)
+	s.Complaints = r.complaints
+	return nil
+}
+
+func (r *round4) save(s *sessionMarshal) error {
+	if err := r.round3.save(s); err != nil {
+		return err
+	}
+	s.ComplaintsFrom = r.complaints
+	return nil
+}
+
+func (r *round5) save(s *sessionMarshal) error {
+	if err := r.round4.save(s); err != nil {
+		return err
+	}
+	s.Complainers = r.complainers
+	return nil
+}
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round1) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round2) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round3) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round4) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+// MarshalSession implements round.SessionMarshaler.
+func (r *round5) MarshalSession() ([]byte, error) { return marshalSession(r) }
+
+func marshalSession(r interface {
+	round.Session
+	save(*sessionMarshal) error
+}) ([]byte, error) {
+	s := &sessionMarshal{Number: r.Number()}
+	if err := r.save(s); err != nil {
+		return nil, fmt.Errorf("keygen: %w", err)
+	}
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	return enc.Marshal(s)
+}
+
+// Restore recreates a round saved with MarshalSession, and implements protocol.RestoreFunc.
+func Restore(data []byte) (round.Session, error) {
+	// The group is only known once the Helper has been unmarshalled.
+	raw := &struct{ Helper *round.Helper }{}
+	if err := cbor.Unmarshal(data, raw); err != nil {
+		return nil, fmt.Errorf("keygen.Restore: %w", err)
+	}
+	if raw.Helper == nil || raw.Helper.Group() == nil {
+		return nil, errors.New("keygen.Restore: missing session")
+	}
+	group := raw.Helper.Group()
+	s := &sessionMarshal{
+		PrivateShare:       group.NewScalar(),
+		VerificationShares: party.EmptyPointMap(group),
+		PublicKey:          group.NewPoint(),
+		EncryptionKey:      group.NewScalar(),
+		EncryptionKeys:     party.EmptyPointMap(group),
+		F_i:                polynomial.EmptyPolynomial(group),
+		ShareFrom:          party.EmptyScalarMap(group),
+	}
+	if err := cbor.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("keygen.Restore: %w", err)
+	}
+	// Missing optional fields keep their initial value when unmarshalling, so we reset them
+	// depending on the variant of the protocol.
+	switch s.Helper.ProtocolID() {
+	case protocolIDDefault, protocolIDTaproot:
+		s.EncryptionKey, s.EncryptionKeys = nil, nil
+	case protocolIDRefreshDefault, protocolIDRefreshTaproot:
+		s.EncryptionKey, s.EncryptionKeys = nil, nil
+		if s.PublicKey.IsIdentity() || s.ChainKey == nil {
+			return nil, round.ErrNilFields
+		}
+	case protocolIDDefaultPVSS, protocolIDTaprootPVSS:
+		if s.EncryptionKey.IsZero() || s.EncryptionKeys == nil {
+			return nil, round.ErrNilFields
+		}
+	default:
+		return nil, fmt.Errorf("keygen.Restore: unexpected protocol %s", s.Helper.ProtocolID())
+	}
+	if s.Number < 1 || s.Number > protocolRounds {
+		return nil, fmt.Errorf("keygen.Restore: invalid round %d", s.Number)
+	}
+	if s.VerificationShares == nil {
+		return nil, round.ErrNilFields
+	}
+	for _, id := range s.Helper.PartyIDs() {
+		if _, ok := s.VerificationShares.Points[id]; !ok {
+			s.VerificationShares.Points[id] = group.NewPoint()
+		}
+	}
+
+	r1 := &round1{
+		Helper:             s.Helper,
+		taproot:            s.Taproot,
+		threshold:          s.Threshold,
+		privateShare:       s.PrivateShare,
+		verificationShares: s.VerificationShares.Points,
+		publicKey:          s.PublicKey,
+		chainKey:           s.ChainKey,
+		encryptionKey:      s.EncryptionKey,
+	}
+	if s.EncryptionKeys != nil {
+		r1.encryptionKeys = s.EncryptionKeys.Points
+	}
+	if s.Number == 1 {
+		return r1, nil
+	}
+
+	if s.F_i == nil {
+		return nil, round.ErrNilFields
+	}
+	r2 := &round2{
+		round1:               r1,
+		f_i:                  s.F_i,
+		Phi:                  make(map[party.ID]*polynomial.Exponent, len(s.Phi)),
+		ChainKeyDecommitment: s.ChainKeyDecommitment,
+		ChainKeys:            s.ChainKeys,
+		ChainKeyCommitments:  s.ChainKeyCommitments,
+	}
+	for id, data := range s.Phi {
+		phi := polynomial.EmptyExponent(group)
+		if err := phi.UnmarshalBinary(data); err != nil {
+			return nil, fmt.Errorf("keygen.Restore: %w", err)
+		}
+		r2.Phi[id] = phi
+	}
+	if r2.ChainKeys == nil {
+		r2.ChainKeys = make(map[party.ID]types.RID)
+	}
+	if r2.ChainKeyCommitments == nil {
+		r2.ChainKeyCommitments = make(map[party.ID]hash.Commitment)
+	}
+	if s.Number == 2 {
+		return r2, nil
+	}
+
+	if s.ShareFrom == nil {
+		return nil, round.ErrNilFields
+	}
+	r3 := &round3{
+		round2:     r2,
+		shareFrom:  s.ShareFrom.Scalars,
+		complaints: s.Complaints,
+	}
+	if r3.complaints == nil {
+		r3.complaints = make(map[party.ID]bool)
+	}
+	if s.Number == 3 {
+		return r3, nil
+	}
+
+	r4 := &round4{
+		round3:     r3,
+		complaints: s.ComplaintsFrom,
+	}
+	if r4.complaints == nil {
+		r4.complaints = make(map[party.ID][]party.ID)
+	}
+	if s.Number == 4 {
+		return r4, nil
+	}
+
+	return &round5{
+		round4:      r4,
+		complainers: s.Complainers,
+	}, nil
+}
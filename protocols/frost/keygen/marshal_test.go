@@ -0,0 +1,130 @@
+package keygen
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restoreRounds replaces every round by the result of restoring it.
+func restoreRounds(t *testing.T, rounds []round.Session) {
+	for i, r := range rounds {
+		if _, ok := r.(*round.Output); ok {
+			continue
+		}
+		require.Implements(t, (*round.SessionMarshaler)(nil), r)
+		data, err := r.(round.SessionMarshaler).MarshalSession()
+		require.NoError(t, err)
+		restored, err := Restore(data)
+		require.NoError(t, err)
+		require.IsType(t, r, restored)
+		assert.Equal(t, r.SSID(), restored.SSID())
+		assert.Equal(t, r.Hash().Sum(), restored.Hash().Sum())
+		rounds[i] = restored
+	}
+}
+
+func TestRestore(t *testing.T) {
+	N := 4
+	partyIDs := test.PartyIDs(N)
+
+	for _, group := range []curve.Curve{curve.Secp256k1{}, curve.Edwards25519{}} {
+		rounds := make([]round.Session, 0, N)
+		for _, partyID := range partyIDs {
+			r, err := StartKeygenCommon(false, group, partyIDs, N-2, partyID)([]byte("restore"))
+			require.NoError(t, err)
+			rounds = append(rounds, r)
+		}
+		// A false complaint goes through every round of the protocol.
+		rule := &complaintRule{accused: partyIDs[0], complainer: partyIDs[1]}
+		for {
+			restoreRounds(t, rounds)
+			err, done := test.Rounds(rounds, rule)
+			require.NoError(t, err, "failed to process round")
+			if done {
+				break
+			}
+		}
+		checkOutput(t, group, rounds, partyIDs)
+	}
+
+	// The PVSS variant also saves the encryption keys.
+	group := curve.Secp256k1{}
+	partyIDs = partyIDs[:3]
+	encryptionKeys := make(map[party.ID]curve.Point, len(partyIDs))
+	decryptionKeys := make(map[party.ID]curve.Scalar, len(partyIDs))
+	for _, id := range partyIDs {
+		decryptionKeys[id] = sample.Scalar(rand.Reader, group)
+		encryptionKeys[id] = decryptionKeys[id].ActOnBase()
+	}
+	rounds := make([]round.Session, 0, len(partyIDs))
+	for _, partyID := range partyIDs {
+		r, err := StartKeygenPVSS(false, group, partyIDs, 1, partyID, decryptionKeys[partyID], encryptionKeys)(nil)
+		require.NoError(t, err)
+		rounds = append(rounds, r)
+	}
+	for {
+		restoreRounds(t, rounds)
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	checkOutput(t, group, rounds, partyIDs)
+}
+
+func TestRestoreHandler(t *testing.T) {
+	group := curve.Secp256k1{}
+	N := 3
+	partyIDs := test.PartyIDs(N)
+
+	var mtx sync.Mutex
+	results := make(map[party.ID]*Config, N)
+	network := test.NewNetwork(partyIDs)
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(StartKeygenCommon(false, group, partyIDs, 1, id), nil)
+			require.NoError(t, err)
+			h, err = test.RestoringHandlerLoop(id, h, network, Restore)
+			require.NoError(t, err)
+			result, err := h.Result()
+			require.NoError(t, err)
+			require.IsType(t, &Config{}, result)
+			mtx.Lock()
+			results[id] = result.(*Config)
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	for _, id := range partyIDs {
+		assert.True(t, results[partyIDs[0]].PublicKey.Equal(results[id].PublicKey))
+		assert.True(t, results[id].PrivateShare.ActOnBase().Equal(results[id].VerificationShares.Points[id]))
+	}
+
+	// Snapshots can't be decrypted with another key.
+	h, err := protocol.NewMultiHandler(StartKeygenCommon(false, group, partyIDs, 1, partyIDs[0]), nil)
+	require.NoError(t, err)
+	for len(h.Listen()) > 0 {
+		<-h.Listen()
+	}
+	key := make([]byte, protocol.SnapshotKeySize)
+	data, err := h.Snapshot(key)
+	require.NoError(t, err)
+	key[0] = 1
+	_, err = protocol.RestoreMultiHandler(Restore, data, key)
+	assert.Error(t, err)
+}
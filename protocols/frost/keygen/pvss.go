@@ -0,0 +1,216 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/pkg/pvss"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// encryptionKeys maps each participant to the static public key their shares are encrypted to.
+type encryptionKeys map[party.ID]curve.Point
+
+func newEncryptionKeys(group curve.Curve, participants []party.ID, keys map[party.ID]curve.Point) (encryptionKeys, error) {
+	out := make(encryptionKeys, len(participants))
+	for _, id := range participants {
+		D, ok := keys[id]
+		if !ok || D == nil || D.IsIdentity() {
+			return nil, fmt.Errorf("missing encryption key for party %s", id)
+		}
+		if D.Curve().Name() != group.Name() {
+			return nil, fmt.Errorf("invalid encryption key for party %s", id)
+		}
+		out[id] = D
+	}
+	return out, nil
+}
+
+// WriteTo implements io.WriterTo and should be used within the hash.Hash function.
+func (keys encryptionKeys) WriteTo(w io.Writer) (int64, error) {
+	ids := make([]party.ID, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	total := int64(0)
+	for _, id := range party.NewIDSlice(ids) {
+		n, err := id.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		data, err := keys[id].MarshalBinary()
+		if err != nil {
+			return total, err
+		}
+		m, err := w.Write(data)
+		total += int64(m)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Domain implements hash.WriterToWithDomain, and separates this type within hash.Hash.
+func (encryptionKeys) Domain() string {
+	return "Encryption Keys"
+}
+
+// shareHash returns the hash the encryption of the share from one party to another is bound to.
+func (r *round1) shareHash(from, to party.ID) *hash.Hash {
+	h := r.HashForID(from)
+	_ = h.WriteAny(to)
+	return h
+}
+
+// encryptedShares is a map from recipients to encrypted shares, to be easy to marshal.
+type encryptedShares struct {
+	group  curve.Curve
+	Shares map[party.ID]*pvss.Ciphertext
+}
+
+func emptyEncryptedShares(group curve.Curve) *encryptedShares {
+	return &encryptedShares{group: group}
+}
+
+func (s *encryptedShares) MarshalBinary() ([]byte, error) {
+	enc, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+	shareBytes := make(map[party.ID]cbor.RawMessage, len(s.Shares))
+	for k, v := range s.Shares {
+		shareBytes[k], err = enc.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return enc.Marshal(shareBytes)
+}
+
+func (s *encryptedShares) UnmarshalBinary(data []byte) error {
+	if s.group == nil {
+		return errors.New("encryptedShares.UnmarshalBinary called without setting a group")
+	}
+	shareBytes := make(map[party.ID]cbor.RawMessage)
+	if err := cbor.Unmarshal(data, &shareBytes); err != nil {
+		return err
+	}
+	s.Shares = make(map[party.ID]*pvss.Ciphertext, len(shareBytes))
+	for k, v := range shareBytes {
+		ciphertext := pvss.EmptyCiphertext(s.group)
+		if err := cbor.Unmarshal(v, ciphertext); err != nil {
+			return err
+		}
+		s.Shares[k] = ciphertext
+	}
+	return nil
+}
+
+// PublicConfig is the public part of the Config of every participant, as computed by an auditor.
+type PublicConfig struct {
+	// Threshold is the number of accepted corruptions while still being able to sign.
+	Threshold int
+	// PublicKey is the shared public key for this consortium of signers.
+	//
+	// For Taproot keys, this is the point with even y coordinate.
+	PublicKey curve.Point
+	// ChainKey is the additional randomness agreed upon.
+	ChainKey []byte
+	// VerificationShares is a map between parties and a commitment to their private share.
+	VerificationShares *party.PointMap
+}
+
+// AuditPVSS checks the public transcript of a key generation started with StartKeygenPVSS,
+// and returns the public key it produced.
+//
+// messages should contain the broadcast messages sent by all participants, as published on the
+// public log. Every check done by the participants is repeated, including the verification of
+// each encrypted share, so that a successful audit guarantees that every participant received
+// a valid share of the public key.
+//
+// keys contains the identity public key of every one of the participants, as given to protocol.WithIdentities:
+// only the messages they signed are taken into account.
+func AuditPVSS(taproot bool, group curve.Curve, participants []party.ID, threshold int, encryptionKeys map[party.ID]curve.Point, sessionID []byte, keys map[party.ID]protocol.IdentityPublicKey, messages []*protocol.Message) (*PublicConfig, error) {
+	if len(participants) == 0 {
+		return nil, errors.New("keygen.AuditPVSS: no participants")
+	}
+	recipients, err := newEncryptionKeys(group, participants, encryptionKeys)
+	if err != nil {
+		return nil, fmt.Errorf("keygen.AuditPVSS: %w", err)
+	}
+	// The auditor isn't a participant, so we impersonate one without an encryption key,
+	// which skips decryption while checking everything else.
+	session, err := startKeygen(taproot, group, participants, threshold, participants[0], nil, recipients, nil, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("keygen.AuditPVSS: %w", err)
+	}
+	r1 := session.(*round1)
+
+	r2 := &round2{
+		round1:              r1,
+		Phi:                 make(map[party.ID]*polynomial.Exponent, len(participants)),
+		ChainKeys:           make(map[party.ID]types.RID, len(participants)),
+		ChainKeyCommitments: make(map[party.ID]hash.Commitment, len(participants)),
+	}
+	if err = protocol.ReplayBroadcasts(r2, keys, messages); err != nil {
+		return nil, err
+	}
+	r3 := &round3{
+		round2:     r2,
+		shareFrom:  make(map[party.ID]curve.Scalar),
+		complaints: make(map[party.ID]bool),
+	}
+	if err = protocol.ReplayBroadcasts(r3, keys, messages); err != nil {
+		return nil, err
+	}
+
+	next, err := r3.Finalize(nil)
+	if err != nil {
+		return nil, fmt.Errorf("keygen.AuditPVSS: %w", err)
+	}
+	config, err := publicConfig(next)
+	if err != nil {
+		return nil, fmt.Errorf("keygen.AuditPVSS: %w", err)
+	}
+	return config, nil
+}
+
+// publicConfig returns the public part of the output of the final round of the protocol.
+func publicConfig(final round.Session) (*PublicConfig, error) {
+	output, ok := final.(*round.Output)
+	if !ok {
+		return nil, fmt.Errorf("unexpected round %T", final)
+	}
+	switch result := output.Result.(type) {
+	case *Config:
+		return &PublicConfig{
+			Threshold:          result.Threshold,
+			PublicKey:          result.PublicKey,
+			ChainKey:           result.ChainKey,
+			VerificationShares: result.VerificationShares,
+		}, nil
+	case *TaprootConfig:
+		publicKey, err := curve.Secp256k1{}.LiftX(result.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &PublicConfig{
+			Threshold:          result.Threshold,
+			PublicKey:          publicKey,
+			ChainKey:           result.ChainKey,
+			VerificationShares: party.NewPointMap(result.VerificationShares),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unexpected result %T", result)
+	}
+}
@@ -0,0 +1,151 @@
+package keygen
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runPVSS runs the key generation with authenticated handlers, and records every message sent on a public log.
+func runPVSS(t *testing.T, taproot bool, group curve.Curve, partyIDs party.IDSlice, threshold int, sessionID []byte) (map[party.ID]interface{}, map[party.ID]curve.Point, map[party.ID]*protocol.Identities, []*protocol.Message) {
+	ids := identities(t, partyIDs)
+	encryptionKeys := make(map[party.ID]curve.Point, len(partyIDs))
+	decryptionKeys := make(map[party.ID]curve.Scalar, len(partyIDs))
+	for _, id := range partyIDs {
+		decryptionKeys[id] = sample.Scalar(rand.Reader, group)
+		encryptionKeys[id] = decryptionKeys[id].ActOnBase()
+	}
+
+	var mtx sync.Mutex
+	var log []*protocol.Message
+	results := make(map[party.ID]interface{}, len(partyIDs))
+
+	network := test.NewNetwork(partyIDs)
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(StartKeygenPVSS(taproot, group, partyIDs, threshold, id, decryptionKeys[id], encryptionKeys), sessionID, protocol.WithIdentities(ids[id]))
+			require.NoError(t, err)
+			for {
+				select {
+				case msg, ok := <-h.Listen():
+					if !ok {
+						<-network.Done(id)
+						result, err := h.Result()
+						require.NoError(t, err)
+						mtx.Lock()
+						results[id] = result
+						mtx.Unlock()
+						return
+					}
+					mtx.Lock()
+					log = append(log, msg)
+					mtx.Unlock()
+					go network.Send(msg)
+				case msg := <-network.Next(id):
+					h.Accept(msg)
+				}
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	return results, encryptionKeys, ids, log
+}
+
+func TestKeygenPVSS(t *testing.T) {
+	group := curve.Secp256k1{}
+	N := 3
+	threshold := 1
+	partyIDs := test.PartyIDs(N)
+	sessionID := []byte("pvss keygen")
+
+	results, encryptionKeys, ids, log := runPVSS(t, false, group, partyIDs, threshold, sessionID)
+	keys := ids[partyIDs[0]].Keys
+
+	for _, msg := range log {
+		assert.True(t, msg.Broadcast, "expected only broadcast messages")
+	}
+
+	audited, err := AuditPVSS(false, group, partyIDs, threshold, encryptionKeys, sessionID, keys, log)
+	require.NoError(t, err)
+
+	privateKey := group.NewScalar()
+	lagrangeCoefficients := polynomial.Lagrange(group, partyIDs)
+	for _, id := range partyIDs {
+		require.IsType(t, &Config{}, results[id])
+		config := results[id].(*Config)
+		assert.True(t, audited.PublicKey.Equal(config.PublicKey), "different public key")
+		assert.Equal(t, audited.ChainKey, config.ChainKey, "different chain key")
+		assert.True(t, audited.VerificationShares.Points[id].Equal(config.PrivateShare.ActOnBase()), "different verification share")
+		privateKey.Add(group.NewScalar().Set(lagrangeCoefficients[id]).Mul(config.PrivateShare))
+	}
+	assert.True(t, privateKey.ActOnBase().Equal(audited.PublicKey))
+
+	// Swapping two encrypted shares makes the sender culpable.
+	for i, msg := range log {
+		if msg.RoundNumber != 3 || msg.From != partyIDs[0] {
+			continue
+		}
+		body := &broadcast3{Shares: emptyEncryptedShares(group)}
+		require.NoError(t, cbor.Unmarshal(msg.Data, body))
+		body.Shares.Shares[partyIDs[1]], body.Shares.Shares[partyIDs[2]] = body.Shares.Shares[partyIDs[2]], body.Shares.Shares[partyIDs[1]]
+		tampered := *msg
+		tampered.Data, err = cbor.Marshal(body)
+		require.NoError(t, err)
+		tampered.Signature, err = ids[partyIDs[0]].Key.Sign(tampered.Hash())
+		require.NoError(t, err)
+		tamperedLog := append([]*protocol.Message{}, log...)
+		tamperedLog[i] = &tampered
+
+		_, err = AuditPVSS(false, group, partyIDs, threshold, encryptionKeys, sessionID, keys, tamperedLog)
+		require.Error(t, err)
+		require.IsType(t, protocol.Error{}, err)
+		assert.Equal(t, []party.ID{partyIDs[0]}, err.(protocol.Error).Culprits)
+
+		// Without the signature of the sender, the message is ignored, and the sender's message is missing.
+		tampered.Signature = nil
+		_, err = AuditPVSS(false, group, partyIDs, threshold, encryptionKeys, sessionID, keys, tamperedLog)
+		require.Error(t, err)
+		require.IsType(t, protocol.Error{}, err)
+		assert.Equal(t, []party.ID{partyIDs[0]}, err.(protocol.Error).Culprits)
+		assert.Contains(t, err.Error(), "missing broadcast messages")
+	}
+
+	// Auditing with another session ID finds no messages.
+	_, err = AuditPVSS(false, group, partyIDs, threshold, encryptionKeys, []byte("other"), keys, log)
+	assert.Error(t, err)
+}
+
+func TestKeygenPVSSTaproot(t *testing.T) {
+	group := curve.Secp256k1{}
+	N := 3
+	threshold := 1
+	partyIDs := test.PartyIDs(N)
+
+	results, encryptionKeys, ids, log := runPVSS(t, true, group, partyIDs, threshold, nil)
+	keys := ids[partyIDs[0]].Keys
+
+	audited, err := AuditPVSS(true, group, partyIDs, threshold, encryptionKeys, nil, keys, log)
+	require.NoError(t, err)
+	require.True(t, audited.PublicKey.HasEvenY())
+
+	for _, id := range partyIDs {
+		require.IsType(t, &TaprootConfig{}, results[id])
+		config := results[id].(*TaprootConfig)
+		assert.EqualValues(t, audited.PublicKey.XScalar().Bytes(), config.PublicKey, "different public key")
+		assert.True(t, audited.VerificationShares.Points[id].Equal(config.PrivateShare.ActOnBase()), "different verification share")
+	}
+}
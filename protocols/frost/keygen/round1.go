@@ -42,6 +42,14 @@ type round1 struct {
 	verificationShares map[party.ID]curve.Point
 	// publicKey should be the previous public key when refreshing, and 0 otherwise.
 	publicKey curve.Point
+	// chainKey is the previous chain key when refreshing, and nil otherwise.
+	chainKey types.RID
+
+	// encryptionKey is our static private key, when running over a public channel.
+	encryptionKey curve.Scalar
+	// encryptionKeys are the static public keys shares are encrypted to, or nil when shares
+	// are sent over private channels.
+	encryptionKeys encryptionKeys
 }
 
 // VerifyMessage implements round.Round.
@@ -67,8 +75,13 @@ func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
 	// Note: I've adjusted the thresholds in this quote to reflect our convention
 	// that t + 1 participants are needed to create a signature.
 
-	a_i0 := sample.Scalar(rand.Reader, r.Group())
-	a_i0_times_G := a_i0.ActOnBase()
+	//
+	// When refreshing, we set aᵢ₀ = 0 instead, so that the secret doesn't change.
+
+	a_i0 := r.Group().NewScalar()
+	if !r.refresh() {
+		a_i0 = sample.Scalar(rand.Reader, r.Group())
+	}
 	f_i := polynomial.NewPolynomial(r.Group(), r.threshold, a_i0)
 
 	// 2. "Every Pᵢ computes a proof of knowledge to the corresponding secret aᵢ₀
@@ -86,7 +99,13 @@ func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
 	// At this point, we've already hashed context inside of helper, so we just
 	// add in our own ID, and then we're good to go.
 
-	Sigma_i := zksch.NewProof(r.Helper.HashForID(r.SelfID()), a_i0_times_G, a_i0, nil)
+	//
+	// There is nothing to prove when refreshing.
+
+	var Sigma_i *zksch.Proof
+	if !r.refresh() {
+		Sigma_i = zksch.NewProof(r.Helper.HashForID(r.SelfID()), a_i0.ActOnBase(), a_i0, nil)
+	}
 
 	// 3. "Every participant Pᵢ computes a public comment Φᵢ = <ϕᵢ₀, ..., ϕᵢₜ>
 	// where ϕᵢⱼ = aᵢⱼ * G."
@@ -129,6 +148,11 @@ func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
 	}, nil
 }
 
+// refresh returns true if this session refreshes the shares of an existing key.
+func (r *round1) refresh() bool {
+	return !r.publicKey.IsIdentity()
+}
+
 // MessageContent implements round.Round.
 func (round1) MessageContent() round.Content { return nil }
 
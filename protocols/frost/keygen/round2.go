@@ -1,6 +1,7 @@
 package keygen
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
@@ -9,6 +10,7 @@ import (
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/pvss"
 	sch "github.com/MixinNetwork/multi-party-sig/pkg/zk/sch"
 )
 
@@ -54,9 +56,13 @@ func (r *round2) StoreBroadcastMessage(msg round.Message) error {
 	}
 
 	// check nil
-	if !body.Sigma_i.IsValid() || body.Phi_i == nil {
+	if body.Phi_i == nil || (!r.refresh() && !body.Sigma_i.IsValid()) {
 		return round.ErrNilFields
 	}
+	// the constant term must be the identity exactly when refreshing
+	if body.Phi_i.IsConstant != r.refresh() {
+		return errors.New("polynomial has incorrect constant")
+	}
 
 	if err := body.Commitment.Validate(); err != nil {
 		return fmt.Errorf("commitment: %w", err)
@@ -76,8 +82,12 @@ func (r *round2) StoreBroadcastMessage(msg round.Message) error {
 	// To see why this is correct, compare this verification with the proof we
 	// produced in the previous round. Note how we do the same hash cloning,
 	// but this time with the ID of the message sender.
+	//
+	// When refreshing, we checked above that ϕₗ₀ is the identity, so there is no
+	// secret whose knowledge must be proven: the proof only prevents a party from
+	// choosing its contribution to the public key as a function of the others.
 
-	if !body.Sigma_i.Verify(r.Helper.HashForID(from), body.Phi_i.Constant(), nil) {
+	if !r.refresh() && !body.Sigma_i.Verify(r.Helper.HashForID(from), body.Phi_i.Constant(), nil) {
 		return fmt.Errorf("failed to verify Schnorr proof for party %s", from)
 	}
 
@@ -98,26 +108,45 @@ func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
 	// 1. "Each P_i securely sends to each other participant Pₗ a secret share
 	// (l, fᵢ(l)), deleting f_i and each share afterward except for (i, fᵢ(i)),
 	// which they keep for themselves."
+	//
+	// Without private channels, the shares are instead encrypted to each Pₗ,
+	// and broadcast along with the chain key decommitment.
+
+	var shares *encryptedShares
+	if r.encryptionKeys != nil {
+		shares = &encryptedShares{group: r.Group(), Shares: make(map[party.ID]*pvss.Ciphertext, r.N()-1)}
+		for _, l := range r.OtherPartyIDs() {
+			ciphertext, err := pvss.Encrypt(r.shareHash(r.SelfID(), l), r.encryptionKeys[l], r.f_i.Evaluate(l.Scalar(r.Group())))
+			if err != nil {
+				return r, err
+			}
+			shares.Shares[l] = ciphertext
+		}
+	}
 
 	if err := r.BroadcastMessage(out, &broadcast3{
 		C_l:          r.ChainKeys[r.SelfID()],
 		Decommitment: r.ChainKeyDecommitment,
+		Shares:       shares,
 	}); err != nil {
 		return r, err
 	}
 
-	for _, l := range r.OtherPartyIDs() {
-		if err := r.SendMessage(out, &message3{
-			F_li: r.f_i.Evaluate(l.Scalar(r.Group())),
-		}, l); err != nil {
-			return r, err
+	if shares == nil {
+		for _, l := range r.OtherPartyIDs() {
+			if err := r.SendMessage(out, &message3{
+				F_li: r.f_i.Evaluate(l.Scalar(r.Group())),
+			}, l); err != nil {
+				return r, err
+			}
 		}
 	}
 
 	selfShare := r.f_i.Evaluate(r.SelfID().Scalar(r.Group()))
 	return &round3{
-		round2:    r,
-		shareFrom: map[party.ID]curve.Scalar{r.SelfID(): selfShare},
+		round2:     r,
+		shareFrom:  map[party.ID]curve.Scalar{r.SelfID(): selfShare},
+		complaints: make(map[party.ID]bool),
 	}, nil
 }
 
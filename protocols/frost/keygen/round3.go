@@ -21,6 +21,9 @@ type round3 struct {
 	//
 	// shareFrom[l] corresponds to fₗ(i) in the Frost paper, with i our own ID.
 	shareFrom map[party.ID]curve.Scalar
+
+	// complaints contains the parties who sent us a share which failed the VSS condition.
+	complaints map[party.ID]bool
 }
 
 type message3 struct {
@@ -34,6 +37,8 @@ type broadcast3 struct {
 	C_l types.RID
 	// Decommitment = uᵢ decommitment bytes
 	Decommitment hash.Decommitment
+	// Shares holds the encrypted shares for all other parties, when running without private channels.
+	Shares *encryptedShares `cbor:",omitempty"`
 }
 
 // StoreBroadcastMessage implements round.BroadcastRound.
@@ -53,10 +58,49 @@ func (r *round3) StoreBroadcastMessage(msg round.Message) error {
 	if !r.HashForID(from).Decommit(r.ChainKeyCommitments[from], body.Decommitment, body.C_l) {
 		return fmt.Errorf("failed to verify chain key commitment")
 	}
+
+	if r.encryptionKeys != nil {
+		if err := r.storeEncryptedShares(from, body.Shares); err != nil {
+			return err
+		}
+	}
 	r.ChainKeys[from] = body.C_l
 	return nil
 }
 
+// storeEncryptedShares verifies the encryption of every share sent by a party, and decrypts our own.
+//
+// Checking the shares of the other parties isn't needed for our own output, but ensures that
+// every participant reaches the same conclusion as an outside auditor would.
+func (r *round3) storeEncryptedShares(from party.ID, shares *encryptedShares) error {
+	if shares == nil || len(shares.Shares) != r.N()-1 {
+		return round.ErrNilFields
+	}
+	for _, l := range r.PartyIDs() {
+		if l == from {
+			continue
+		}
+		ciphertext := shares.Shares[l]
+		if ciphertext == nil {
+			return fmt.Errorf("missing encrypted share for party %s", l)
+		}
+		// This is the same VSS condition as in StoreMessage, but anybody can check it.
+		expected := r.Phi[from].Evaluate(l.Scalar(r.Group()))
+		if !ciphertext.Verify(r.shareHash(from, l), r.encryptionKeys[l], expected) {
+			return fmt.Errorf("failed to verify encrypted share for party %s", l)
+		}
+		if l != r.SelfID() || r.encryptionKey == nil {
+			continue
+		}
+		share, err := ciphertext.Decrypt(r.encryptionKey)
+		if err != nil {
+			return err
+		}
+		r.shareFrom[from] = share
+	}
+	return nil
+}
+
 // VerifyMessage implements round.Round.
 func (r *round3) VerifyMessage(msg round.Message) error {
 	body, ok := msg.Content.(*message3)
@@ -75,6 +119,9 @@ func (r *round3) VerifyMessage(msg round.Message) error {
 // StoreMessage implements round.Round.
 //
 // Verify the VSS condition here since we will not be sending this message to other parties for verification.
+//
+// Since nobody else can check this message, a failure doesn't abort the protocol immediately.
+// Instead, we complain about the sender in the next round, and the sender has to justify the share publicly.
 func (r *round3) StoreMessage(msg round.Message) error {
 	from, body := msg.From, msg.Content.(*message3)
 
@@ -88,7 +135,8 @@ func (r *round3) StoreMessage(msg round.Message) error {
 	expected := body.F_li.ActOnBase()
 	actual := r.Phi[from].Evaluate(r.SelfID().Scalar(r.Group()))
 	if !expected.Equal(actual) {
-		return fmt.Errorf("VSS failed to validate")
+		r.complaints[from] = true
+		return nil
 	}
 
 	r.shareFrom[from] = body.F_li
@@ -97,10 +145,38 @@ func (r *round3) StoreMessage(msg round.Message) error {
 }
 
 // Finalize implements round.Round.
-func (r *round3) Finalize(chan<- *round.Message) (round.Session, error) {
-	chainKey := types.EmptyRID()
-	for _, j := range r.PartyIDs() {
-		chainKey.XOR(r.ChainKeys[j])
+//
+// Without private channels, every share has already been verified publicly, and we are done.
+// Otherwise, we broadcast our complaints, which may be empty.
+func (r *round3) Finalize(out chan<- *round.Message) (round.Session, error) {
+	if r.encryptionKeys != nil {
+		return r.finish(), nil
+	}
+
+	complaints := make([]party.ID, 0, len(r.complaints))
+	for _, j := range r.OtherPartyIDs() {
+		if r.complaints[j] {
+			complaints = append(complaints, j)
+		}
+	}
+	if err := r.BroadcastMessage(out, &broadcast4{Complaints: complaints}); err != nil {
+		return r, err
+	}
+	return &round4{
+		round3:     r,
+		complaints: map[party.ID][]party.ID{r.SelfID(): complaints},
+	}, nil
+}
+
+// finish computes the output of the protocol, once we hold a valid share from every party.
+func (r *round3) finish() round.Session {
+	// A refresh keeps the previous chain key, so that derived keys don't change.
+	chainKey := r.chainKey
+	if chainKey == nil {
+		chainKey = types.EmptyRID()
+		for _, j := range r.PartyIDs() {
+			chainKey.XOR(r.ChainKeys[j])
+		}
 	}
 
 	// These steps come from Figure 1, Round 2 of the Frost paper
@@ -165,7 +241,7 @@ func (r *round3) Finalize(chan<- *round.Message) (round.Session, error) {
 			PublicKey:          YSecp.XScalar().Bytes(),
 			ChainKey:           chainKey,
 			VerificationShares: secpVerificationShares,
-		}), nil
+		})
 	}
 
 	return r.ResultRound(&Config{
@@ -175,14 +251,19 @@ func (r *round3) Finalize(chan<- *round.Message) (round.Session, error) {
 		PublicKey:          r.publicKey,
 		ChainKey:           chainKey,
 		VerificationShares: party.NewPointMap(r.verificationShares),
-	}), nil
+	})
 }
 
 // RoundNumber implements round.Content.
 func (message3) RoundNumber() round.Number { return 3 }
 
 // MessageContent implements round.Round.
+//
+// When shares are encrypted and broadcast, no direct message is expected.
 func (r *round3) MessageContent() round.Content {
+	if r.encryptionKeys != nil {
+		return nil
+	}
 	return &message3{
 		F_li: r.Group().NewScalar(),
 	}
@@ -192,7 +273,12 @@ func (r *round3) MessageContent() round.Content {
 func (broadcast3) RoundNumber() round.Number { return 3 }
 
 // BroadcastContent implements round.BroadcastRound.
-func (r *round3) BroadcastContent() round.BroadcastContent { return &broadcast3{} }
+func (r *round3) BroadcastContent() round.BroadcastContent {
+	if r.encryptionKeys != nil {
+		return &broadcast3{Shares: emptyEncryptedShares(r.Group())}
+	}
+	return &broadcast3{}
+}
 
 // Number implements round.Round.
 func (round3) Number() round.Number { return 3 }
@@ -0,0 +1,104 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+)
+
+// This round is an addition to the Frost paper, and corresponds to the complaint phase
+// of Pedersen's VSS, as used in the DKG of Gennaro, Jarecki, Krawczyk and Rabin:
+//
+//	https://link.springer.com/article/10.1007/s00145-006-0347-3
+type round4 struct {
+	*round3
+
+	// complaints[j] contains the parties who party j complained about, ourselves included.
+	complaints map[party.ID][]party.ID
+}
+
+type broadcast4 struct {
+	round.ReliableBroadcastContent
+	// Complaints contains the parties who sent an invalid share to this party.
+	Complaints []party.ID
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+func (r *round4) StoreBroadcastMessage(msg round.Message) error {
+	from := msg.From
+	body, ok := msg.Content.(*broadcast4)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+
+	accused := make(map[party.ID]bool, len(body.Complaints))
+	for _, j := range body.Complaints {
+		if j == from || !r.PartyIDs().Contains(j) {
+			return fmt.Errorf("invalid complaint against party %s", j)
+		}
+		if accused[j] {
+			return errors.New("duplicate complaint")
+		}
+		accused[j] = true
+	}
+	r.complaints[from] = body.Complaints
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round4) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round4) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round.
+//
+// If nobody complained, we are done. Otherwise, we publicly reveal the shares we sent to
+// the parties who complained about us, which may be none.
+func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
+	complainers := r.complainers()
+	if len(complainers) == 0 {
+		return r.finish(), nil
+	}
+
+	justifications := make(map[party.ID][]byte, len(complainers[r.SelfID()]))
+	for _, l := range complainers[r.SelfID()] {
+		share, err := r.f_i.Evaluate(l.Scalar(r.Group())).MarshalBinary()
+		if err != nil {
+			return r, err
+		}
+		justifications[l] = share
+	}
+	if err := r.BroadcastMessage(out, &broadcast5{Shares: justifications}); err != nil {
+		return r, err
+	}
+	return &round5{
+		round4:      r,
+		complainers: complainers,
+	}, nil
+}
+
+// complainers returns the parties who complained about each party, indexed by the accused party.
+func (r *round4) complainers() map[party.ID][]party.ID {
+	complainers := make(map[party.ID][]party.ID)
+	for _, l := range r.PartyIDs() {
+		for _, j := range r.complaints[l] {
+			complainers[j] = append(complainers[j], l)
+		}
+	}
+	return complainers
+}
+
+// MessageContent implements round.Round.
+func (round4) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast4) RoundNumber() round.Number { return 4 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (round4) BroadcastContent() round.BroadcastContent { return &broadcast4{} }
+
+// Number implements round.Round.
+func (round4) Number() round.Number { return 4 }
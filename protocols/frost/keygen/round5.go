@@ -0,0 +1,91 @@
+package keygen
+
+import (
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+)
+
+// This round corresponds to the justification phase of Pedersen's VSS, and only happens
+// if some party complained in the previous round.
+type round5 struct {
+	*round4
+
+	// complainers[j] contains the parties who complained about party j.
+	complainers map[party.ID][]party.ID
+}
+
+type broadcast5 struct {
+	round.NormalBroadcastContent
+	// Shares contains the shares this party sent to each party who complained about it.
+	Shares map[party.ID][]byte
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// Every party checks the disputed shares against the polynomial commitment of their sender.
+// A share failing the check, or a missing share, proves that the sender is at fault, and
+// the protocol aborts blaming them. Otherwise, the complaint was unfounded, and whoever
+// complained uses the revealed share instead.
+//
+// The complainer isn't blamed for an unfounded complaint, as in the DKG of Gennaro et al.: the
+// only share made public is its own, and the keygen completes with it. Aborting instead would let
+// any single party prevent the keygen from succeeding, by complaining about an honest party.
+func (r *round5) StoreBroadcastMessage(msg round.Message) error {
+	from := msg.From
+	body, ok := msg.Content.(*broadcast5)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+
+	complainers := r.complainers[from]
+	if len(body.Shares) != len(complainers) {
+		return fmt.Errorf("expected %d justifications, got %d", len(complainers), len(body.Shares))
+	}
+	for _, l := range complainers {
+		data, ok := body.Shares[l]
+		if !ok {
+			return fmt.Errorf("missing justification for party %s", l)
+		}
+		share := r.Group().NewScalar()
+		if err := share.UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("justification for party %s: %w", l, err)
+		}
+		// This is the same VSS condition as in round 3, but done publicly.
+		expected := share.ActOnBase()
+		actual := r.Phi[from].Evaluate(l.Scalar(r.Group()))
+		if !expected.Equal(actual) {
+			return fmt.Errorf("VSS failed to validate justification for party %s", l)
+		}
+		if l == r.SelfID() {
+			r.shareFrom[from] = share
+		}
+	}
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round5) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round5) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round.
+//
+// Every complaint has been justified, so we now hold a valid share from every party.
+func (r *round5) Finalize(chan<- *round.Message) (round.Session, error) {
+	return r.finish(), nil
+}
+
+// MessageContent implements round.Round.
+func (round5) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast5) RoundNumber() round.Number { return 5 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (round5) BroadcastContent() round.BroadcastContent { return &broadcast5{} }
+
+// Number implements round.Round.
+func (round5) Number() round.Number { return 5 }
@@ -0,0 +1,197 @@
+// Package mixin signs Mixin kernel transactions with Frost Ed25519 keys.
+//
+// The Frost public key acts as the public spend key of a Mixin address, and every
+// transaction input spends a one-time ghost key derived from it. Each input is signed
+// by its own Frost session, using the sign.ProtocolMixinPublic variant.
+package mixin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"golang.org/x/sync/errgroup"
+)
+
+// RunFunc executes the Frost session for a single transaction input, and returns its result.
+//
+// This is usually done by creating a protocol.MultiHandler from start and sessionID,
+// and driving it over the transport shared with the other signers.
+type RunFunc func(input int, sessionID []byte, start protocol.StartFunc) (interface{}, error)
+
+// TransactionSigner signs all inputs of a Mixin kernel transaction with a Frost Ed25519 key.
+type TransactionSigner struct {
+	config  *frost.Config
+	signers []party.ID
+	tx      *common.VersionedTransaction
+	hash    crypto.Hash
+
+	// masks[i] is the mask of the output spent by input i.
+	masks []crypto.Key
+	// ghosts[i] is the one-time key spent by input i.
+	ghosts []crypto.Key
+	// keyIndexes[i] is the index of ghosts[i] in the keys of the spent output.
+	keyIndexes []uint16
+	// signatures[i] is the signature of input i, once available.
+	signatures []*crypto.Signature
+
+	mtx sync.Mutex
+}
+
+// NewTransactionSigner prepares the signing of tx, whose inputs must all be owned by the Frost key of config.
+//
+// reader provides the mask and keys of the outputs being spent, which must be sent to the
+// deterministic Mixin address with config.PublicKey as public spend key.
+func NewTransactionSigner(config *frost.Config, signers []party.ID, tx *common.VersionedTransaction, reader common.UTXOKeysReader) (*TransactionSigner, error) {
+	if config.Curve().Name() != (curve.Edwards25519{}).Name() {
+		return nil, fmt.Errorf("mixin: invalid curve %s", config.Curve().Name())
+	}
+	if tx.Version < common.TxVersionHashSignature {
+		return nil, fmt.Errorf("mixin: invalid transaction version %d", tx.Version)
+	}
+	if len(tx.Inputs) == 0 {
+		return nil, errors.New("mixin: transaction without inputs")
+	}
+	if n := len(tx.SignaturesMap); n > 0 && n != len(tx.Inputs) {
+		return nil, fmt.Errorf("mixin: invalid signatures map %d/%d", n, len(tx.Inputs))
+	}
+
+	s := &TransactionSigner{
+		config:     config,
+		signers:    signers,
+		tx:         tx,
+		hash:       tx.PayloadHash(),
+		masks:      make([]crypto.Key, len(tx.Inputs)),
+		ghosts:     make([]crypto.Key, len(tx.Inputs)),
+		keyIndexes: make([]uint16, len(tx.Inputs)),
+		signatures: make([]*crypto.Signature, len(tx.Inputs)),
+	}
+	for i, in := range tx.Inputs {
+		if in.Deposit != nil || in.Mint != nil || len(in.Genesis) > 0 {
+			return nil, fmt.Errorf("mixin: input %d is not an UTXO", i)
+		}
+		utxo, err := reader.ReadUTXOKeys(in.Hash, in.Index)
+		if err != nil {
+			return nil, fmt.Errorf("mixin: input %d: %w", i, err)
+		}
+		if utxo == nil {
+			return nil, fmt.Errorf("mixin: input %d not found %s:%d", i, in.Hash, in.Index)
+		}
+		ghost, err := frost.MixinGhostKey(config, utxo.Mask, uint64(in.Index))
+		if err != nil {
+			return nil, fmt.Errorf("mixin: input %d: %w", i, err)
+		}
+		found := false
+		for k, key := range utxo.Keys {
+			if key != nil && *key == ghost {
+				s.keyIndexes[i] = uint16(k)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("mixin: input %d is not owned by %s", i, ghost)
+		}
+		s.masks[i] = utxo.Mask
+		s.ghosts[i] = ghost
+	}
+	return s, nil
+}
+
+// PayloadHash is the hash signed by every input of the transaction.
+func (s *TransactionSigner) PayloadHash() crypto.Hash {
+	return s.hash
+}
+
+// Inputs returns the number of inputs, i.e. the number of Frost sessions needed.
+func (s *TransactionSigner) Inputs() int {
+	return len(s.ghosts)
+}
+
+// SessionID returns the session ID for signing the given input.
+//
+// It is derived from the payload hash and the input index, so that all signers agree
+// on it and it is unique for every input.
+func (s *TransactionSigner) SessionID(input int) []byte {
+	sessionID := make([]byte, 0, len(s.hash)+4)
+	sessionID = append(sessionID, s.hash[:]...)
+	return binary.BigEndian.AppendUint32(sessionID, uint32(input))
+}
+
+// StartFunc returns the Frost signing protocol for the given input.
+func (s *TransactionSigner) StartFunc(input int) protocol.StartFunc {
+	return frost.SignMixinGhost(s.config, s.signers, s.hash[:], s.masks[input], uint64(s.tx.Inputs[input].Index))
+}
+
+// SetSignature stores the result of the Frost session for the given input, after verifying it.
+func (s *TransactionSigner) SetSignature(input int, result interface{}) error {
+	if input < 0 || input >= len(s.ghosts) {
+		return fmt.Errorf("mixin: invalid input index %d/%d", input, len(s.ghosts))
+	}
+	signature, ok := result.(*frost.Signature)
+	if !ok || signature == nil {
+		return fmt.Errorf("mixin: invalid result %T for input %d", result, input)
+	}
+	var sig crypto.Signature
+	copy(sig[:], signature.Serialize())
+	if !s.ghosts[input].Verify(s.hash, sig) {
+		return fmt.Errorf("mixin: invalid signature for input %d", input)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.signatures[input] = &sig
+	return nil
+}
+
+// Transaction returns a copy of the transaction, with the signatures of all inputs attached.
+//
+// Signatures already present in the transaction, e.g. from other keys of a multisig output, are kept.
+func (s *TransactionSigner) Transaction() (*common.VersionedTransaction, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	signed, err := common.UnmarshalVersionedTransaction(s.tx.Marshal())
+	if err != nil {
+		return nil, fmt.Errorf("mixin: %w", err)
+	}
+	if len(signed.SignaturesMap) == 0 {
+		signed.SignaturesMap = make([]map[uint16]*crypto.Signature, len(s.signatures))
+	}
+	for i, sig := range s.signatures {
+		if sig == nil {
+			return nil, fmt.Errorf("mixin: input %d not signed", i)
+		}
+		if signed.SignaturesMap[i] == nil {
+			signed.SignaturesMap[i] = make(map[uint16]*crypto.Signature)
+		}
+		signed.SignaturesMap[i][s.keyIndexes[i]] = sig
+	}
+	return common.UnmarshalVersionedTransaction(signed.Marshal())
+}
+
+// Sign runs the Frost sessions of all inputs concurrently with run, and returns the signed transaction.
+func (s *TransactionSigner) Sign(run RunFunc) (*common.VersionedTransaction, error) {
+	var errGroup errgroup.Group
+	for i := range s.ghosts {
+		input := i
+		errGroup.Go(func() error {
+			result, err := run(input, s.SessionID(input), s.StartFunc(input))
+			if err != nil {
+				return fmt.Errorf("mixin: input %d: %w", input, err)
+			}
+			return s.SetSignature(input, result)
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+	return s.Transaction()
+}
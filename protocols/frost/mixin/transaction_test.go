@@ -0,0 +1,126 @@
+package mixin
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/polynomial"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/sample"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+type utxoReader map[crypto.Hash]*common.UTXOKeys
+
+func (r utxoReader) ReadUTXOKeys(hash crypto.Hash, index uint) (*common.UTXOKeys, error) {
+	return r[hash], nil
+}
+
+func newKey() crypto.Key {
+	seed := make([]byte, 64)
+	_, _ = rand.Read(seed)
+	return crypto.NewKeyFromSeed(seed)
+}
+
+func TestTransactionSigner(t *testing.T) {
+	group := curve.Edwards25519{}
+
+	N := 4
+	threshold := 2
+	partyIDs := test.PartyIDs(N)
+
+	secret := sample.Scalar(rand.Reader, group)
+	f := polynomial.NewPolynomial(group, threshold, secret)
+	publicKey := secret.ActOnBase()
+	verificationShares := make(map[party.ID]curve.Point, N)
+	for _, id := range partyIDs {
+		verificationShares[id] = f.Evaluate(id.Scalar(group)).ActOnBase()
+	}
+
+	// the deterministic address owning the spent outputs, with the Frost key as spend key
+	var spendPublic crypto.Key
+	pb, _ := publicKey.MarshalBinary()
+	copy(spendPublic[:], pb)
+	viewPublic := spendPublic.DeterministicHashDerive().Public()
+	other := newKey().Public()
+
+	reader := make(utxoReader)
+	tx := common.NewTransactionV5(crypto.Blake3Hash([]byte("asset")))
+	for i := uint(0); i < 3; i++ {
+		r := newKey()
+		ghost := crypto.DeriveGhostPublicKey(&r, &viewPublic, &spendPublic, uint64(i))
+		hash := crypto.Blake3Hash([]byte{byte(i)})
+		reader[hash] = &common.UTXOKeys{
+			Mask: r.Public(),
+			Keys: []*crypto.Key{&other, ghost},
+		}
+		tx.AddInput(hash, i)
+	}
+	tx.Extra = []byte("frost")
+	ver := tx.AsVersioned()
+
+	networks := make([]*test.Network, len(tx.Inputs))
+	for i := range networks {
+		networks[i] = test.NewNetwork(partyIDs)
+	}
+
+	var errGroup errgroup.Group
+	results := make([]*common.VersionedTransaction, N)
+	for idx, id := range partyIDs {
+		errGroup.Go(func() error {
+			config := &frost.Config{
+				ID:                 id,
+				Threshold:          threshold,
+				PublicKey:          publicKey,
+				PrivateShare:       f.Evaluate(id.Scalar(group)),
+				VerificationShares: party.NewPointMap(verificationShares),
+			}
+			signer, err := NewTransactionSigner(config, partyIDs, ver, reader)
+			if err != nil {
+				return err
+			}
+			signed, err := signer.Sign(func(input int, sessionID []byte, start protocol.StartFunc) (interface{}, error) {
+				h, err := protocol.NewMultiHandler(start, sessionID)
+				if err != nil {
+					return nil, err
+				}
+				test.HandlerLoop(id, h, networks[input])
+				return h.Result()
+			})
+			results[idx] = signed
+			return err
+		})
+	}
+	require.NoError(t, errGroup.Wait())
+
+	for _, signed := range results {
+		require.NotNil(t, signed)
+		require.Len(t, signed.SignaturesMap, len(tx.Inputs))
+		msg := signed.PayloadHash()
+		assert.Equal(t, ver.PayloadHash(), msg)
+		for i, in := range signed.Inputs {
+			utxo := reader[in.Hash]
+			sigs := signed.SignaturesMap[i]
+			require.Len(t, sigs, 1)
+			require.NotNil(t, sigs[1])
+			assert.True(t, utxo.Keys[1].Verify(msg, *sigs[1]))
+		}
+	}
+
+	_, err := NewTransactionSigner(&frost.Config{
+		ID:                 partyIDs[0],
+		Threshold:          threshold,
+		PublicKey:          sample.Scalar(rand.Reader, group).ActOnBase(),
+		PrivateShare:       f.Evaluate(partyIDs[0].Scalar(group)),
+		VerificationShares: party.NewPointMap(verificationShares),
+	}, partyIDs, ver, reader)
+	assert.Error(t, err, "expected inputs not owned by a different spend key")
+}
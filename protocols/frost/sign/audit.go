@@ -0,0 +1,83 @@
+package sign
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost/keygen"
+)
+
+// Audit checks the transcript of a signing session started with StartSignCommon, and returns the signature it produced.
+//
+// config is the public output of the key generation, as returned by keygen.Audit, and messages should contain the
+// broadcast messages of all signers, such as the messages of a protocol.Transcript recorded by any of them.
+// The response of every signer is checked against its verification share, and the signature against the public key,
+// without any secret share.
+//
+// keys contains the identity public key of every one of the signers, as given to protocol.WithIdentities:
+// only the messages they signed are taken into account.
+func Audit(config *keygen.PublicConfig, signers []party.ID, messageHash []byte, variant int, sessionID []byte, keys map[party.ID]protocol.IdentityPublicKey, messages []*protocol.Message) (interface{}, error) {
+	if config == nil || config.PublicKey == nil || config.VerificationShares == nil {
+		return nil, errors.New("sign.Audit: missing public config")
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("sign.Audit: no signers")
+	}
+	group := config.PublicKey.Curve()
+	var mS curve.Scalar
+	if variant == ProtocolMixinPublic {
+		if len(messageHash) < 32 {
+			return nil, fmt.Errorf("sign.Audit: %d", len(messageHash))
+		}
+		mS = group.NewScalar()
+		if err := mS.UnmarshalBinary(messageHash[:32]); err != nil {
+			return nil, fmt.Errorf("sign.Audit: %w", err)
+		}
+		messageHash = messageHash[32:]
+	}
+
+	// The auditor isn't a signer, so we impersonate one with a zero share. The nonces and the response
+	// it computes are replaced by the ones it broadcast.
+	session, err := startSign(&keygen.Config{
+		ID:                 signers[0],
+		Threshold:          config.Threshold,
+		PrivateShare:       group.NewScalar(),
+		PublicKey:          config.PublicKey,
+		ChainKey:           config.ChainKey,
+		VerificationShares: config.VerificationShares,
+	}, signers, messageHash, variant, mS, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("sign.Audit: %w", err)
+	}
+
+	var r round.Session = session
+	for r.Number() < protocolRounds {
+		if r.Number() > 1 {
+			if err = protocol.ReplayBroadcasts(r, keys, messages); err != nil {
+				return nil, err
+			}
+		}
+		if r, err = r.Finalize(make(chan *round.Message, 1)); err != nil {
+			return nil, fmt.Errorf("sign.Audit: %w", err)
+		}
+	}
+	if err = protocol.ReplayBroadcasts(r, keys, messages); err != nil {
+		return nil, err
+	}
+	if r, err = r.Finalize(nil); err != nil {
+		return nil, fmt.Errorf("sign.Audit: %w", err)
+	}
+
+	switch final := r.(type) {
+	case *round.Output:
+		return final.Result, nil
+	case *round.Abort:
+		return nil, protocol.Error{Culprits: final.Culprits, Err: final.Err}
+	default:
+		return nil, fmt.Errorf("sign.Audit: unexpected round %T", r)
+	}
+}
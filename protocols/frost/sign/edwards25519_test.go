@@ -95,6 +95,7 @@ func testSignEdwards25519(t *testing.T, variant int) {
 }
 
 func checkOutputEd25519(t *testing.T, rounds []round.Session, public curve.Point, m []byte, variant int) {
+	group := curve.Edwards25519{}
 	for _, r := range rounds {
 		require.IsType(t, &round.Output{}, r, "expected result round")
 		resultRound := r.(*round.Output)
@@ -111,7 +112,6 @@ func checkOutputEd25519(t *testing.T, rounds []round.Session, public curve.Point
 		case ProtocolEd25519SHA512:
 			assert.True(t, signature.VerifyEd25519(public, m), "expected valid ed25519 signature")
 		case ProtocolMixinPublic:
-			group := curve.Edwards25519{}
 			r := group.NewScalar()
 			r.UnmarshalBinary(m[:32])
 			P := r.ActOnBase().Add(public)
@@ -124,6 +124,13 @@ func checkOutputEd25519(t *testing.T, rounds []round.Session, public curve.Point
 		assert.Len(t, pb, 32)
 		sig := signature.Serialize()
 		assert.Len(t, sig, 64)
+		parsed, err := ParseSignature(group, sig)
+		require.NoError(t, err)
+		data, err := parsed.MarshalBinary()
+		require.NoError(t, err)
+		assert.Equal(t, sig, data)
+		assert.True(t, parsed.R.Equal(signature.R))
+		assert.True(t, parsed.Z().Equal(signature.Z()))
 		var mpub crypto.Key
 		copy(mpub[:], pb)
 		var msig crypto.Signature
@@ -143,3 +150,78 @@ func checkOutputEd25519(t *testing.T, rounds []round.Session, public curve.Point
 		}
 	}
 }
+
+func TestSignMixinGhost(t *testing.T) {
+	group := curve.Edwards25519{}
+
+	N := 5
+	threshold := 2
+
+	partyIDs := test.PartyIDs(N)
+
+	secret := sample.Scalar(rand.Reader, group)
+	f := polynomial.NewPolynomial(group, threshold, secret)
+	publicKey := secret.ActOnBase()
+	sh := crypto.Blake3Hash([]byte{0xDE, 0xAD, 0xBE, 0xEF})
+	message := sh[:]
+
+	verificationShares := make(map[party.ID]curve.Point, N)
+	for _, id := range partyIDs {
+		verificationShares[id] = f.Evaluate(id.Scalar(group)).ActOnBase()
+	}
+
+	// an output sent to the deterministic address of publicKey, as the kernel creates it.
+	seed := make([]byte, 64)
+	_, _ = rand.Read(seed)
+	r := crypto.NewKeyFromSeed(seed)
+	R := r.Public()
+	var B crypto.Key
+	pb, _ := publicKey.MarshalBinary()
+	copy(B[:], pb)
+	A := B.DeterministicHashDerive().Public()
+	ghost := crypto.DeriveGhostPublicKey(&r, &A, &B, 3)
+
+	_, P, err := MixinGhostKey(publicKey, R, 3)
+	require.NoError(t, err)
+	assert.Equal(t, *ghost, P)
+	_, P, err = MixinGhostKey(publicKey, R, 4)
+	require.NoError(t, err)
+	assert.NotEqual(t, *ghost, P)
+
+	rounds := make([]round.Session, 0, N)
+	for _, id := range partyIDs {
+		result := &keygen.Config{
+			ID:                 id,
+			Threshold:          threshold,
+			PublicKey:          publicKey,
+			PrivateShare:       f.Evaluate(id.Scalar(group)),
+			VerificationShares: party.NewPointMap(verificationShares),
+		}
+		_, err := StartSignMixinGhost(result, partyIDs, message, crypto.Key{2}, 3)(nil)
+		require.Error(t, err, "expected invalid mask")
+
+		r, err := StartSignMixinGhost(result, partyIDs, message, R, 3)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+
+	oneTimeKey := group.NewPoint()
+	require.NoError(t, oneTimeKey.UnmarshalBinary(ghost[:]))
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+		signature := r.(*round.Output).Result.(*Signature)
+		assert.True(t, signature.VerifyEd25519(oneTimeKey, message), "expected valid mixin signature")
+
+		var msig crypto.Signature
+		copy(msig[:], signature.Serialize())
+		assert.True(t, ghost.Verify(sh, msig), "expected valid kernel signature")
+	}
+}
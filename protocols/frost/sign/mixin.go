@@ -0,0 +1,68 @@
+package sign
+
+import (
+	"fmt"
+
+	"filippo.io/edwards25519"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost/keygen"
+)
+
+// MixinGhostKey computes the one-time public key of the output with mask R and index i,
+// sent to the deterministic Mixin address whose public spend key is Y:
+//
+//	P = H(a•R || i)•G + Y
+//
+// As in the Mixin kernel, the private view key a of this address is derived from Y itself.
+// The ghost key scalar H(a•R || i) is returned along with P.
+func MixinGhostKey(Y curve.Point, R crypto.Key, outputIndex uint64) (curve.Scalar, crypto.Key, error) {
+	var B crypto.Key
+	if Y.Curve().Name() != (curve.Edwards25519{}).Name() {
+		return nil, B, fmt.Errorf("invalid curve %s", Y.Curve().Name())
+	}
+	if _, err := edwards25519.NewIdentityPoint().SetBytes(R[:]); err != nil {
+		return nil, B, fmt.Errorf("invalid mask: %w", err)
+	}
+	pb, err := Y.MarshalBinary()
+	if err != nil {
+		return nil, B, err
+	}
+	copy(B[:], pb)
+	a := B.DeterministicHashDerive()
+
+	x := crypto.HashScalar(crypto.KeyMultPubPriv(&R, &a), outputIndex)
+	mS := curve.Edwards25519{}.NewScalar()
+	if err := mS.UnmarshalBinary(x.Bytes()); err != nil {
+		return nil, B, err
+	}
+	pb, err = mS.ActOnBase().Add(Y).MarshalBinary()
+	if err != nil {
+		return nil, B, err
+	}
+	var P crypto.Key
+	copy(P[:], pb)
+	// the kernel must recognize P as an output of the address, with the same view key.
+	if *crypto.ViewGhostOutputKey(&P, &a, &R, outputIndex) != B {
+		return nil, B, fmt.Errorf("one-time key mismatch %s", P)
+	}
+	return mS, P, nil
+}
+
+// StartSignMixinGhost creates a ProtocolMixinPublic signing session for the output with mask R
+// and index outputIndex, sent to the deterministic Mixin address of result.PublicKey.
+//
+// The ghost key scalar is computed with MixinGhostKey, and the signature then verifies as a
+// plain Ed25519 signature of message under the one-time public key of the output.
+func StartSignMixinGhost(result *keygen.Config, signers []party.ID, message []byte, R crypto.Key, outputIndex uint64) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		mS, _, err := MixinGhostKey(result.PublicKey, R, outputIndex)
+		if err != nil {
+			return nil, fmt.Errorf("sign.StartSignMixinGhost: %w", err)
+		}
+		return startSign(result, signers, message, ProtocolMixinPublic, mS, sessionID)
+	}
+}
@@ -40,6 +40,9 @@ type round1 struct {
 
 	// mixin scalar H(Ra || i)
 	mS curve.Scalar
+
+	// T is the adaptor point, only set when producing an adaptor pre-signature.
+	T curve.Point
 }
 
 // VerifyMessage implements round.Round.
@@ -114,13 +114,21 @@ func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
 		RShares[l] = RShares[l].Add(r.D[l])
 		R = R.Add(RShares[l])
 	}
+	// Adaptor signatures commit to R + T instead, while the responses still
+	// only cover the nonces behind R.
+	if r.T != nil {
+		R = R.Add(r.T)
+	}
 	var c curve.Scalar
 	switch r.ProtocolID() {
-	case protocolIDTaproot:
+	case protocolIDTaproot, protocolIDTaprootAdaptor:
 		// BIP-340 adjustment: We need R to have an even y coordinate. This means
 		// conditionally negating k = ∑ᵢ (dᵢ + (eᵢ ρᵢ)), which we can accomplish
 		// by negating our dᵢ, eᵢ, if necessary. This entails negating the RShares
 		// as well.
+		//
+		// For adaptor signatures, the parity of R + T decides instead, and the
+		// adaptor secret gets negated when completing the signature.
 		if !R.HasEvenY() {
 			r.d_i.Negate()
 			r.e_i.Negate()
@@ -135,7 +143,7 @@ func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
 		PBytes := r.Y.XScalar().Bytes()
 		cHash := taproot.TaggedHash("BIP0340/challenge", RBytes, PBytes, r.M)
 		c = r.Group().NewScalar().SetNat(new(saferith.Nat).SetBytes(cHash))
-	case protocolIDDefault:
+	case protocolIDDefault, protocolIDDefaultAdaptor:
 		cHash := hash.New()
 		_ = cHash.WriteAny(R, r.Y, r.M)
 		c = sample.Scalar(cHash.Digest(), r.Group())
@@ -18,6 +18,8 @@ import (
 type round3 struct {
 	*round2
 	// R is the group commitment, and the first part of the consortium signature
+	//
+	// For adaptor signatures, this already includes the adaptor point T.
 	R curve.Point
 	// RShares is the fraction each participant contributes to the group commitment
 	//
@@ -123,6 +125,37 @@ func (r *round3) Finalize(chan<- *round.Message) (round.Session, error) {
 			return r.AbortRound(fmt.Errorf("generated signature failed to verify")), nil
 		}
 
+		return r.ResultRound(sig), nil
+	case protocolIDTaprootAdaptor:
+		RBytes, err := r.R.MarshalBinary()
+		if err != nil {
+			return r, err
+		}
+		zBytes, err := z.MarshalBinary()
+		if err != nil {
+			return r, err
+		}
+		sig := taproot.AdaptorSignature(make([]byte, 0, taproot.AdaptorSignatureLen))
+		sig = append(sig, RBytes...)
+		sig = append(sig, zBytes...)
+
+		taprootPub := taproot.PublicKey(r.Y.XScalar().Bytes())
+
+		if !taprootPub.VerifyAdaptor(sig, r.T, r.M) {
+			return r.AbortRound(fmt.Errorf("generated adaptor signature failed to verify")), nil
+		}
+
+		return r.ResultRound(sig), nil
+	case protocolIDDefaultAdaptor:
+		sig := &AdaptorSignature{
+			R: r.R,
+			z: z,
+		}
+
+		if !sig.Verify(r.Y, r.T, r.M) {
+			return r.AbortRound(fmt.Errorf("generated adaptor signature failed to verify")), nil
+		}
+
 		return r.ResultRound(sig), nil
 	case protocolIDEd25519SHA512:
 		sig := &Signature{
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
 	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
 	"github.com/MixinNetwork/multi-party-sig/pkg/party"
 	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
@@ -21,11 +22,93 @@ const (
 	protocolIDTaproot       = "frost/sign-threshold-taproot"
 	protocolIDEd25519SHA512 = "frost/sign-threshold-ed25519-sha512"
 	protocolIDMixinPublic   = "frost/sign-threshold-mixin-public"
+	// Frost Sign with Threshold, producing adaptor pre-signatures.
+	protocolIDDefaultAdaptor = "frost/sign-threshold-default-adaptor"
+	protocolIDTaprootAdaptor = "frost/sign-threshold-taproot-adaptor"
 	// This protocol has 3 concrete rounds.
 	protocolRounds round.Number = 3
 )
 
+// StartSignCommon creates the first round of a Frost signing session, for one of the supported variants.
+//
+// For ProtocolMixinPublic, messageHash must be prefixed by the 32 byte ghost key scalar H(a•R || i).
+// Prefer StartSignMixinGhost, which computes this scalar from the output instead.
 func StartSignCommon(result *keygen.Config, signers []party.ID, messageHash []byte, protocol int) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		var mS curve.Scalar
+		if protocol == ProtocolMixinPublic {
+			if len(messageHash) < 32 {
+				return nil, fmt.Errorf("sign.StartSignCommon: %d", len(messageHash))
+			}
+			mS = result.Curve().NewScalar()
+			err := mS.UnmarshalBinary(messageHash[:32])
+			if err != nil {
+				return nil, fmt.Errorf("sign.StartSignCommon: %w", err)
+			}
+			messageHash = messageHash[32:]
+		}
+		return startSign(result, signers, messageHash, protocol, mS, sessionID)
+	}
+}
+
+// startSign creates the first round of a signing session, with mS being the
+// mixin ghost key scalar for ProtocolMixinPublic.
+func startSign(result *keygen.Config, signers []party.ID, messageHash []byte, protocol int, mS curve.Scalar, sessionID []byte) (round.Session, error) {
+	info := round.Info{
+		FinalRoundNumber: protocolRounds,
+		SelfID:           result.ID,
+		PartyIDs:         signers,
+		Threshold:        result.Threshold,
+		Group:            result.PublicKey.Curve(),
+	}
+	switch protocol {
+	case ProtocolTaproot:
+		info.ProtocolID = protocolIDTaproot
+		if result.Curve().Name() != (curve.Secp256k1{}).Name() {
+			return nil, fmt.Errorf("sign.StartSignCommon: %s", result.Curve().Name())
+		}
+	case ProtocolEd25519SHA512:
+		info.ProtocolID = protocolIDEd25519SHA512
+		if result.Curve().Name() != (curve.Edwards25519{}).Name() {
+			return nil, fmt.Errorf("sign.StartSignCommon: %s", result.Curve().Name())
+		}
+	case ProtocolMixinPublic:
+		info.ProtocolID = protocolIDMixinPublic
+		if result.Curve().Name() != (curve.Edwards25519{}).Name() {
+			return nil, fmt.Errorf("sign.StartSignCommon: %s", result.Curve().Name())
+		}
+		if mS == nil {
+			return nil, fmt.Errorf("sign.StartSignCommon: missing mixin ghost key scalar")
+		}
+	case ProtocolDefault:
+		info.ProtocolID = protocolIDDefault
+	default:
+		return nil, fmt.Errorf("sign.StartSignCommon: %d", protocol)
+	}
+
+	helper, err := round.NewSession(info, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sign.StartSign: %w", err)
+	}
+	return &round1{
+		Helper:  helper,
+		M:       messageHash,
+		Y:       result.PublicKey,
+		YShares: result.VerificationShares.Points,
+		s_i:     result.PrivateShare,
+		mS:      mS,
+	}, nil
+}
+
+// StartSignAdaptor is like StartSignCommon, but produces a pre-signature encrypted under
+// the adaptor point T, instead of a complete signature.
+//
+// The group commitment is shifted to R + T, so that the discrete logarithm t of T
+// completes the pre-signature, and can in turn be extracted from the completed signature.
+//
+// Only ProtocolDefault and ProtocolTaproot are supported, producing an *AdaptorSignature
+// and a taproot.AdaptorSignature respectively.
+func StartSignAdaptor(result *keygen.Config, signers []party.ID, messageHash []byte, T curve.Point, protocol int) protocol.StartFunc {
 	return func(sessionID []byte) (round.Session, error) {
 		info := round.Info{
 			FinalRoundNumber: protocolRounds,
@@ -36,50 +119,37 @@ func StartSignCommon(result *keygen.Config, signers []party.ID, messageHash []by
 		}
 		switch protocol {
 		case ProtocolTaproot:
-			info.ProtocolID = protocolIDTaproot
+			info.ProtocolID = protocolIDTaprootAdaptor
 			if result.Curve().Name() != (curve.Secp256k1{}).Name() {
-				return nil, fmt.Errorf("sign.StartSignCommon: %s", result.Curve().Name())
-			}
-		case ProtocolEd25519SHA512:
-			info.ProtocolID = protocolIDEd25519SHA512
-			if result.Curve().Name() != (curve.Edwards25519{}).Name() {
-				return nil, fmt.Errorf("sign.StartSignCommon: %s", result.Curve().Name())
-			}
-		case ProtocolMixinPublic:
-			info.ProtocolID = protocolIDMixinPublic
-			if result.Curve().Name() != (curve.Edwards25519{}).Name() {
-				return nil, fmt.Errorf("sign.StartSignCommon: %s", result.Curve().Name())
+				return nil, fmt.Errorf("sign.StartSignAdaptor: %s", result.Curve().Name())
 			}
 		case ProtocolDefault:
-			info.ProtocolID = protocolIDDefault
+			info.ProtocolID = protocolIDDefaultAdaptor
 		default:
-			return nil, fmt.Errorf("sign.StartSignCommon: %d", protocol)
+			return nil, fmt.Errorf("sign.StartSignAdaptor: %d", protocol)
+		}
+		if T == nil || T.Curve().Name() != result.Curve().Name() || T.IsIdentity() {
+			return nil, fmt.Errorf("sign.StartSignAdaptor: invalid adaptor point")
+		}
+		TBytes, err := T.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("sign.StartSignAdaptor: %w", err)
 		}
 
-		helper, err := round.NewSession(info, sessionID, nil)
+		helper, err := round.NewSession(info, sessionID, nil, &hash.BytesWithDomain{
+			TheDomain: "Adaptor Point",
+			Bytes:     TBytes,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("sign.StartSign: %w", err)
+			return nil, fmt.Errorf("sign.StartSignAdaptor: %w", err)
 		}
-		r := &round1{
+		return &round1{
 			Helper:  helper,
 			M:       messageHash,
 			Y:       result.PublicKey,
 			YShares: result.VerificationShares.Points,
 			s_i:     result.PrivateShare,
-		}
-
-		if protocol == ProtocolMixinPublic {
-			if len(r.M) < 32 {
-				return nil, fmt.Errorf("sign.StartSignCommon: %d", len(r.M))
-			}
-			r.mS = result.Curve().NewScalar()
-			err = r.mS.UnmarshalBinary(r.M[:32])
-			if err != nil {
-				panic(err)
-			}
-			r.M = r.M[32:]
-		}
-
-		return r, nil
+			T:       T,
+		}, nil
 	}
 }
@@ -25,6 +25,17 @@ func checkOutput(t *testing.T, rounds []round.Session, public curve.Point, m []b
 		require.IsType(t, &Signature{}, resultRound.Result, "expected signature result")
 		signature := resultRound.Result.(*Signature)
 		assert.True(t, signature.Verify(public, m), "expected valid signature")
+
+		data, err := signature.MarshalBinary()
+		require.NoError(t, err)
+		assert.Len(t, data, 65)
+		parsed, err := ParseSignature(public.Curve(), data)
+		require.NoError(t, err)
+		assert.True(t, parsed.R.Equal(signature.R))
+		assert.True(t, parsed.Z().Equal(signature.Z()))
+		assert.True(t, parsed.Verify(public, m), "expected valid parsed signature")
+		_, err = ParseSignature(public.Curve(), data[1:])
+		assert.Error(t, err)
 	}
 }
 
@@ -166,3 +177,132 @@ func TestSignTaproot(t *testing.T) {
 
 	checkOutputTaproot(t, rounds, newPublicKey, steak)
 }
+
+func TestSignAdaptor(t *testing.T) {
+	group := curve.Secp256k1{}
+
+	N := 5
+	threshold := 2
+
+	partyIDs := test.PartyIDs(N)
+
+	secret := sample.Scalar(rand.Reader, group)
+	f := polynomial.NewPolynomial(group, threshold, secret)
+	publicKey := secret.ActOnBase()
+	steak := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+
+	adaptorSecret := sample.Scalar(rand.Reader, group)
+	adaptorPoint := adaptorSecret.ActOnBase()
+
+	verificationShares := make(map[party.ID]curve.Point, N)
+	for _, id := range partyIDs {
+		verificationShares[id] = f.Evaluate(id.Scalar(group)).ActOnBase()
+	}
+
+	rounds := make([]round.Session, 0, N)
+	for _, id := range partyIDs {
+		result := &keygen.Config{
+			ID:                 id,
+			Threshold:          threshold,
+			PublicKey:          publicKey,
+			PrivateShare:       f.Evaluate(id.Scalar(group)),
+			VerificationShares: party.NewPointMap(verificationShares),
+		}
+		r, err := StartSignAdaptor(result, partyIDs, steak, adaptorPoint, ProtocolDefault)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+		resultRound := r.(*round.Output)
+		require.IsType(t, &AdaptorSignature{}, resultRound.Result, "expected adaptor signature result")
+		preSignature := resultRound.Result.(*AdaptorSignature)
+		assert.True(t, preSignature.Verify(publicKey, adaptorPoint, steak), "expected valid adaptor signature")
+		assert.False(t, preSignature.Verify(publicKey, group.NewBasePoint(), steak), "expected invalid adaptor signature")
+
+		signature := preSignature.Complete(adaptorSecret)
+		assert.True(t, signature.Verify(publicKey, steak), "expected valid completed signature")
+
+		extracted, err := preSignature.Extract(signature, adaptorPoint)
+		require.NoError(t, err)
+		assert.True(t, extracted.Equal(adaptorSecret), "expected extracted adaptor secret")
+	}
+}
+
+func TestSignTaprootAdaptor(t *testing.T) {
+	group := curve.Secp256k1{}
+	N := 5
+	threshold := 2
+
+	partyIDs := test.PartyIDs(N)
+
+	secret := sample.Scalar(rand.Reader, group)
+	publicPoint := secret.ActOnBase()
+	if !publicPoint.HasEvenY() {
+		secret.Negate()
+		publicPoint = publicPoint.Negate()
+	}
+	f := polynomial.NewPolynomial(group, threshold, secret)
+	publicKey := taproot.PublicKey(publicPoint.XScalar().Bytes())
+	steakHash := sha256.New()
+	_, _ = steakHash.Write([]byte{0xDE, 0xAD, 0xBE, 0xEF})
+	steak := steakHash.Sum(nil)
+
+	verificationShares := make(map[party.ID]curve.Point, N)
+	for _, id := range partyIDs {
+		verificationShares[id] = f.Evaluate(id.Scalar(group)).ActOnBase()
+	}
+
+	// Repeat, in order to hit both parities of R + T.
+	for i := 0; i < 4; i++ {
+		adaptorSecret := sample.Scalar(rand.Reader, group)
+		adaptorPoint := adaptorSecret.ActOnBase()
+
+		rounds := make([]round.Session, 0, N)
+		for _, id := range partyIDs {
+			result := &keygen.Config{
+				ID:                 id,
+				Threshold:          threshold,
+				PublicKey:          publicPoint,
+				PrivateShare:       f.Evaluate(id.Scalar(group)),
+				VerificationShares: party.NewPointMap(verificationShares),
+			}
+			r, err := StartSignAdaptor(result, partyIDs, steak, adaptorPoint, ProtocolTaproot)(nil)
+			require.NoError(t, err, "round creation should not result in an error")
+			rounds = append(rounds, r)
+		}
+
+		for {
+			err, done := test.Rounds(rounds, nil)
+			require.NoError(t, err, "failed to process round")
+			if done {
+				break
+			}
+		}
+
+		for _, r := range rounds {
+			require.IsType(t, &round.Output{}, r, "expected result round")
+			resultRound := r.(*round.Output)
+			require.IsType(t, taproot.AdaptorSignature{}, resultRound.Result, "expected taproot adaptor signature result")
+			preSignature := resultRound.Result.(taproot.AdaptorSignature)
+			assert.True(t, publicKey.VerifyAdaptor(preSignature, adaptorPoint, steak), "expected valid adaptor signature")
+
+			signature, err := preSignature.Complete(adaptorSecret)
+			require.NoError(t, err)
+			assert.True(t, publicKey.Verify(signature, steak), "expected valid completed signature")
+
+			extracted, err := preSignature.Extract(signature, adaptorPoint)
+			require.NoError(t, err)
+			assert.True(t, extracted.Equal(adaptorSecret), "expected extracted adaptor secret")
+		}
+	}
+}
@@ -2,6 +2,7 @@ package sign
 
 import (
 	"crypto/ed25519"
+	"fmt"
 	"io"
 
 	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
@@ -40,6 +41,74 @@ type Signature struct {
 	z curve.Scalar
 }
 
+// EmptySignature returns a new signature with a given curve, ready to be unmarshalled.
+func EmptySignature(group curve.Curve) *Signature {
+	return &Signature{R: group.NewPoint(), z: group.NewScalar()}
+}
+
+// ParseSignature decodes a signature produced by MarshalBinary for the given curve.
+//
+// For secp256k1, this is the compressed point R followed by the 32 byte big-endian z,
+// and for Ed25519 this is the 64 byte encoding of RFC 8032.
+func ParseSignature(group curve.Curve, b []byte) (*Signature, error) {
+	sig := EmptySignature(group)
+	if err := sig.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// Z returns the response scalar z.
+func (sig *Signature) Z() curve.Scalar {
+	return sig.z.Curve().NewScalar().Set(sig.z)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding R followed by z.
+func (sig *Signature) MarshalBinary() ([]byte, error) {
+	if sig.R == nil || sig.z == nil {
+		return nil, fmt.Errorf("sign.Signature.MarshalBinary: empty signature")
+	}
+	rb, err := sig.R.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	zb, err := sig.z.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(rb, zb...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// The signature must have been created with EmptySignature, so that the curve is known.
+func (sig *Signature) UnmarshalBinary(data []byte) error {
+	if sig.R == nil || sig.z == nil {
+		return fmt.Errorf("sign.Signature.UnmarshalBinary: unknown curve")
+	}
+	rb, err := sig.R.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	zb, err := sig.z.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if len(data) != len(rb)+len(zb) {
+		return fmt.Errorf("sign.Signature.UnmarshalBinary: invalid length %d", len(data))
+	}
+	R := sig.R.Curve().NewPoint()
+	if err := R.UnmarshalBinary(data[:len(rb)]); err != nil {
+		return err
+	}
+	z := sig.z.Curve().NewScalar()
+	if err := z.UnmarshalBinary(data[len(rb):]); err != nil {
+		return err
+	}
+	sig.R, sig.z = R, z
+	return nil
+}
+
 func (sig *Signature) Serialize() []byte {
 	rb, err := sig.R.MarshalBinary()
 	if err != nil {
@@ -76,3 +145,62 @@ func (sig *Signature) VerifyEd25519(public curve.Point, m []byte) bool {
 	pub := ed25519.PublicKey(pb)
 	return ed25519.Verify(pub, m, sig.Serialize())
 }
+
+// AdaptorSignature represents a Schnorr pre-signature, encrypted under an adaptor point T.
+//
+// This pre-signature claims to satisfy:
+//
+//	z * G = (R - T) + H(R, Y, m) * Y
+//
+// for a public key Y, where R is the adapted commitment point, i.e. the sum of
+// the group nonce commitment and T.
+// Anybody knowing t, with T = t * G, can complete it into a valid Signature,
+// and anybody holding both the pre-signature and the completed Signature can extract t.
+type AdaptorSignature struct {
+	// R is the adapted commitment point, including the adaptor point T.
+	R curve.Point
+	// z is the response scalar, missing the adaptor secret t.
+	z curve.Scalar
+}
+
+// Verify checks if a pre-signature equation actually holds, for the adaptor point T.
+//
+// Note that m is the hash of a message, and not the message itself.
+func (sig *AdaptorSignature) Verify(public, T curve.Point, m []byte) bool {
+	group := public.Curve()
+
+	challengeHash := hash.New()
+	_ = challengeHash.WriteAny(sig.R, public, messageHash(m))
+	challenge := sample.Scalar(challengeHash.Digest(), group)
+
+	expected := challenge.Act(public)
+	expected = expected.Add(sig.R.Sub(T))
+
+	actual := sig.z.ActOnBase()
+
+	return expected.Equal(actual)
+}
+
+// Complete uses the adaptor secret t to turn the pre-signature into a valid Signature.
+func (sig *AdaptorSignature) Complete(t curve.Scalar) *Signature {
+	z := t.Curve().NewScalar().Set(sig.z).Add(t)
+	return &Signature{
+		R: sig.R,
+		z: z,
+	}
+}
+
+// Extract recovers the adaptor secret t from a completed Signature.
+//
+// An error is returned if the signature was not completed from this pre-signature,
+// which is checked against the adaptor point T.
+func (sig *AdaptorSignature) Extract(completed *Signature, T curve.Point) (curve.Scalar, error) {
+	if !completed.R.Equal(sig.R) {
+		return nil, fmt.Errorf("sign.AdaptorSignature.Extract: commitment mismatch")
+	}
+	t := T.Curve().NewScalar().Set(completed.z).Sub(sig.z)
+	if !t.ActOnBase().Equal(T) {
+		return nil, fmt.Errorf("sign.AdaptorSignature.Extract: adaptor secret mismatch")
+	}
+	return t, nil
+}
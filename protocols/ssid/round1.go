@@ -0,0 +1,57 @@
+package ssid
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+type round1 struct {
+	*round.Helper
+	// next creates the protocol to run with the agreed SSID, or is nil if the SSID is the result.
+	next protocol.StartFunc
+}
+
+// VerifyMessage implements round.Round.
+func (r *round1) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (r *round1) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - sample ρᵢ
+// - commit to ρᵢ, and broadcast the commitment.
+func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
+	rho, err := types.NewRID(rand.Reader)
+	if err != nil {
+		return r, fmt.Errorf("failed to sample rho")
+	}
+	commitment, decommitment, err := r.HashForID(r.SelfID()).Commit(rho)
+	if err != nil {
+		return r, fmt.Errorf("failed to commit to rho")
+	}
+	if err = r.BroadcastMessage(out, &broadcast2{Commitment: commitment}); err != nil {
+		return r, err
+	}
+	return &round2{
+		round1:       r,
+		Rho:          map[party.ID]types.RID{r.SelfID(): rho},
+		Commitments:  map[party.ID]hash.Commitment{r.SelfID(): commitment},
+		Decommitment: decommitment,
+	}, nil
+}
+
+// Chained implements round.Chain.
+func (r *round1) Chained() bool { return r.next != nil }
+
+// MessageContent implements round.Round.
+func (round1) MessageContent() round.Content { return nil }
+
+// Number implements round.Round.
+func (round1) Number() round.Number { return 1 }
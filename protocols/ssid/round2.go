@@ -0,0 +1,74 @@
+package ssid
+
+import (
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+)
+
+type round2 struct {
+	*round1
+	// Rho[j] = ρⱼ, only known for ourselves until the next round.
+	Rho map[party.ID]types.RID
+	// Commitments[j] = H(j, ρⱼ, uⱼ)
+	Commitments map[party.ID]hash.Commitment
+	// Decommitment = uᵢ
+	Decommitment hash.Decommitment
+}
+
+type broadcast2 struct {
+	// the commitments must be the same for everybody, so that no party can adapt its
+	// randomness to the others.
+	round.ReliableBroadcastContent
+	// Commitment = H(i, ρᵢ, uᵢ)
+	Commitment hash.Commitment
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - save commitment Vⱼ.
+func (r *round2) StoreBroadcastMessage(msg round.Message) error {
+	body, ok := msg.Content.(*broadcast2)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+	if err := body.Commitment.Validate(); err != nil {
+		return fmt.Errorf("commitment: %w", err)
+	}
+	r.Commitments[msg.From] = body.Commitment
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round2) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round2) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - reveal ρᵢ and uᵢ.
+func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
+	if err := r.BroadcastMessage(out, &broadcast3{
+		Rho:          r.Rho[r.SelfID()],
+		Decommitment: r.Decommitment,
+	}); err != nil {
+		return r, err
+	}
+	return &round3{round2: r}, nil
+}
+
+// MessageContent implements round.Round.
+func (round2) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast2) RoundNumber() round.Number { return 2 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (round2) BroadcastContent() round.BroadcastContent { return &broadcast2{} }
+
+// Number implements round.Round.
+func (round2) Number() round.Number { return 2 }
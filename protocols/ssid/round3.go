@@ -0,0 +1,78 @@
+package ssid
+
+import (
+	"errors"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/common/types"
+	"github.com/MixinNetwork/multi-party-sig/pkg/hash"
+)
+
+type round3 struct {
+	*round2
+}
+
+type broadcast3 struct {
+	// a party can only open its commitment in one way, so the reveal doesn't need to be reliable.
+	round.NormalBroadcastContent
+	Rho          types.RID
+	Decommitment hash.Decommitment
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - verify that ρⱼ opens the commitment Vⱼ.
+func (r *round3) StoreBroadcastMessage(msg round.Message) error {
+	from := msg.From
+	body, ok := msg.Content.(*broadcast3)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+	if err := body.Rho.Validate(); err != nil {
+		return err
+	}
+	if err := body.Decommitment.Validate(); err != nil {
+		return err
+	}
+	if !r.HashForID(from).Decommit(r.Commitments[from], body.Decommitment, body.Rho) {
+		return errors.New("failed to decommit")
+	}
+	r.Rho[from] = body.Rho
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round3) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round3) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - set ρ = ⊕ⱼ ρⱼ, and output SSID = H(sid, ρ).
+func (r *round3) Finalize(chan<- *round.Message) (round.Session, error) {
+	rho := types.EmptyRID()
+	for _, j := range r.PartyIDs() {
+		rho.XOR(r.Rho[j])
+	}
+	h := r.Hash()
+	_ = h.WriteAny(rho)
+	ssid := h.Sum()
+
+	if r.next == nil {
+		return r.ResultRound(ssid), nil
+	}
+	return r.startNext(ssid)
+}
+
+// MessageContent implements round.Round.
+func (round3) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast3) RoundNumber() round.Number { return 3 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (round3) BroadcastContent() round.BroadcastContent { return &broadcast3{} }
+
+// Number implements round.Round.
+func (round3) Number() round.Number { return 3 }
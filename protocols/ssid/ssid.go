@@ -0,0 +1,92 @@
+// Package ssid implements a commit-reveal protocol with which parties agree on a fresh session
+// identifier, to be given as the sessionID of another protocol.
+//
+// Every party commits to some randomness, which is only revealed once all commitments have been
+// reliably broadcast, so that the output is unpredictable as long as one party is honest.
+package ssid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+const (
+	protocolID = "ssid/commit-reveal"
+	// This protocol has 3 concrete rounds.
+	protocolRounds round.Number = 3
+)
+
+// These assert that our rounds implement the round.Round interface.
+var (
+	_ round.Round = (*round1)(nil)
+	_ round.Round = (*round2)(nil)
+	_ round.Round = (*round3)(nil)
+)
+
+// Agree returns a StartFunc for the protocol, whose result is the agreed SSID as a []byte.
+//
+// The sessionID given to the StartFunc is optional, since the output is random anyway.
+func Agree(selfID party.ID, participants []party.ID) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		return start(selfID, participants, nil, sessionID)
+	}
+}
+
+// Chain returns a StartFunc which first agrees on an SSID, and then continues with the protocol
+// created by next, using the agreed SSID as its sessionID. Both run through the same handler,
+// whose result is the one of next.
+//
+// next must have the same participants.
+func Chain(selfID party.ID, participants []party.ID, next protocol.StartFunc) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if next == nil {
+			return nil, errors.New("ssid.Chain: missing protocol")
+		}
+		return start(selfID, participants, next, sessionID)
+	}
+}
+
+func start(selfID party.ID, participants []party.ID, next protocol.StartFunc, sessionID []byte) (round.Session, error) {
+	helper, err := round.NewSession(round.Info{
+		ProtocolID:       protocolID,
+		FinalRoundNumber: protocolRounds,
+		SelfID:           selfID,
+		PartyIDs:         participants,
+	}, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ssid: %w", err)
+	}
+	return &round1{Helper: helper, next: next}, nil
+}
+
+// startNext creates the first round of the next protocol, and checks that it is run by the same parties.
+func (r *round1) startNext(ssid []byte) (round.Session, error) {
+	s, err := r.next(ssid)
+	if err != nil {
+		return nil, fmt.Errorf("ssid: failed to start next protocol: %w", err)
+	}
+	if s.SelfID() != r.SelfID() || !samePartyIDs(s.PartyIDs(), r.PartyIDs()) {
+		return nil, errors.New("ssid: next protocol has different participants")
+	}
+	if bytes.Equal(s.SSID(), r.SSID()) {
+		return nil, errors.New("ssid: next protocol ignores the session ID")
+	}
+	return s, nil
+}
+
+func samePartyIDs(a, b party.IDSlice) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package ssid
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func run(t *testing.T, partyIDs party.IDSlice, start func(id party.ID) protocol.StartFunc) map[party.ID]interface{} {
+	network := test.NewNetwork(partyIDs)
+	var mtx sync.Mutex
+	results := make(map[party.ID]interface{}, len(partyIDs))
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(start(id), nil)
+			require.NoError(t, err)
+			test.HandlerLoop(id, h, network)
+			result, err := h.Result()
+			require.NoError(t, err)
+			mtx.Lock()
+			results[id] = result
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestAgree(t *testing.T) {
+	partyIDs := test.PartyIDs(4)
+	agree := func(id party.ID) protocol.StartFunc { return Agree(id, partyIDs) }
+
+	first := run(t, partyIDs, agree)
+	ssid := first[partyIDs[0]].([]byte)
+	assert.NotEmpty(t, ssid)
+	for _, id := range partyIDs {
+		assert.Equal(t, ssid, first[id], "all parties should output the same SSID")
+	}
+
+	second := run(t, partyIDs, agree)
+	assert.NotEqual(t, ssid, second[partyIDs[0]], "every execution should output a new SSID")
+}
+
+func TestChain(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+	results := run(t, partyIDs, func(id party.ID) protocol.StartFunc {
+		return Chain(id, partyIDs, frost.Keygen(group, id, partyIDs, 1))
+	})
+	publicKey := results[partyIDs[0]].(*frost.Config).PublicKey
+	for _, id := range partyIDs {
+		assert.True(t, publicKey.Equal(results[id].(*frost.Config).PublicKey))
+	}
+
+	// the next protocol must be run by the same parties.
+	h, err := protocol.NewMultiHandler(Chain(partyIDs[0], partyIDs, frost.Keygen(group, partyIDs[0], partyIDs[:2], 1)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, protocolID, (<-h.Listen()).Protocol)
+	_, err = Chain(partyIDs[0], partyIDs, nil)(nil)
+	assert.Error(t, err)
+}
+
+// TestChainEarlyMessages delivers the messages of the next protocol to a party before the
+// reveals it needs to finish the agreement, after it was sent messages for other sessions
+// which fill its buffer.
+func TestChainEarlyMessages(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+	late := partyIDs[0]
+	handlers := make(map[party.ID]*protocol.MultiHandler, len(partyIDs))
+	var queue []*protocol.Message
+	drain := func(h *protocol.MultiHandler) {
+		for {
+			select {
+			case msg, ok := <-h.Listen():
+				if !ok {
+					return
+				}
+				queue = append(queue, msg)
+			default:
+				return
+			}
+		}
+	}
+	for _, id := range partyIDs {
+		h, err := protocol.NewMultiHandler(Chain(id, partyIDs, frost.Keygen(group, id, partyIDs, 1)), nil)
+		require.NoError(t, err)
+		handlers[id] = h
+		drain(h)
+	}
+
+	// the messages for other sessions are evicted by the ones for the next protocol.
+	for _, id := range partyIDs {
+		for i := 0; id != late && i < 8; i++ {
+			handlers[late].Accept(&protocol.Message{
+				SSID:        []byte{byte(i)},
+				From:        id,
+				Protocol:    "frost/keygen-threshold-default",
+				RoundNumber: 2,
+				Data:        []byte{1},
+				Broadcast:   true,
+			})
+		}
+	}
+	assert.EqualValues(t, 8, handlers[late].Dropped()[protocol.RejectBufferFull])
+
+	held := func(msg *protocol.Message) bool {
+		return msg.Protocol == protocolID && msg.RoundNumber == 3 && msg.IsFor(late)
+	}
+	earlyMessages := 0
+	for len(queue) > 0 {
+		// deliver the first message which isn't held back, or the held ones once nothing else is left.
+		i := 0
+		for i < len(queue) && held(queue[i]) {
+			i++
+		}
+		if i == len(queue) {
+			i = 0
+		}
+		msg := queue[i]
+		queue = append(queue[:i], queue[i+1:]...)
+		for _, id := range partyIDs {
+			if id == msg.From || !msg.IsFor(id) {
+				continue
+			}
+			if id == late && msg.Protocol != protocolID && !bytes.Equal(handlers[late].SSID(), msg.SSID) {
+				earlyMessages++
+			}
+			handlers[id].Accept(msg)
+			drain(handlers[id])
+		}
+	}
+	assert.NotZero(t, earlyMessages)
+
+	var publicKey curve.Point
+	for _, id := range partyIDs {
+		result, err := handlers[id].Result()
+		require.NoError(t, err)
+		if publicKey == nil {
+			publicKey = result.(*frost.Config).PublicKey
+		}
+		assert.True(t, publicKey.Equal(result.(*frost.Config).PublicKey))
+	}
+}
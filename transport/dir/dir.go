@@ -0,0 +1,171 @@
+// Package dir exchanges protocol messages through a shared directory, for parties running
+// as separate processes on the same machine, or on machines sharing a file system.
+//
+// Every message is written to its own file, in a subdirectory per session named after the
+// hex encoded SSID, and each party periodically reads the files it hasn't seen yet.
+// Files are written under a temporary name and then renamed, so that a party never reads
+// a partial message, and a message is never modified once written. The directory can
+// therefore also be copied between air-gapped machines, as long as no file is removed
+// before every party has finished.
+//
+// Like a relay, the directory is not trusted: anybody who can write to it can inject
+// messages, so these should be authenticated and encrypted end-to-end with
+// protocol.WithIdentities unless the directory is private to the parties.
+package dir
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// DefaultPollInterval is the time between two reads of the directory.
+const DefaultPollInterval = 100 * time.Millisecond
+
+// messageExt is the extension of message files, which temporary files don't have.
+const messageExt = ".msg"
+
+// Mailbox writes and reads the messages of one party in a shared directory.
+type Mailbox struct {
+	// Path is the shared directory, created if needed.
+	Path string
+	// ID is the party.ID of this party.
+	ID party.ID
+	// PollInterval is the time between two reads of the directory, DefaultPollInterval if zero.
+	PollInterval time.Duration
+}
+
+// New returns a Mailbox for party id in the directory at path.
+func New(path string, id party.ID) *Mailbox {
+	return &Mailbox{Path: path, ID: id}
+}
+
+func (m *Mailbox) session(ssid []byte) string {
+	return filepath.Join(m.Path, hex.EncodeToString(ssid))
+}
+
+func (m *Mailbox) pollInterval() time.Duration {
+	if m.PollInterval > 0 {
+		return m.PollInterval
+	}
+	return DefaultPollInterval
+}
+
+// Send writes msg to the directory of its session.
+//
+// The file is named after the hash of the message, so writing the same message twice is harmless.
+func (m *Mailbox) Send(msg *protocol.Message) error {
+	if len(msg.SSID) == 0 {
+		return errors.New("dir: message without SSID")
+	}
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("dir: %w", err)
+	}
+	session := m.session(msg.SSID)
+	if err = os.MkdirAll(session, 0o700); err != nil {
+		return fmt.Errorf("dir: %w", err)
+	}
+	digest := sha256.Sum256(data)
+	name := filepath.Join(session, hex.EncodeToString(digest[:])+messageExt)
+
+	tmp, err := os.CreateTemp(session, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("dir: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		return fmt.Errorf("dir: %w", err)
+	}
+	return nil
+}
+
+// Receive returns the messages for this party in session ssid which are not in seen, and adds their files to seen.
+// The directory is not trusted, so these must still be authenticated by the handler.
+func (m *Mailbox) Receive(ssid []byte, seen map[string]bool) ([]*protocol.Message, error) {
+	entries, err := os.ReadDir(m.session(ssid))
+	if errors.Is(err, fs.ErrNotExist) {
+		// nobody has sent anything yet.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dir: %w", err)
+	}
+	var messages []*protocol.Message
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasSuffix(name, messageExt) || seen[name] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.session(ssid), name))
+		if err != nil {
+			return nil, fmt.Errorf("dir: %w", err)
+		}
+		seen[name] = true
+		msg := &protocol.Message{}
+		if err = msg.UnmarshalBinary(data); err != nil || !msg.IsFor(m.ID) || !bytes.Equal(msg.SSID, ssid) {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// HandlerLoop blocks until the handler has finished, exchanging its messages for session ssid
+// through the directory. The result of the execution is given by Handler.Result().
+//
+// Messages are written before HandlerLoop returns. If ctx is done first, or the directory can't be
+// read or written, the error is returned.
+func HandlerLoop(ctx context.Context, h protocol.Handler, m *Mailbox, ssid []byte) error {
+	ticker := time.NewTicker(m.pollInterval())
+	defer ticker.Stop()
+	seen := make(map[string]bool)
+
+	for {
+		select {
+
+		// outgoing messages
+		case msg, ok := <-h.Listen():
+			if !ok {
+				// the channel was closed, indicating that the protocol is done executing.
+				return nil
+			}
+			if err := m.Send(msg); err != nil {
+				return err
+			}
+
+		// incoming messages
+		case <-ticker.C:
+			messages, err := m.Receive(ssid, seen)
+			if err != nil {
+				return err
+			}
+			for _, msg := range messages {
+				h.Accept(msg)
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
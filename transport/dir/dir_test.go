@@ -0,0 +1,91 @@
+package dir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailbox(t *testing.T) {
+	path := t.TempDir()
+	partyIDs := test.PartyIDs(3)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+	ssid := []byte("ssid")
+
+	mailbox := New(path, a)
+	toB := &protocol.Message{SSID: ssid, From: a, To: b, RoundNumber: 2, Data: []byte{1}}
+	toAll := &protocol.Message{SSID: ssid, From: a, RoundNumber: 2, Data: []byte{2}}
+	require.NoError(t, mailbox.Send(toB))
+	// writing the same message twice only stores it once.
+	require.NoError(t, mailbox.Send(toB))
+	require.NoError(t, mailbox.Send(toAll))
+	require.NoError(t, mailbox.Send(&protocol.Message{SSID: []byte("other"), From: a, Data: []byte{3}}))
+	assert.Error(t, mailbox.Send(&protocol.Message{From: a, Data: []byte{4}}))
+
+	// partial or foreign files are ignored.
+	session := filepath.Join(path, "73736964")
+	require.NoError(t, os.WriteFile(filepath.Join(session, ".tmp-1"), []byte{1}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(session, "invalid.msg"), []byte{1}, 0o600))
+
+	seen := map[string]bool{}
+	messages, err := New(path, b).Receive(ssid, seen)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Len(t, seen, 3)
+	messages, err = New(path, b).Receive(ssid, seen)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	messages, err = New(path, c).Receive(ssid, map[string]bool{})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, toAll.Data, messages[0].Data)
+
+	messages, err = New(path, c).Receive([]byte("none"), map[string]bool{})
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestHandlerLoop(t *testing.T) {
+	path := t.TempDir()
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+
+	var mtx sync.Mutex
+	results := make(map[party.ID]*frost.Config, len(partyIDs))
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(frost.Keygen(group, id, partyIDs, 1), []byte("session"))
+			require.NoError(t, err)
+			mailbox := New(path, id)
+			mailbox.PollInterval = 10 * time.Millisecond
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			require.NoError(t, HandlerLoop(ctx, h, mailbox, h.SSID()))
+			result, err := h.Result()
+			require.NoError(t, err)
+			mtx.Lock()
+			results[id] = result.(*frost.Config)
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	for _, id := range partyIDs {
+		assert.True(t, results[partyIDs[0]].PublicKey.Equal(results[id].PublicKey))
+	}
+}
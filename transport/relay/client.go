@@ -0,0 +1,255 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	// pollWait is the duration of a long poll.
+	pollWait = 30 * time.Second
+	// minBackoff and maxBackoff bound the time between two failed requests.
+	minBackoff = 50 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+// Client posts and receives the messages of one party through a relay.
+type Client struct {
+	// URL is the base URL of the relay.
+	URL string
+	// ID is the party.ID of this party.
+	ID party.ID
+	// HTTPClient is used for all requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// Stream receives messages over a single streamed response, instead of long polling.
+	Stream bool
+}
+
+// NewClient returns a Client for party id, connecting to the relay at url.
+func NewClient(url string, id party.ID) *Client {
+	return &Client{URL: url, ID: id}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) endpoint(ssid []byte) string {
+	return fmt.Sprintf("%s/sessions/%s/messages", c.URL, hex.EncodeToString(ssid))
+}
+
+// Send posts msg to the mailbox of its session.
+//
+// Posting the same message twice is harmless, so failed requests can simply be retried.
+func (c *Client) Send(ctx context.Context, msg *protocol.Message) error {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("relay: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(msg.SSID), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("relay: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/cbor")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("relay: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// Receive returns the messages for this party posted in session ssid after the first `after` ones,
+// as well as the cursor to use for the next call. It waits up to `wait` for at least one message.
+func (c *Client) Receive(ctx context.Context, ssid []byte, after uint64, wait time.Duration) ([]*protocol.Message, uint64, error) {
+	resp, err := c.get(ctx, ssid, after, url.Values{"wait": {wait.String()}})
+	if err != nil {
+		return nil, after, err
+	}
+	defer resp.Body.Close()
+	batch := &Batch{}
+	if err = cbor.NewDecoder(resp.Body).Decode(batch); err != nil {
+		return nil, after, fmt.Errorf("relay: %w", err)
+	}
+	return c.parse(ssid, batch), batch.Next, nil
+}
+
+// stream delivers the messages for this party to out, until the response ends,
+// and returns the cursor for the next request.
+func (c *Client) stream(ctx context.Context, ssid []byte, after uint64, out chan<- *protocol.Message) (uint64, error) {
+	resp, err := c.get(ctx, ssid, after, url.Values{"stream": {"1"}})
+	if err != nil {
+		return after, err
+	}
+	defer resp.Body.Close()
+	dec := cbor.NewDecoder(resp.Body)
+	for {
+		batch := &Batch{}
+		if err = dec.Decode(batch); err != nil {
+			return after, fmt.Errorf("relay: %w", err)
+		}
+		for _, msg := range c.parse(ssid, batch) {
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return after, ctx.Err()
+			}
+		}
+		after = batch.Next
+	}
+}
+
+func (c *Client) get(ctx context.Context, ssid []byte, after uint64, query url.Values) (*http.Response, error) {
+	query.Set("to", string(c.ID))
+	query.Set("after", strconv.FormatUint(after, 10))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(ssid)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("relay: %w", err)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("relay: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// parse returns the messages of a batch, dropping those which aren't for us or from another session.
+// The relay is not trusted, so these must still be authenticated by the handler.
+func (c *Client) parse(ssid []byte, batch *Batch) []*protocol.Message {
+	messages := make([]*protocol.Message, 0, len(batch.Messages))
+	for _, data := range batch.Messages {
+		msg := &protocol.Message{}
+		if err := msg.UnmarshalBinary(data); err != nil || !msg.IsFor(c.ID) || !bytes.Equal(msg.SSID, ssid) {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// receive delivers the messages for this party to out, retrying failed requests until ctx is done.
+func (c *Client) receive(ctx context.Context, ssid []byte, out chan<- *protocol.Message) {
+	var after uint64
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		var err error
+		if c.Stream {
+			after, err = c.stream(ctx, ssid, after, out)
+		} else {
+			var messages []*protocol.Message
+			messages, after, err = c.Receive(ctx, ssid, after, pollWait)
+			for _, msg := range messages {
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		if err == nil {
+			backoff = minBackoff
+			continue
+		}
+		if !sleep(ctx, backoff) {
+			return
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// HandlerLoop blocks until the handler has finished, exchanging its messages for session ssid
+// through the relay. The result of the execution is given by Handler.Result().
+//
+// Messages are posted before HandlerLoop returns, and failed requests are retried until ctx is done,
+// in which case the error of ctx is returned.
+func HandlerLoop(ctx context.Context, h protocol.Handler, c *Client, ssid []byte) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	incoming := make(chan *protocol.Message)
+	go c.receive(ctx, ssid, incoming)
+
+	for {
+		select {
+
+		// outgoing messages
+		case msg, ok := <-h.Listen():
+			if !ok {
+				// the channel was closed, indicating that the protocol is done executing.
+				return nil
+			}
+			for backoff := minBackoff; ; backoff = min(2*backoff, maxBackoff) {
+				err := c.Send(ctx, msg)
+				if err == nil {
+					break
+				}
+				// the relay rejected the message, so sending it again won't help.
+				var status *statusError
+				if errors.As(err, &status) && status.code < http.StatusInternalServerError {
+					return err
+				}
+				if !sleep(ctx, backoff) {
+					return ctx.Err()
+				}
+			}
+
+		// incoming messages
+		case msg := <-incoming:
+			h.Accept(msg)
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// statusError is returned when the relay responds with an unexpected status.
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return "relay: " + e.message
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	message := resp.Status
+	if body = bytes.TrimSpace(body); len(body) > 0 {
+		message += ": " + string(body)
+	}
+	return &statusError{code: resp.StatusCode, message: message}
+}
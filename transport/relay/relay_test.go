@@ -0,0 +1,147 @@
+package relay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailbox(t *testing.T) {
+	server := httptest.NewServer(NewServer(time.Minute))
+	defer server.Close()
+	partyIDs := test.PartyIDs(3)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+	ssid := []byte("ssid")
+	ctx := context.Background()
+
+	client := NewClient(server.URL, a)
+	toB := &protocol.Message{SSID: ssid, From: a, To: b, RoundNumber: 2, Data: []byte{1}}
+	toAll := &protocol.Message{SSID: ssid, From: a, RoundNumber: 2, Data: []byte{2}}
+	require.NoError(t, client.Send(ctx, toB))
+	// posting the same message twice only stores it once.
+	require.NoError(t, client.Send(ctx, toB))
+	require.NoError(t, client.Send(ctx, toAll))
+	require.NoError(t, client.Send(ctx, &protocol.Message{SSID: []byte("other"), From: a, Data: []byte{3}}))
+
+	messages, next, err := NewClient(server.URL, b).Receive(ctx, ssid, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, toB.Data, messages[0].Data)
+	assert.Equal(t, toAll.Data, messages[1].Data)
+	assert.EqualValues(t, 2, next)
+
+	messages, _, err = NewClient(server.URL, c).Receive(ctx, ssid, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, toAll.Data, messages[0].Data)
+
+	// a long poll returns once a message is posted.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = NewClient(server.URL, c).Send(ctx, &protocol.Message{SSID: ssid, From: c, To: b, Data: []byte{4}})
+	}()
+	messages, next, err = NewClient(server.URL, b).Receive(ctx, ssid, next, 10*time.Second)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, []byte{4}, messages[0].Data)
+	assert.EqualValues(t, 3, next)
+
+	// messages from another session are rejected.
+	err = client.Send(ctx, &protocol.Message{SSID: nil, From: a, Data: []byte{5}})
+	assert.Error(t, err)
+}
+
+func TestServerLimits(t *testing.T) {
+	relay := NewServer(time.Minute)
+	relay.MaxSessions = 1
+	server := httptest.NewServer(relay)
+	defer server.Close()
+	a, b := party.ID("a"), party.ID("b")
+	ctx := context.Background()
+	client := NewClient(server.URL, a)
+
+	// receiving doesn't create sessions, so it can't exhaust them.
+	for _, ssid := range []string{"x", "y", "z"} {
+		messages, next, err := NewClient(server.URL, b).Receive(ctx, []byte(ssid), 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		assert.Zero(t, next)
+	}
+	assert.Empty(t, relay.sessions)
+
+	// a long poll for a session which doesn't exist yet returns once it is created.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = client.Send(ctx, &protocol.Message{SSID: []byte("ssid"), From: a, Data: []byte{1}})
+	}()
+	messages, _, err := NewClient(server.URL, b).Receive(ctx, []byte("ssid"), 0, 10*time.Second)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	var status *statusError
+	err = client.Send(ctx, &protocol.Message{SSID: []byte("other"), From: a, Data: []byte{2}})
+	require.ErrorAs(t, err, &status)
+	assert.Equal(t, http.StatusServiceUnavailable, status.code)
+
+	relay.MaxBytes = relay.bytes
+	err = client.Send(ctx, &protocol.Message{SSID: []byte("ssid"), From: a, Data: []byte{3}})
+	require.ErrorAs(t, err, &status)
+	assert.Equal(t, http.StatusInsufficientStorage, status.code)
+	// a message posted again is still accepted, since it isn't stored twice.
+	require.NoError(t, client.Send(ctx, &protocol.Message{SSID: []byte("ssid"), From: a, Data: []byte{1}}))
+
+	// expired sessions free their space.
+	relay.TTL = time.Millisecond
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, client.Send(ctx, &protocol.Message{SSID: []byte("abcd"), From: a, Data: []byte{2}}))
+	assert.Len(t, relay.sessions, 1)
+}
+
+func TestHandlerLoop(t *testing.T) {
+	server := httptest.NewServer(NewServer(time.Minute))
+	defer server.Close()
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+
+	for i, stream := range []bool{false, true} {
+		// each execution needs its own session.
+		sessionID := []byte{byte(i)}
+		var mtx sync.Mutex
+		results := make(map[party.ID]*frost.Config, len(partyIDs))
+		var wg sync.WaitGroup
+		for _, id := range partyIDs {
+			wg.Add(1)
+			go func(id party.ID) {
+				defer wg.Done()
+				h, err := protocol.NewMultiHandler(frost.Keygen(group, id, partyIDs, 1), sessionID)
+				require.NoError(t, err)
+				client := NewClient(server.URL, id)
+				client.Stream = stream
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				require.NoError(t, HandlerLoop(ctx, h, client, h.SSID()))
+				result, err := h.Result()
+				require.NoError(t, err)
+				mtx.Lock()
+				results[id] = result.(*frost.Config)
+				mtx.Unlock()
+			}(id)
+		}
+		wg.Wait()
+
+		for _, id := range partyIDs {
+			assert.True(t, results[partyIDs[0]].PublicKey.Equal(results[id].PublicKey))
+		}
+	}
+}
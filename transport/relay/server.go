@@ -0,0 +1,283 @@
+// Package relay implements a store-and-forward server for protocol messages, and its client.
+//
+// Parties which can't reach each other directly post their messages to the relay, which keeps
+// them in a mailbox per session, identified by the SSID. Each party then polls or streams the
+// messages intended for it. The relay only reads the headers of the messages, and never needs
+// any key material: messages should be authenticated and encrypted end-to-end with
+// protocol.WithIdentities, since anybody can read a mailbox.
+//
+// The HTTP API is
+//
+//	POST /sessions/{ssid}/messages                        body: Message.MarshalBinary()
+//	GET  /sessions/{ssid}/messages?to={id}&after={n}&wait={duration}
+//	GET  /sessions/{ssid}/messages?to={id}&after={n}&stream=1
+//
+// where ssid is hex encoded. Messages are numbered in the order they were posted, and a GET returns
+// a CBOR encoded Batch with the messages for `to` which were posted after the first `after` ones.
+// With wait, the request blocks until there is at least one message, and with stream, the response
+// is a sequence of Batch which ends when the client disconnects.
+//
+// Sessions are only created by posting messages. The number of sessions and the total size of
+// their messages are bounded, and further messages are rejected until sessions expire.
+package relay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	// DefaultTTL is the time after which a session without new messages is deleted.
+	DefaultTTL = time.Hour
+	// MaxMessageSize bounds the size of a single message.
+	MaxMessageSize = 32 << 20
+	// MaxMessages bounds the number of messages of a session.
+	MaxMessages = 1 << 16
+	// DefaultMaxSessions is the default bound on the number of sessions of a Server.
+	DefaultMaxSessions = 1 << 12
+	// DefaultMaxBytes is the default bound on the total size of the messages stored by a Server.
+	DefaultMaxBytes = 1 << 30
+	// maxWait bounds the duration of a long poll.
+	maxWait = time.Minute
+)
+
+// Batch is the response to a GET request.
+type Batch struct {
+	// Next is the number of messages posted so far, and should be used as `after` in the next request.
+	Next uint64
+	// Messages contains the marshalled messages for the recipient.
+	Messages [][]byte
+}
+
+// Server is an http.Handler relaying messages between parties.
+type Server struct {
+	// TTL is the time after which a session without new messages is deleted.
+	TTL time.Duration
+	// MaxSessions bounds the number of sessions. Messages for a new session are rejected
+	// until enough sessions expire.
+	MaxSessions int
+	// MaxBytes bounds the total size of the messages of all sessions. Messages are rejected
+	// until enough sessions expire.
+	MaxBytes int64
+
+	mux       *http.ServeMux
+	mtx       sync.Mutex
+	sessions  map[string]*session
+	bytes     int64
+	lastSweep time.Time
+	// created is closed and replaced whenever a session is created, for the requests waiting for it.
+	created chan struct{}
+}
+
+type entry struct {
+	from, to party.ID
+	data     []byte
+}
+
+// session is the mailbox of a protocol execution.
+type session struct {
+	messages []entry
+	// digests contains the hashes of the messages, so that messages posted twice are only stored once.
+	digests map[[sha256.Size]byte]bool
+	// updated is closed and replaced whenever a message is posted.
+	updated    chan struct{}
+	lastActive time.Time
+	// bytes is the total size of the messages.
+	bytes int64
+}
+
+// NewServer returns a Server deleting sessions after ttl without new messages,
+// with DefaultMaxSessions and DefaultMaxBytes as bounds.
+func NewServer(ttl time.Duration) *Server {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	s := &Server{
+		TTL:         ttl,
+		MaxSessions: DefaultMaxSessions,
+		MaxBytes:    DefaultMaxBytes,
+		mux:         http.NewServeMux(),
+		sessions:    map[string]*session{},
+		lastSweep:   time.Now(),
+		created:     make(chan struct{}),
+	}
+	s.mux.HandleFunc("POST /sessions/{ssid}/messages", s.post)
+	s.mux.HandleFunc("GET /sessions/{ssid}/messages", s.get)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// sweep deletes the expired sessions. s.mtx must be held.
+func (s *Server) sweep() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) <= s.TTL/2 {
+		return
+	}
+	for id, m := range s.sessions {
+		if now.Sub(m.lastActive) > s.TTL {
+			s.bytes -= m.bytes
+			delete(s.sessions, id)
+		}
+	}
+	s.lastSweep = now
+}
+
+func (s *Server) post(w http.ResponseWriter, r *http.Request) {
+	ssid, err := hex.DecodeString(r.PathValue("ssid"))
+	if err != nil || len(ssid) == 0 {
+		http.Error(w, "invalid ssid", http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, MaxMessageSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > MaxMessageSize {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	msg := &protocol.Message{}
+	if err = msg.UnmarshalBinary(data); err != nil || msg.From == "" || !bytes.Equal(msg.SSID, ssid) {
+		http.Error(w, "invalid message", http.StatusBadRequest)
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.sweep()
+	m := s.sessions[r.PathValue("ssid")]
+	digest := sha256.Sum256(data)
+	if m != nil && m.digests[digest] {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if s.bytes+int64(len(data)) > s.MaxBytes {
+		http.Error(w, "relay is full", http.StatusInsufficientStorage)
+		return
+	}
+	if m == nil {
+		if len(s.sessions) >= s.MaxSessions {
+			http.Error(w, "too many sessions", http.StatusServiceUnavailable)
+			return
+		}
+		m = &session{
+			digests: map[[sha256.Size]byte]bool{},
+			updated: make(chan struct{}),
+		}
+		s.sessions[r.PathValue("ssid")] = m
+		close(s.created)
+		s.created = make(chan struct{})
+	}
+	if len(m.messages) >= MaxMessages {
+		http.Error(w, "too many messages", http.StatusInsufficientStorage)
+		return
+	}
+	m.digests[digest] = true
+	m.messages = append(m.messages, entry{from: msg.From, to: msg.To, data: data})
+	m.bytes += int64(len(data))
+	s.bytes += int64(len(data))
+	m.lastActive = time.Now()
+	close(m.updated)
+	m.updated = make(chan struct{})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	if _, err := hex.DecodeString(r.PathValue("ssid")); err != nil {
+		http.Error(w, "invalid ssid", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	to := party.ID(query.Get("to"))
+	if to == "" {
+		http.Error(w, "missing recipient", http.StatusBadRequest)
+		return
+	}
+	var after uint64
+	if v := query.Get("after"); v != "" {
+		var err error
+		if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+	var wait time.Duration
+	if v := query.Get("wait"); v != "" {
+		var err error
+		if wait, err = time.ParseDuration(v); err != nil || wait < 0 {
+			http.Error(w, "invalid wait", http.StatusBadRequest)
+			return
+		}
+		wait = min(wait, maxWait)
+	}
+	stream := query.Get("stream") != ""
+
+	ssid := r.PathValue("ssid")
+	w.Header().Set("Content-Type", "application/cbor")
+	enc := cbor.NewEncoder(w)
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		batch, updated := s.collect(ssid, to, after)
+		after = batch.Next
+		if len(batch.Messages) > 0 || (!stream && wait == 0) {
+			if err := enc.Encode(batch); err != nil || !stream {
+				return
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		select {
+		case <-updated:
+		case <-timeout:
+			_ = enc.Encode(batch)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// collect returns the messages of session ssid for `to` posted after the first `after` ones, and
+// a channel which is closed when new messages are posted.
+//
+// If the session has fewer messages than `after`, then the relay was restarted, and the cursor
+// is moved back to the current number of messages. Sessions are only created by posting
+// messages, so if there is no session yet, the channel is closed when any session is created.
+func (s *Server) collect(ssid string, to party.ID, after uint64) (*Batch, <-chan struct{}) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.sweep()
+	m := s.sessions[ssid]
+	if m == nil {
+		return &Batch{}, s.created
+	}
+	batch := &Batch{Next: uint64(len(m.messages))}
+	for i := after; i < uint64(len(m.messages)); i++ {
+		e := m.messages[i]
+		if e.from != to && (e.to == "" || e.to == to) {
+			batch.Messages = append(batch.Messages, e.data)
+		}
+	}
+	return batch, m.updated
+}
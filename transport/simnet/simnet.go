@@ -0,0 +1,376 @@
+// Package simnet runs protocol handlers over a simulated network, in order to test them against
+// lost, delayed, reordered and duplicated messages, and network partitions.
+//
+// The simulation is deterministic: handlers are driven one message at a time, in virtual time,
+// and every fault is drawn from a random source seeded with Network.Seed. A failing execution can
+// therefore be replayed by running it again with the same seed, and Network.Trace lists the fate
+// of every message. The contents of the messages still vary between runs, since protocols sample
+// their secrets from crypto/rand, but the schedule only depends on the headers.
+//
+// Handlers must not rely on wall-clock time, so they should be created without a round timeout.
+package simnet
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+const (
+	// DefaultLatency is the latency of the links of a new Network.
+	DefaultLatency = 10 * time.Millisecond
+	// DefaultMaxEvents bounds the number of messages delivered by Run.
+	DefaultMaxEvents = 1 << 20
+)
+
+// ErrStalled is returned by Run when some handlers are not done, and no message is left to deliver.
+var ErrStalled = errors.New("simnet: stalled")
+
+// Link describes the faults of the messages sent from one party to another.
+// Probabilities are between 0 and 1.
+type Link struct {
+	// Latency is the minimum delay of a message.
+	Latency time.Duration
+	// Jitter is the maximum random delay added to Latency.
+	Jitter time.Duration
+	// Drop is the probability that a message is lost.
+	Drop float64
+	// Duplicate is the probability that a message is delivered a second time, after another delay.
+	Duplicate float64
+	// Reorder is the probability that a message is held back by up to ReorderDelay,
+	// so that the messages sent after it overtake it.
+	Reorder float64
+	// ReorderDelay is the maximum delay added to the messages which are held back.
+	ReorderDelay time.Duration
+}
+
+// partition separates groups of parties between two instants.
+type partition struct {
+	from, until time.Duration
+	group       map[party.ID]int
+}
+
+// node is a handler taking part in the simulation.
+type node struct {
+	id     party.ID
+	h      protocol.Handler
+	listen <-chan *protocol.Message
+}
+
+// Network delivers the messages of handlers according to the faults of its links.
+//
+// It is not safe for concurrent use: handlers are added, and the network is configured, before calling Run.
+type Network struct {
+	// Seed is the seed given to New, from which all faults are drawn. Logging it allows replaying a failing run.
+	Seed int64
+	// Link applies to the pairs of parties without a link set by SetLink.
+	Link Link
+	// MaxEvents bounds the number of messages delivered by Run, after which it fails.
+	MaxEvents int
+	// ResendRequests is the number of times Run asks the handlers for their missing messages,
+	// using MultiHandler.RequestMissing, when the execution is stalled. By default, Run fails instead.
+	ResendRequests int
+
+	rng        *rand.Rand
+	now        time.Duration
+	seq        uint64
+	nodes      map[party.ID]*node
+	links      map[[2]party.ID]Link
+	partitions []partition
+	queue      deliveries
+	trace      []Event
+}
+
+// New returns a Network whose faults are drawn from seed, and whose links have DefaultLatency,
+// without any other fault.
+func New(seed int64) *Network {
+	return &Network{
+		Seed:      seed,
+		Link:      Link{Latency: DefaultLatency},
+		MaxEvents: DefaultMaxEvents,
+		rng:       rand.New(rand.NewSource(seed)),
+		nodes:     map[party.ID]*node{},
+		links:     map[[2]party.ID]Link{},
+	}
+}
+
+// SetLink sets the faults of the messages sent by from to to.
+func (n *Network) SetLink(from, to party.ID, l Link) {
+	n.links[[2]party.ID{from, to}] = l
+}
+
+// Partition separates the given groups of parties from the virtual instant from until the instant until:
+// messages sent in the meantime between parties of different groups are dropped.
+// Parties which are not in any group can reach everybody.
+func (n *Network) Partition(from, until time.Duration, groups ...[]party.ID) {
+	p := partition{from: from, until: until, group: map[party.ID]int{}}
+	for i, group := range groups {
+		for _, id := range group {
+			p.group[id] = i
+		}
+	}
+	n.partitions = append(n.partitions, p)
+}
+
+// Add adds the handler of party id to the network. The messages it has output so far are sent by Run,
+// once all handlers have been added.
+func (n *Network) Add(id party.ID, h protocol.Handler) {
+	n.nodes[id] = &node{id: id, h: h, listen: h.Listen()}
+}
+
+// Now returns the virtual time elapsed since the start of the simulation.
+func (n *Network) Now() time.Duration {
+	return n.now
+}
+
+// Trace returns the events of the simulation so far, in order.
+func (n *Network) Trace() []Event {
+	return append([]Event(nil), n.trace...)
+}
+
+// Run delivers messages until every handler is done.
+//
+// It returns ErrStalled if some handlers are waiting for messages which will never arrive,
+// and an error if MaxEvents messages were delivered before the end.
+func (n *Network) Run() error {
+	for _, id := range n.ids() {
+		n.send(id, n.drain(n.nodes[id]))
+	}
+	requests := n.ResendRequests
+	for events := 0; ; events++ {
+		if events >= n.MaxEvents {
+			return fmt.Errorf("simnet: no result after %d events", events)
+		}
+		if n.queue.Len() > 0 {
+			n.deliver(heap.Pop(&n.queue).(*delivery))
+			continue
+		}
+		waiting := n.Waiting()
+		if len(waiting) == 0 {
+			return nil
+		}
+		if requests == 0 {
+			return fmt.Errorf("%w at %s: %v are not done", ErrStalled, n.now, waiting)
+		}
+		requests--
+		n.requestMissing(waiting)
+	}
+}
+
+// Waiting returns the parties whose handlers are not done.
+func (n *Network) Waiting() []party.ID {
+	var ids []party.ID
+	for _, id := range n.ids() {
+		if n.nodes[id].listen != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// requestMissing sends the resend requests of the given parties, once the partitions which may
+// have caused the stall are over.
+func (n *Network) requestMissing(ids []party.ID) {
+	for _, p := range n.partitions {
+		if p.from <= n.now && n.now < p.until {
+			n.now = p.until
+		}
+	}
+	for _, id := range ids {
+		if h, ok := n.nodes[id].h.(interface{ RequestMissing() []*protocol.Message }); ok {
+			n.send(id, h.RequestMissing())
+		}
+	}
+}
+
+// ids returns the parties of the network, sorted so that iterating over them is deterministic.
+func (n *Network) ids() []party.ID {
+	ids := make([]party.ID, 0, len(n.nodes))
+	for id := range n.nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// send schedules the delivery of messages sent by from to all their recipients.
+func (n *Network) send(from party.ID, messages []*protocol.Message) {
+	for _, msg := range messages {
+		for _, to := range n.ids() {
+			if msg.IsFor(to) {
+				n.sendTo(from, to, msg)
+			}
+		}
+	}
+}
+
+func (n *Network) sendTo(from, to party.ID, msg *protocol.Message) {
+	event := newEvent(n.now, from, to, msg)
+	if n.partitioned(from, to) {
+		event.Kind = EventPartitioned
+		n.trace = append(n.trace, event)
+		return
+	}
+	l, ok := n.links[[2]party.ID{from, to}]
+	if !ok {
+		l = n.Link
+	}
+	if n.rng.Float64() < l.Drop {
+		event.Kind = EventDropped
+		n.trace = append(n.trace, event)
+		return
+	}
+	n.schedule(to, msg, n.delay(l), false)
+	if n.rng.Float64() < l.Duplicate {
+		n.schedule(to, msg, n.delay(l), true)
+	}
+}
+
+// delay draws the delay of a message sent over l.
+func (n *Network) delay(l Link) time.Duration {
+	d := l.Latency
+	if l.Jitter > 0 {
+		d += time.Duration(n.rng.Int63n(int64(l.Jitter)))
+	}
+	if n.rng.Float64() < l.Reorder && l.ReorderDelay > 0 {
+		d += time.Duration(n.rng.Int63n(int64(l.ReorderDelay)))
+	}
+	return d
+}
+
+func (n *Network) partitioned(from, to party.ID) bool {
+	for _, p := range n.partitions {
+		if n.now < p.from || n.now >= p.until {
+			continue
+		}
+		i, okFrom := p.group[from]
+		j, okTo := p.group[to]
+		if okFrom && okTo && i != j {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Network) schedule(to party.ID, msg *protocol.Message, delay time.Duration, duplicate bool) {
+	n.seq++
+	heap.Push(&n.queue, &delivery{at: n.now + delay, seq: n.seq, to: to, msg: msg, duplicate: duplicate})
+}
+
+// deliver gives a message to its recipient, and sends the messages output in response.
+func (n *Network) deliver(d *delivery) {
+	n.now = d.at
+	event := newEvent(n.now, d.msg.From, d.to, d.msg)
+	event.Kind = EventDelivered
+	event.Duplicate = d.duplicate
+	nd := n.nodes[d.to]
+	if nd.listen == nil {
+		event.Reason = protocol.RejectFinished
+		n.trace = append(n.trace, event)
+		n.answerResendRequest(nd, d.msg)
+		return
+	}
+
+	// the handler is run in another goroutine, so that it can't block on a full Listen() channel.
+	accepted := make(chan error, 1)
+	go func() {
+		accepted <- accept(nd.h, d.msg)
+	}()
+	var out []*protocol.Message
+	for done := false; !done; {
+		select {
+		case msg, ok := <-nd.listen:
+			if !ok {
+				nd.listen = nil
+				continue
+			}
+			out = append(out, msg)
+		case err := <-accepted:
+			var rejected *protocol.RejectError
+			if errors.As(err, &rejected) {
+				event.Reason = rejected.Reason
+			}
+			done = true
+		}
+	}
+	out = append(out, n.drain(nd)...)
+	n.trace = append(n.trace, event)
+	n.send(nd.id, out)
+	if event.Reason == protocol.RejectFinished {
+		n.answerResendRequest(nd, d.msg)
+	}
+}
+
+// answerResendRequest answers the resend requests received by a handler which is done,
+// as a transport would.
+func (n *Network) answerResendRequest(nd *node, msg *protocol.Message) {
+	h, ok := nd.h.(*protocol.MultiHandler)
+	if ok && msg.Type == protocol.MessageResendRequest {
+		n.send(nd.id, h.Resend(msg.From, msg.RoundNumber))
+	}
+}
+
+// drain returns the messages which are ready on the Listen() channel of nd.
+func (n *Network) drain(nd *node) []*protocol.Message {
+	var out []*protocol.Message
+	for nd.listen != nil {
+		select {
+		case msg, ok := <-nd.listen:
+			if !ok {
+				nd.listen = nil
+				continue
+			}
+			out = append(out, msg)
+		default:
+			return out
+		}
+	}
+	return out
+}
+
+// accept gives msg to h, and returns the reason why it was dropped, if h can tell.
+func accept(h protocol.Handler, msg *protocol.Message) error {
+	if h, ok := h.(interface{ AcceptWithError(*protocol.Message) error }); ok {
+		return h.AcceptWithError(msg)
+	}
+	h.Accept(msg)
+	return nil
+}
+
+// delivery is a message in flight.
+type delivery struct {
+	at        time.Duration
+	seq       uint64
+	to        party.ID
+	msg       *protocol.Message
+	duplicate bool
+}
+
+// deliveries is a heap of messages in flight, ordered by delivery time, and then by the order in which they were sent.
+type deliveries []*delivery
+
+func (q deliveries) Len() int { return len(q) }
+
+func (q deliveries) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q deliveries) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *deliveries) Push(x interface{}) { *q = append(*q, x.(*delivery)) }
+
+func (q *deliveries) Pop() interface{} {
+	old := *q
+	d := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return d
+}
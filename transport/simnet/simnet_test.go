@@ -0,0 +1,128 @@
+package simnet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keygen runs frost.Keygen over n, and returns the handlers.
+func keygen(t *testing.T, n *Network, partyIDs party.IDSlice) (map[party.ID]*protocol.MultiHandler, error) {
+	handlers := make(map[party.ID]*protocol.MultiHandler, len(partyIDs))
+	for _, id := range partyIDs {
+		h, err := protocol.NewMultiHandler(frost.Keygen(curve.Secp256k1{}, id, partyIDs, 1), nil)
+		require.NoError(t, err)
+		handlers[id] = h
+		n.Add(id, h)
+	}
+	return handlers, n.Run()
+}
+
+func requireResults(t *testing.T, handlers map[party.ID]*protocol.MultiHandler) {
+	for id, h := range handlers {
+		_, err := h.Result()
+		require.NoError(t, err, "party %s", id)
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	partyIDs := test.PartyIDs(4)
+	n := New(1)
+	handlers, err := keygen(t, n, partyIDs)
+	require.NoError(t, err)
+	requireResults(t, handlers)
+
+	trace := n.Trace()
+	require.NotEmpty(t, trace)
+	for i, e := range trace {
+		assert.Equal(t, EventDelivered, e.Kind, e.String())
+		assert.False(t, e.Duplicate)
+		if i > 0 {
+			assert.GreaterOrEqual(t, e.Time, trace[i-1].Time)
+		}
+	}
+	assert.Equal(t, trace[len(trace)-1].Time, n.Now())
+}
+
+func TestFaults(t *testing.T) {
+	partyIDs := test.PartyIDs(4)
+	run := func(seed int64) ([]Event, error) {
+		n := New(seed)
+		n.Link = Link{
+			Latency:      5 * time.Millisecond,
+			Jitter:       20 * time.Millisecond,
+			Drop:         0.1,
+			Duplicate:    0.2,
+			Reorder:      0.3,
+			ReorderDelay: 100 * time.Millisecond,
+		}
+		n.ResendRequests = 100
+		handlers, err := keygen(t, n, partyIDs)
+		if err == nil {
+			requireResults(t, handlers)
+		}
+		return n.Trace(), err
+	}
+
+	trace, err := run(42)
+	require.NoError(t, err)
+	count := map[EventKind]int{}
+	duplicates := 0
+	for _, e := range trace {
+		count[e.Kind]++
+		if e.Duplicate {
+			duplicates++
+		}
+	}
+	assert.NotZero(t, count[EventDropped])
+	assert.NotZero(t, duplicates)
+
+	// the same seed gives the same schedule.
+	replayed, err := run(42)
+	require.NoError(t, err)
+	assert.Equal(t, trace, replayed)
+	other, err := run(43)
+	require.NoError(t, err)
+	assert.NotEqual(t, trace, other)
+}
+
+func TestPartition(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+
+	n := New(1)
+	n.Partition(0, time.Second, []party.ID{a}, []party.ID{b, c})
+	_, err := keygen(t, n, partyIDs)
+	assert.True(t, errors.Is(err, ErrStalled))
+	for _, e := range n.Trace() {
+		if (e.From == a) != (e.To == a) {
+			assert.Equal(t, EventPartitioned, e.Kind, e.String())
+		}
+	}
+
+	// once the partition is over, the missing messages are sent again.
+	n = New(1)
+	n.Partition(0, time.Second, []party.ID{a}, []party.ID{b, c})
+	n.ResendRequests = 10
+	handlers, err := keygen(t, n, partyIDs)
+	require.NoError(t, err)
+	requireResults(t, handlers)
+	assert.GreaterOrEqual(t, n.Now(), time.Second)
+
+	// a link can be configured for a single pair of parties.
+	n = New(1)
+	n.SetLink(a, b, Link{Drop: 1})
+	_, err = keygen(t, n, partyIDs)
+	assert.True(t, errors.Is(err, ErrStalled))
+	for _, e := range n.Trace() {
+		assert.Equal(t, e.From == a && e.To == b, e.Kind == EventDropped, e.String())
+	}
+}
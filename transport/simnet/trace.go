@@ -0,0 +1,83 @@
+package simnet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/common/round"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// EventKind tells what happened to a message.
+type EventKind uint8
+
+const (
+	// EventDelivered is a message given to its recipient.
+	EventDelivered EventKind = iota
+	// EventDropped is a message lost by its link.
+	EventDropped
+	// EventPartitioned is a message sent across a partition.
+	EventPartitioned
+)
+
+var eventKinds = [...]string{
+	EventDelivered:   "delivered",
+	EventDropped:     "dropped",
+	EventPartitioned: "partitioned",
+}
+
+// String implements fmt.Stringer.
+func (k EventKind) String() string {
+	if int(k) < len(eventKinds) {
+		return eventKinds[k]
+	}
+	return fmt.Sprintf("EventKind(%d)", k)
+}
+
+// Event is the fate of a message sent from one party to another.
+//
+// It only contains the headers of the message, so that the traces of two runs with the same seed are equal.
+type Event struct {
+	// Time is the virtual time at which the message was delivered, or dropped when it was sent.
+	Time time.Duration
+	Kind EventKind
+	From party.ID
+	To   party.ID
+
+	Protocol    string
+	RoundNumber round.Number
+	Type        protocol.MessageType
+	Broadcast   bool
+	// Duplicate is true for the second delivery of a duplicated message.
+	Duplicate bool
+	// Reason is the reason why the recipient rejected a delivered message, or RejectNone.
+	Reason protocol.RejectReason
+}
+
+func newEvent(now time.Duration, from, to party.ID, msg *protocol.Message) Event {
+	return Event{
+		Time:        now,
+		From:        from,
+		To:          to,
+		Protocol:    msg.Protocol,
+		RoundNumber: msg.RoundNumber,
+		Type:        msg.Type,
+		Broadcast:   msg.Broadcast,
+	}
+}
+
+// String implements fmt.Stringer.
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s %s -> %s: %s round %d type %d", e.Time, e.Kind, e.From, e.To, e.Protocol, e.RoundNumber, e.Type)
+	if e.Broadcast {
+		s += " broadcast"
+	}
+	if e.Duplicate {
+		s += " duplicate"
+	}
+	if e.Reason != protocol.RejectNone {
+		s += " rejected: " + e.Reason.String()
+	}
+	return s
+}
@@ -0,0 +1,370 @@
+package tcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+const (
+	// maxMessageSize bounds the size of a single message.
+	maxMessageSize = 32 << 20
+	// handshakeTimeout bounds the time to set up a connection.
+	handshakeTimeout = 10 * time.Second
+	// minBackoff and maxBackoff bound the time between two connection attempts to a peer.
+	minBackoff = 50 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+// peer holds the messages sent to another party.
+type peer struct {
+	id          party.ID
+	address     string
+	certificate *x509.Certificate
+
+	mtx sync.Mutex
+	// messages contains all the messages sent to this peer, numbered by their index.
+	messages [][]byte
+	// acknowledged is the number of messages the peer received.
+	acknowledged uint64
+	// pending is signaled when a message is added.
+	pending chan struct{}
+	// acked is closed and replaced whenever acknowledged changes.
+	acked chan struct{}
+}
+
+func newPeer(id party.ID, p *Peer) *peer {
+	return &peer{
+		id:          id,
+		address:     p.Address,
+		certificate: p.Certificate,
+		pending:     make(chan struct{}, 1),
+		acked:       make(chan struct{}),
+	}
+}
+
+func (p *peer) push(data []byte) {
+	p.mtx.Lock()
+	p.messages = append(p.messages, data)
+	p.mtx.Unlock()
+	select {
+	case p.pending <- struct{}{}:
+	default:
+	}
+}
+
+// from returns the messages starting at index.
+func (p *peer) from(index uint64) [][]byte {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if index >= uint64(len(p.messages)) {
+		return nil
+	}
+	return p.messages[index:]
+}
+
+func (p *peer) acknowledge(count uint64) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if count <= p.acknowledged || count > uint64(len(p.messages)) {
+		return
+	}
+	p.acknowledged = count
+	close(p.acked)
+	p.acked = make(chan struct{})
+}
+
+func (p *peer) flush(ctx context.Context) error {
+	for {
+		p.mtx.Lock()
+		done := p.acknowledged == uint64(len(p.messages))
+		acked := p.acked
+		p.mtx.Unlock()
+		if done {
+			return nil
+		}
+		select {
+		case <-acked:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// inbound holds the state of the messages received from another party.
+type inbound struct {
+	mtx sync.Mutex
+	// epoch is the epoch of the Network the peer is currently sending from.
+	epoch uint64
+	// received is the number of messages received during this epoch.
+	received uint64
+}
+
+// run sends the messages for p, reconnecting until the Network is closed.
+func (n *Network) run(p *peer) {
+	defer n.wg.Done()
+	backoff := minBackoff
+	for {
+		connected, _ := n.send(p)
+		if connected {
+			backoff = minBackoff
+		}
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// send connects to p, and sends the messages it hasn't received until the connection fails.
+func (n *Network) send(p *peer) (bool, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: handshakeTimeout},
+		Config:    n.clientConfig(p),
+	}
+	conn, err := dialer.DialContext(n.ctx, "tcp", p.address)
+	if err != nil {
+		return false, err
+	}
+	if !n.track(conn) {
+		_ = conn.Close()
+		return false, net.ErrClosed
+	}
+	defer n.untrack(conn)
+
+	// we announce our epoch, and the peer tells us how many messages of this epoch it received.
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err = writeUint64(conn, n.epoch); err != nil {
+		return false, err
+	}
+	next, err := readUint64(conn)
+	if err != nil {
+		return false, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	p.acknowledge(next)
+
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			count, err := readUint64(conn)
+			if err != nil {
+				failed <- err
+				return
+			}
+			p.acknowledge(count)
+		}
+	}()
+
+	for {
+		messages := p.from(next)
+		for _, data := range messages {
+			if err = writeFrame(conn, next, data); err != nil {
+				return true, err
+			}
+			next++
+		}
+		select {
+		case <-p.pending:
+		case err = <-failed:
+			return true, err
+		case <-n.ctx.Done():
+			return true, n.ctx.Err()
+		}
+	}
+}
+
+// accept handles incoming connections until the listener is closed.
+func (n *Network) accept() {
+	defer n.wg.Done()
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			if n.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if !n.track(conn) {
+			_ = conn.Close()
+			return
+		}
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			defer n.untrack(conn)
+			_ = n.receive(tls.Server(conn, n.serverConfig()))
+		}()
+	}
+}
+
+// receive delivers the messages from an incoming connection.
+func (n *Network) receive(conn *tls.Conn) error {
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := conn.HandshakeContext(n.ctx); err != nil {
+		return err
+	}
+	id, ok := n.identify(conn.ConnectionState().PeerCertificates)
+	if !ok {
+		return errors.New("unknown peer")
+	}
+	in := n.inbound[id]
+
+	epoch, err := readUint64(conn)
+	if err != nil {
+		return err
+	}
+	in.mtx.Lock()
+	if in.epoch != epoch {
+		in.epoch, in.received = epoch, 0
+	}
+	received := in.received
+	in.mtx.Unlock()
+	if err = writeUint64(conn, received); err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	for {
+		index, data, err := readFrame(conn)
+		if err != nil {
+			return err
+		}
+		in.mtx.Lock()
+		if in.epoch != epoch || index > in.received {
+			// the peer restarted, or we missed a message, so it needs to reconnect.
+			in.mtx.Unlock()
+			return fmt.Errorf("unexpected message %d", index)
+		}
+		if index == in.received {
+			in.received++
+			// messages which aren't from this peer are dropped, but still acknowledged.
+			msg := &protocol.Message{}
+			if err = msg.UnmarshalBinary(data); err == nil && msg.From == id {
+				select {
+				case n.incoming <- msg:
+				case <-n.ctx.Done():
+					in.mtx.Unlock()
+					return n.ctx.Err()
+				}
+			}
+		}
+		received = in.received
+		in.mtx.Unlock()
+		if err = writeUint64(conn, received); err != nil {
+			return err
+		}
+	}
+}
+
+// identify returns the party whose pinned certificate was presented.
+func (n *Network) identify(certificates []*x509.Certificate) (party.ID, bool) {
+	if len(certificates) == 0 {
+		return "", false
+	}
+	for id, p := range n.peers {
+		if bytes.Equal(p.certificate.Raw, certificates[0].Raw) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// serverConfig only accepts the certificates of our peers.
+//
+// Certificates are pinned instead of being verified against a CA, so the usual verification is skipped.
+func (n *Network) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{n.config.Certificate},
+		ClientAuth:   tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			for _, p := range n.peers {
+				if len(rawCerts) > 0 && bytes.Equal(p.certificate.Raw, rawCerts[0]) {
+					return nil
+				}
+			}
+			return errors.New("tcp: unknown client certificate")
+		},
+	}
+}
+
+// clientConfig only accepts the certificate of p.
+func (n *Network) clientConfig(p *peer) *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS13,
+		Certificates:       []tls.Certificate{n.config.Certificate},
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 || !bytes.Equal(p.certificate.Raw, rawCerts[0]) {
+				return fmt.Errorf("tcp: unexpected certificate for %s", p.id)
+			}
+			return nil
+		},
+	}
+}
+
+func randomEpoch() (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+func writeUint64(w io.Writer, v uint64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	_, err := w.Write(b[:])
+	return err
+}
+
+func readUint64(r io.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// writeFrame writes a message with its index, prefixed by its length.
+func writeFrame(w io.Writer, index uint64, data []byte) error {
+	frame := make([]byte, 12, 12+len(data))
+	binary.BigEndian.PutUint64(frame[:8], index)
+	binary.BigEndian.PutUint32(frame[8:], uint32(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+func readFrame(r io.Reader) (uint64, []byte, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	index := binary.BigEndian.Uint64(header[:8])
+	length := binary.BigEndian.Uint32(header[8:])
+	if length > maxMessageSize {
+		return 0, nil, fmt.Errorf("message of %d bytes is too large", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return index, data, nil
+}
@@ -0,0 +1,220 @@
+// Package tcp connects the parties of a protocol over TCP, using mutual TLS.
+//
+// Every party listens for connections from the others, and opens one connection to each of them
+// for the messages it sends. Both sides authenticate with a TLS certificate which the other side
+// has pinned for their party.ID, so that messages can't be forged or read by anybody else.
+//
+// Messages to a party are numbered, and kept until that party acknowledges them.
+// When a connection drops, the sender reconnects and resends what wasn't acknowledged,
+// and the receiver drops the messages it already delivered.
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+)
+
+// Peer is another party of the protocol.
+type Peer struct {
+	// Address is the host:port the party listens on.
+	Address string
+	// Certificate is the certificate the party authenticates with.
+	Certificate *x509.Certificate
+}
+
+// Config describes the parties a Network connects.
+type Config struct {
+	// ID is the party.ID of this party.
+	ID party.ID
+	// Address is the host:port to listen on, when using Listen.
+	Address string
+	// Certificate is the certificate this party authenticates with, both as a client and a server.
+	Certificate tls.Certificate
+	// Peers maps the other parties to their address and certificate.
+	Peers map[party.ID]*Peer
+}
+
+// Network sends and receives the messages of one party.
+type Network struct {
+	config   *Config
+	listener net.Listener
+	// epoch identifies this Network, so that peers know that we start numbering messages from 0 again.
+	epoch    uint64
+	peers    map[party.ID]*peer
+	inbound  map[party.ID]*inbound
+	incoming chan *protocol.Message
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mtx    sync.Mutex
+	// conns contains the open connections, so that they can be closed.
+	conns map[net.Conn]struct{}
+}
+
+// Listen starts a Network listening on config.Address.
+func Listen(config *Config) (*Network, error) {
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("tcp: %w", err)
+	}
+	n, err := NewNetwork(config, listener)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+// NewNetwork starts a Network accepting connections from listener, which is closed by Close.
+func NewNetwork(config *Config, listener net.Listener) (*Network, error) {
+	if config.ID == "" || len(config.Certificate.Certificate) == 0 {
+		return nil, errors.New("tcp: missing ID or certificate")
+	}
+	for id, p := range config.Peers {
+		if id == config.ID {
+			return nil, errors.New("tcp: peers can't contain ourselves")
+		}
+		if p == nil || p.Address == "" || p.Certificate == nil {
+			return nil, fmt.Errorf("tcp: missing address or certificate for %s", id)
+		}
+	}
+	epoch, err := randomEpoch()
+	if err != nil {
+		return nil, fmt.Errorf("tcp: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Network{
+		config:   config,
+		listener: listener,
+		epoch:    epoch,
+		peers:    make(map[party.ID]*peer, len(config.Peers)),
+		inbound:  make(map[party.ID]*inbound, len(config.Peers)),
+		incoming: make(chan *protocol.Message, 4*len(config.Peers)),
+		ctx:      ctx,
+		cancel:   cancel,
+		conns:    map[net.Conn]struct{}{},
+	}
+	for id, p := range config.Peers {
+		n.peers[id] = newPeer(id, p)
+		n.inbound[id] = &inbound{}
+	}
+
+	n.wg.Add(1)
+	go n.accept()
+	for _, p := range n.peers {
+		n.wg.Add(1)
+		go n.run(p)
+	}
+	return n, nil
+}
+
+// Addr returns the address the Network listens on.
+func (n *Network) Addr() net.Addr {
+	return n.listener.Addr()
+}
+
+// Send queues msg for all the peers it is intended for, and returns immediately.
+func (n *Network) Send(msg *protocol.Message) {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		return
+	}
+	for id, p := range n.peers {
+		if msg.IsFor(id) {
+			p.push(data)
+		}
+	}
+}
+
+// Next returns a channel with the messages received from all peers.
+func (n *Network) Next() <-chan *protocol.Message {
+	return n.incoming
+}
+
+// Flush blocks until all the messages sent so far have been acknowledged by their recipient,
+// or until ctx is done.
+//
+// This should be called before Close, since the last messages of a protocol may still be
+// needed by the other parties after we are done.
+func (n *Network) Flush(ctx context.Context) error {
+	for _, p := range n.peers {
+		if err := p.flush(ctx); err != nil {
+			return fmt.Errorf("tcp: flush %s: %w", p.id, err)
+		}
+	}
+	return nil
+}
+
+// Close stops the Network, closing all of its connections.
+func (n *Network) Close() error {
+	n.cancel()
+	err := n.listener.Close()
+	n.mtx.Lock()
+	for conn := range n.conns {
+		_ = conn.Close()
+	}
+	n.mtx.Unlock()
+	n.wg.Wait()
+	return err
+}
+
+// track records conn as open, and returns false if the Network was closed.
+func (n *Network) track(conn net.Conn) bool {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.ctx.Err() != nil {
+		return false
+	}
+	n.conns[conn] = struct{}{}
+	return true
+}
+
+func (n *Network) untrack(conn net.Conn) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	delete(n.conns, conn)
+	_ = conn.Close()
+}
+
+// disconnect closes all open connections, which are then reestablished.
+func (n *Network) disconnect() {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	for conn := range n.conns {
+		_ = conn.Close()
+	}
+}
+
+// HandlerLoop blocks until the handler has finished, sending its messages through the Network
+// and delivering the ones received. The result of the execution is given by Handler.Result().
+//
+// Network.Flush should be called afterwards, before closing the Network.
+func HandlerLoop(h protocol.Handler, n *Network) {
+	for {
+		select {
+
+		// outgoing messages
+		case msg, ok := <-h.Listen():
+			if !ok {
+				// the channel was closed, indicating that the protocol is done executing.
+				return
+			}
+			n.Send(msg)
+
+		// incoming messages
+		case msg := <-n.Next():
+			if h.CanAccept(msg) {
+				h.Accept(msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tcp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/multi-party-sig/internal/test"
+	"github.com/MixinNetwork/multi-party-sig/pkg/math/curve"
+	"github.com/MixinNetwork/multi-party-sig/pkg/party"
+	"github.com/MixinNetwork/multi-party-sig/pkg/protocol"
+	"github.com/MixinNetwork/multi-party-sig/protocols/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// networks connects the given parties over loopback.
+func networks(t *testing.T, partyIDs []party.ID) map[party.ID]*Network {
+	configs := make(map[party.ID]*Config, len(partyIDs))
+	listeners := make(map[party.ID]net.Listener, len(partyIDs))
+	for _, id := range partyIDs {
+		certificate, _, err := GenerateCertificate(id, time.Hour)
+		require.NoError(t, err)
+		listeners[id], err = net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		configs[id] = &Config{ID: id, Certificate: certificate, Peers: map[party.ID]*Peer{}}
+	}
+	for _, id := range partyIDs {
+		for _, other := range partyIDs {
+			if id != other {
+				configs[id].Peers[other] = &Peer{
+					Address:     listeners[other].Addr().String(),
+					Certificate: configs[other].Certificate.Leaf,
+				}
+			}
+		}
+	}
+	result := make(map[party.ID]*Network, len(partyIDs))
+	for _, id := range partyIDs {
+		n, err := NewNetwork(configs[id], listeners[id])
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = n.Close() })
+		result[id] = n
+	}
+	return result
+}
+
+func message(from party.ID, i int) *protocol.Message {
+	return &protocol.Message{From: from, Protocol: "test", RoundNumber: 2, Data: []byte{byte(i)}}
+}
+
+func TestReconnect(t *testing.T) {
+	partyIDs := test.PartyIDs(2)
+	a, b := partyIDs[0], partyIDs[1]
+	nets := networks(t, partyIDs)
+
+	count := 50
+	go func() {
+		for i := 0; i < count; i++ {
+			nets[a].Send(message(a, i))
+			if i%10 == 5 {
+				nets[a].disconnect()
+				nets[b].disconnect()
+			}
+		}
+	}()
+	for i := 0; i < count; i++ {
+		select {
+		case msg := <-nets[b].Next():
+			require.Equal(t, []byte{byte(i)}, msg.Data, "messages should arrive once and in order")
+		case <-time.After(10 * time.Second):
+			t.Fatalf("message %d not received", i)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, nets[a].Flush(ctx))
+	select {
+	case msg := <-nets[b].Next():
+		t.Fatalf("unexpected message %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestForgedMessages(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+	nets := networks(t, partyIDs)
+
+	// a can't send messages in the name of c.
+	nets[a].Send(message(c, 0))
+	nets[a].Send(message(a, 1))
+	msg := <-nets[b].Next()
+	assert.Equal(t, a, msg.From)
+	assert.Equal(t, []byte{1}, msg.Data)
+
+	// a party whose certificate isn't pinned can't connect.
+	certificate, _, err := GenerateCertificate(a, time.Hour)
+	require.NoError(t, err)
+	impostor, err := Listen(&Config{
+		ID:          a,
+		Address:     "127.0.0.1:0",
+		Certificate: certificate,
+		Peers:       map[party.ID]*Peer{b: nets[a].config.Peers[b]},
+	})
+	require.NoError(t, err)
+	defer impostor.Close()
+	impostor.Send(message(a, 2))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.Error(t, impostor.Flush(ctx))
+	select {
+	case msg := <-nets[b].Next():
+		t.Fatalf("unexpected message %v", msg)
+	default:
+	}
+}
+
+func TestHandlerLoop(t *testing.T) {
+	group := curve.Secp256k1{}
+	partyIDs := test.PartyIDs(3)
+	nets := networks(t, partyIDs)
+
+	// connections keep dropping during the protocol.
+	stop := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Millisecond):
+				nets[partyIDs[i%len(partyIDs)]].disconnect()
+			}
+		}
+	}()
+	defer close(stop)
+
+	var mtx sync.Mutex
+	results := make(map[party.ID]*frost.Config, len(partyIDs))
+	var wg sync.WaitGroup
+	for _, id := range partyIDs {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(frost.Keygen(group, id, partyIDs, 1), nil)
+			require.NoError(t, err)
+			HandlerLoop(h, nets[id])
+			result, err := h.Result()
+			require.NoError(t, err)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			require.NoError(t, nets[id].Flush(ctx))
+			mtx.Lock()
+			results[id] = result.(*frost.Config)
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	for _, id := range partyIDs {
+		assert.True(t, results[partyIDs[0]].PublicKey.Equal(results[id].PublicKey))
+	}
+}